@@ -0,0 +1,314 @@
+// 访问日志
+// 包装ResponseWriter记录实际输出的状态码、字节数和耗时，每个请求结束后写一行日志
+// 支持bingo、combined、json和自定义模板格式，健康检查等路径可以不记录，正常的应答可以采样记录
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lixy529/gotools/logs"
+)
+
+// 内置的访问日志格式
+const (
+	AccessFormatBingo    = "bingo"    // 竖线分隔，同之前的版本，最后加上字节数、耗时和请求ID
+	AccessFormatCombined = "combined" // Apache combined格式，最后加上耗时(秒)和请求ID
+	AccessFormatJson     = "json"     // 每个请求一行JSON
+)
+
+// AccessLogConfig 访问日志配置
+type AccessLogConfig struct {
+	Format    string   // 日志格式: bingo | combined | json | 自定义模板，如：{{.Method}} {{.Uri}} {{.Status}} {{.Duration}}，默认bingo
+	File      string   // 日志文件名，写到AppRoot/log下，为空时写框架日志
+	SkipPaths []string // 不记录2xx、3xx应答的路径，如：/healthz，以/结尾时匹配前缀
+	Sample    float64  // 2xx、3xx应答的采样率，0~1，默认1全部记录，4xx、5xx应答全部记录
+}
+
+// AccessEntry 一条访问日志，自定义模板可以使用其中的字段
+type AccessEntry struct {
+	Time       time.Time     `json:"time"`
+	RemoteAddr string        `json:"remote_addr"`
+	ClientIp   string        `json:"client_ip"`
+	Method     string        `json:"method"`
+	Uri        string        `json:"uri"`
+	Proto      string        `json:"proto"`
+	Host       string        `json:"host"`
+	Status     int           `json:"status"`
+	Bytes      int64         `json:"bytes"`
+	Duration   time.Duration `json:"-"`
+	DurationMs float64       `json:"duration_ms"`
+	Referer    string        `json:"referer,omitempty"`
+	UserAgent  string        `json:"user_agent,omitempty"`
+	Forward    string        `json:"forward,omitempty"` // X-Forwarded-For
+	Route      string        `json:"route,omitempty"`   // 路由请求路径，如：/user/:id
+	RouteName  string        `json:"route_name,omitempty"`
+	Controller string        `json:"controller,omitempty"`
+	Action     string        `json:"action,omitempty"`
+	RequestId  string        `json:"request_id,omitempty"`
+}
+
+// accessLogger 编译后的访问日志配置
+type accessLogger struct {
+	format string
+	tpl    *template.Template // 自定义模板
+	skip   []string
+	sample float64
+}
+
+// SetAccessLog 设置访问日志的格式和记录规则，默认使用配置文件的[access_log]段
+// 自定义模板错误时panic
+//   参数
+//     cfg: 访问日志配置
+//   返回
+//     void
+func (rt *RouterTab) SetAccessLog(cfg AccessLogConfig) {
+	al := &accessLogger{format: cfg.Format, skip: cfg.SkipPaths, sample: cfg.Sample}
+	switch cfg.Format {
+	case "":
+		al.format = AccessFormatBingo
+	case AccessFormatBingo, AccessFormatCombined, AccessFormatJson:
+	default:
+		tpl, err := template.New("access").Parse(cfg.Format)
+		if err != nil {
+			panic(fmt.Sprintf("accesslog: format [%s] is error, %s", cfg.Format, err.Error()))
+		}
+		al.tpl = tpl
+	}
+	if al.sample <= 0 || al.sample > 1 {
+		al.sample = 1
+	}
+	rt.access = al
+}
+
+// accessKey 请求的accessWriter在context里的key
+type accessKey struct{}
+
+// accessWriter 记录实际输出的状态码和字节数，同时记录匹配到的路由
+type accessWriter struct {
+	http.ResponseWriter
+	status   int         // 输出的状态码，还没有输出时为0
+	bytes    int64       // 输出的字节数，不含Header
+	hijacked bool        // 连接是否已被接管
+	route    *RouterInfo // 匹配到的路由，没有匹配到时为nil
+	rt       *RouterTab  // 处理请求的路由表
+}
+
+// WriteHeader 输出状态码，实现http.ResponseWriter接口，1xx的中间应答不记录
+//   参数
+//     code: 状态码
+//   返回
+//     void
+func (aw *accessWriter) WriteHeader(code int) {
+	if aw.status == 0 && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		aw.status = code
+	}
+	aw.ResponseWriter.WriteHeader(code)
+}
+
+// Write 输出数据，实现http.ResponseWriter接口
+//   参数
+//     b: 数据
+//   返回
+//     输出的字节数、错误信息
+func (aw *accessWriter) Write(b []byte) (int, error) {
+	if aw.status == 0 {
+		aw.status = http.StatusOK
+	}
+	n, err := aw.ResponseWriter.Write(b)
+	aw.bytes += int64(n)
+	return n, err
+}
+
+// Flush 实现http.Flusher接口
+//   参数
+//     void
+//   返回
+//     void
+func (aw *accessWriter) Flush() {
+	if f, ok := aw.ResponseWriter.(http.Flusher); ok {
+		if aw.status == 0 {
+			aw.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack 实现http.Hijacker接口
+//   参数
+//     void
+//   返回
+//     连接、读写缓冲、错误信息
+func (aw *accessWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := aw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("bingo: ResponseWriter does not support Hijacker")
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		aw.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// finalStatus 返回请求结束后的状态码
+// 没有输出时，连接被接管记为101，客户端断开记为499，否则记为200
+//   参数
+//     r: Request对象
+//   返回
+//     状态码
+func (aw *accessWriter) finalStatus(r *http.Request) int {
+	if aw.status != 0 {
+		return aw.status
+	}
+	switch {
+	case aw.hijacked:
+		return http.StatusSwitchingProtocols
+	case r.Context().Err() != nil:
+		return StatusClientClosed
+	}
+	return http.StatusOK
+}
+
+// setAccessRoute 记录请求匹配到的路由，用于访问日志和监控指标
+//   参数
+//     r:  Request对象
+//     ri: 匹配到的路由
+//   返回
+//     void
+func setAccessRoute(r *http.Request, ri *RouterInfo) {
+	if aw, ok := r.Context().Value(accessKey{}).(*accessWriter); ok {
+		aw.route = ri
+	}
+}
+
+// accessLog 请求结束后写访问日志
+//   参数
+//     aw:    accessWriter对象
+//     r:     Request对象
+//     start: 请求开始的时间
+//   返回
+//     void
+func (rt *RouterTab) accessLog(aw *accessWriter, r *http.Request, start time.Time) {
+	status := aw.finalStatus(r)
+
+	al := rt.access
+	if al == nil {
+		al = &accessLogger{format: AccessFormatBingo, sample: 1}
+	}
+	if status < 400 {
+		if matchPaths(al.skip, r.URL.Path) {
+			return
+		}
+		if al.sample < 1 && rand.Float64() >= al.sample {
+			return
+		}
+	}
+
+	duration := time.Since(start)
+	e := &AccessEntry{
+		Time:       start,
+		RemoteAddr: r.RemoteAddr,
+		ClientIp:   RateKeyIP(r),
+		Method:     r.Method,
+		Uri:        rt.uri(r),
+		Proto:      r.Proto,
+		Host:       r.Host,
+		Status:     status,
+		Bytes:      aw.bytes,
+		Duration:   duration,
+		DurationMs: float64(duration) / float64(time.Millisecond),
+		Referer:    r.Header.Get("Referer"),
+		UserAgent:  r.Header.Get("User-Agent"),
+		Forward:    r.Header.Get("X-Forwarded-For"),
+		RequestId:  RequestId(r),
+	}
+	if ri := aw.route; ri != nil {
+		e.Route = ri.pattern
+		e.RouteName = ri.name
+		e.Controller = ri.controllerName()
+		e.Action = ri.method
+	}
+
+	logger := Alogger
+	if logger == nil {
+		logger = Flogger
+	}
+	line := al.line(r, e)
+	if status >= 400 {
+		logger.Error(line)
+	} else {
+		logger.Info(line)
+	}
+}
+
+// line 按格式生成一行访问日志
+//   参数
+//     r: Request对象
+//     e: 访问日志
+//   返回
+//     日志内容
+func (al *accessLogger) line(r *http.Request, e *AccessEntry) string {
+	if al.tpl != nil {
+		buf := &bytes.Buffer{}
+		if err := al.tpl.Execute(buf, e); err != nil {
+			return fmt.Sprintf("accesslog: template err [%s]", err.Error())
+		}
+		return buf.String()
+	}
+
+	switch al.format {
+	case AccessFormatCombined:
+		return fmt.Sprintf(`%s - - [%s] "%s %s %s" %d %d "%s" "%s" %.3f %s`, e.ClientIp, e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+			e.Method, e.Uri, e.Proto, e.Status, e.Bytes, e.Referer, e.UserAgent, e.Duration.Seconds(), e.RequestId)
+	case AccessFormatJson:
+		b, _ := json.Marshal(e)
+		return string(b)
+	}
+
+	proxy := ""
+	if AppCfg.ServerCfg.ForwardName != "" {
+		proxy = r.Header.Get(AppCfg.ServerCfg.ForwardName)
+	}
+	return fmt.Sprintf("%s|%s|%s|%d|%s|%s|%s|%s|%d|%.3f|%s", e.RemoteAddr, e.Method, e.Uri, e.Status, e.UserAgent, e.Host, e.Forward, proxy, e.Bytes, e.DurationMs, e.RequestId)
+}
+
+// matchPaths 判断请求路径是否在列表里，以/结尾的按前缀匹配
+//   参数
+//     paths:   路径列表
+//     urlPath: 请求路径
+//   返回
+//     在列表里返回true，否则返回false
+func matchPaths(paths []string, urlPath string) bool {
+	for _, p := range paths {
+		if urlPath == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(urlPath, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// newAccessLogger 实例化访问日志单独使用的文件日志
+//   参数
+//     file: 日志文件名，写到AppRoot/log下
+//   返回
+//     日志对象、错误信息
+func newAccessLogger(file string) (logs.Logger, error) {
+	l := &logs.FileLogs{}
+	err := l.Init(fmt.Sprintf(`{"FilePath":"%s/log","filename":"%s","maxlines":0,"maxsize":4000,"perm":"0660","level":1}`, AppRoot, file))
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
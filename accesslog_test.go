@@ -0,0 +1,153 @@
+package bingo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lixy529/gotools/logs"
+)
+
+// lineLogger 记录写入的日志行
+type lineLogger struct {
+	logs.Logger
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *lineLogger) Info(v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprint(v...))
+}
+
+func (l *lineLogger) Error(v ...interface{}) {
+	l.Info(v...)
+}
+
+func (l *lineLogger) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := l.lines
+	l.lines = nil
+	return lines
+}
+
+// TestAccessLog 测试访问日志记录实际的状态码、字节数和路由
+func TestAccessLog(t *testing.T) {
+	logger := &lineLogger{}
+	old := Alogger
+	Alogger = logger
+	defer func() { Alogger = old }()
+
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.SetAccessLog(AccessLogConfig{Format: AccessFormatJson, SkipPaths: []string{"/healthz", "/internal/"}})
+	rt.HandleFunc("/access/:id", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}, WithName("access"))
+	rt.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	rt.HandleFunc("/internal/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	rt.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/healthz", http.StatusFound)
+	})
+
+	// 控制器输出的状态码和字节数
+	r := httptest.NewRequest("GET", "/access/1?a=b", nil)
+	r.Header.Set("X-Request-Id", "req-1")
+	rt.ServeHTTP(httptest.NewRecorder(), r)
+	lines := logger.take()
+	if len(lines) != 1 {
+		t.Fatalf("access log failed. Got %v.", lines)
+	}
+	var e AccessEntry
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatalf("Unmarshal failed. err: %s", err.Error())
+	}
+	if e.Status != http.StatusGone || e.Bytes != 5 || e.Uri != "/access/1?a=b" || e.Method != "GET" {
+		t.Errorf("access log failed. Got %+v.", e)
+	}
+	if e.Route != "/access/:id" || e.RouteName != "access" || e.Controller == "" || e.RequestId != "req-1" {
+		t.Errorf("access log route failed. Got %+v.", e)
+	}
+
+	// 跳转
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/redirect", nil))
+	if lines = logger.take(); len(lines) != 1 || !strings.Contains(lines[0], `"status":302`) {
+		t.Errorf("redirect log failed. Got %v.", lines)
+	}
+
+	// 不记录的路径，404仍然记录
+	for _, url := range []string{"/healthz", "/internal/ping", "/internal/none"} {
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+	if lines = logger.take(); len(lines) != 1 || !strings.Contains(lines[0], `"status":404`) {
+		t.Errorf("skip failed. Got %v.", lines)
+	}
+
+	// 客户端断开
+	ctx, cancel := context.WithCancel(context.Background())
+	rt.HandleFunc("/cancel", func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-r.Context().Done()
+	})
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/cancel", nil).WithContext(ctx))
+	if lines = logger.take(); len(lines) != 1 || !strings.Contains(lines[0], `"status":499`) {
+		t.Errorf("client closed failed. Got %v.", lines)
+	}
+}
+
+// TestAccessLogFormat 测试内置格式和自定义模板
+func TestAccessLogFormat(t *testing.T) {
+	logger := &lineLogger{}
+	old := Alogger
+	Alogger = logger
+	defer func() { Alogger = old }()
+
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.HandleFunc("/fmt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}, WithName("fmt"))
+
+	tests := []struct {
+		format string
+		check  func(line string) bool
+	}{
+		{"", func(line string) bool {
+			fields := strings.Split(line, "|")
+			return len(fields) == 11 && fields[10] != "" && fields[1] == "GET" && fields[2] == "/fmt" && fields[3] == "200" && fields[8] == "5"
+		}},
+		{AccessFormatCombined, func(line string) bool {
+			return strings.Contains(line, `"GET /fmt HTTP/1.1" 200 5 "" "ua" `)
+		}},
+		{"{{.Method}} {{.RouteName}} {{.Status}} {{.Bytes}}", func(line string) bool {
+			return line == "GET fmt 200 5"
+		}},
+	}
+	for _, test := range tests {
+		rt.SetAccessLog(AccessLogConfig{Format: test.format})
+		r := httptest.NewRequest("GET", "/fmt", nil)
+		r.Header.Set("User-Agent", "ua")
+		rt.ServeHTTP(httptest.NewRecorder(), r)
+		if lines := logger.take(); len(lines) != 1 || !test.check(lines[0]) {
+			t.Errorf("format [%s] failed. Got %v.", test.format, lines)
+		}
+	}
+
+	defer func() {
+		if err := recover(); err == nil {
+			t.Errorf("SetAccessLog failed. Got nil, expected panic for bad template.")
+		}
+	}()
+	rt.SetAccessLog(AccessLogConfig{Format: "{{.Method"})
+}
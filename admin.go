@@ -0,0 +1,348 @@
+// 管理端口
+// 和业务端口分开监听，支持TCP地址和unix socket，提供pprof、expvar、路由表、生效的配置(屏蔽密码)、协程堆栈和运行时修改日志级别
+// 配置了token时请求要带上token，没有配置时只能监听本机地址或unix socket，配置取配置文件的[admin]段
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"regexp"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strings"
+	"sync"
+
+	"github.com/lixy529/gotools/logs"
+)
+
+// adminUnixPrefix 监听unix socket的地址前缀，如：unix:/tmp/admin.sock
+const adminUnixPrefix = "unix:"
+
+// adminMask 屏蔽后的配置值
+const adminMask = "******"
+
+// AdminConfig 管理端口配置
+type AdminConfig struct {
+	On    bool   // 是否开启
+	Addr  string // 监听地址，如：127.0.0.1:8090 或 unix:/tmp/admin.sock，默认127.0.0.1:8090
+	Token string // 请求需要带的token，Header为Authorization: Bearer <token>或X-Admin-Token，为空时只能监听本机地址
+}
+
+var (
+	adminMu     sync.Mutex
+	adminServer *http.Server // 管理端口的服务，没有开启时为nil
+)
+
+// secretKeyRe 值需要屏蔽的配置项名称
+var secretKeyRe = regexp.MustCompile(`(?i)(pass|pwd|secret|token|auth|credential)`)
+
+// userInfoRe 连接串里的用户名和密码，如：root:root123@tcp(127.0.0.1:3306)、redis://:pwd@127.0.0.1
+var userInfoRe = regexp.MustCompile(`([^:/@\s"]*):([^@/\s"]+)@`)
+
+// jsonSecretRe JSON配置里的密码字段，如：{"password":"xxx"}
+var jsonSecretRe = regexp.MustCompile(`(?i)("[^"]*(?:pass|pwd|secret|token|auth)[^"]*"\s*:\s*)"[^"]*"`)
+
+// AdminHandler 返回管理端口的http.Handler，没有做token校验，可以挂到其它的内部服务上
+//   参数
+//     void
+//   返回
+//     http.Handler
+func AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", adminIndex)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/routes", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		Router.PrintRoutes(w)
+	})
+	mux.HandleFunc("/config", adminConfig)
+	mux.HandleFunc("/goroutines", adminGoroutines)
+	mux.HandleFunc("/loglevel", adminLogLevel)
+	mux.Handle("/metrics", MetricsHandler())
+	mux.Handle("/healthz", LivenessHandler())
+	mux.Handle("/readyz", ReadinessHandler())
+	return mux
+}
+
+// adminIndex 输出管理端口的功能列表
+//   参数
+//     w: ResponseWriter对象
+//     r: Request对象
+//   返回
+//     void
+func adminIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "/debug/pprof/  pprof")
+	fmt.Fprintln(w, "/debug/vars    expvar")
+	fmt.Fprintln(w, "/routes        route table")
+	fmt.Fprintln(w, "/config        effective config, secrets masked")
+	fmt.Fprintln(w, "/goroutines    goroutine dump, ?debug=1 groups identical stacks")
+	fmt.Fprintln(w, "/loglevel      GET log levels, POST logger=frame|business|access&level=debug|info|warn|error|fatal")
+	fmt.Fprintln(w, "/metrics       metrics")
+	fmt.Fprintln(w, "/healthz       liveness")
+	fmt.Fprintln(w, "/readyz        readiness")
+}
+
+// adminConfig 输出配置文件里生效的配置，密码等敏感信息被屏蔽
+//   参数
+//     w: ResponseWriter对象
+//     r: Request对象
+//   返回
+//     void
+func adminConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := make(map[string]map[string]string)
+	if GlobalCfg != nil {
+		for _, sec := range GlobalCfg.GetSecs() {
+			items, _ := GlobalCfg.GetSec(sec)
+			masked := make(map[string]string, len(items))
+			for k, v := range items {
+				masked[k] = maskConfig(k, v)
+			}
+			cfg[strings.ToLower(sec)] = masked
+		}
+	}
+	writeAdminJson(w, map[string]interface{}{
+		"app_root": AppRoot,
+		"run_mode": AppCfg.RunMode,
+		"config":   cfg,
+	})
+}
+
+// maskConfig 屏蔽配置里的敏感信息
+//   参数
+//     key: 配置项名称
+//     val: 配置值
+//   返回
+//     屏蔽后的值
+func maskConfig(key, val string) string {
+	if secretKeyRe.MatchString(key) {
+		return adminMask
+	}
+	val = userInfoRe.ReplaceAllString(val, "${1}:"+adminMask+"@")
+	return jsonSecretRe.ReplaceAllString(val, `${1}"`+adminMask+`"`)
+}
+
+// adminGoroutines 输出所有协程的堆栈
+//   参数
+//     w: ResponseWriter对象
+//     r: Request对象
+//   返回
+//     void
+func adminGoroutines(w http.ResponseWriter, r *http.Request) {
+	debug := 2
+	if r.FormValue("debug") == "1" {
+		debug = 1
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "goroutines: %d\n\n", runtime.NumGoroutine())
+	rpprof.Lookup("goroutine").WriteTo(w, debug)
+}
+
+// adminLoggers 返回可以修改级别的日志，访问日志和框架日志相同时不单独列出
+//   参数
+//     void
+//   返回
+//     日志名称和日志对象
+func adminLoggers() map[string]logs.Logger {
+	m := map[string]logs.Logger{"frame": Flogger, "business": Glogger}
+	if Alogger != Flogger {
+		m["access"] = Alogger
+	}
+	return m
+}
+
+// adminLogLevel GET返回各日志的级别，POST修改日志的级别
+//   参数
+//     w: ResponseWriter对象
+//     r: Request对象
+//   返回
+//     void
+func adminLogLevel(w http.ResponseWriter, r *http.Request) {
+	loggers := adminLoggers()
+	switch r.Method {
+	case "GET", "HEAD":
+	case "POST", "PUT":
+		name := r.FormValue("logger")
+		l, ok := loggers[name]
+		if !ok || l == nil {
+			http.Error(w, fmt.Sprintf("logger [%s] not found", name), http.StatusNotFound)
+			return
+		}
+		level := logs.GetLevelIdByName(strings.ToUpper(r.FormValue("level")))
+		if level < 0 {
+			http.Error(w, fmt.Sprintf("level [%s] is error", r.FormValue("level")), http.StatusBadRequest)
+			return
+		}
+		old, ok := logLevel(l)
+		if !ok || !setLogLevel(l, level) {
+			http.Error(w, fmt.Sprintf("logger [%s] does not support changing level", name), http.StatusBadRequest)
+			return
+		}
+		if Flogger != nil {
+			Flogger.Warnf("admin: logger [%s] level changed from %s to %s by %s", name, logs.GetLevelNameById(old), logs.GetLevelNameById(level), r.RemoteAddr)
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	levels := make(map[string]string, len(loggers))
+	for name, l := range loggers {
+		if level, ok := logLevel(l); ok {
+			levels[name] = logs.GetLevelNameById(level)
+		}
+	}
+	writeAdminJson(w, levels)
+}
+
+// writeAdminJson 输出JSON
+//   参数
+//     w: ResponseWriter对象
+//     v: 输出的数据
+//   返回
+//     void
+func writeAdminJson(w http.ResponseWriter, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(b)
+}
+
+// adminAuth 校验请求的token
+//   参数
+//     token: 配置的token，为空时不校验
+//     h:     管理端口的http.Handler
+//   返回
+//     http.Handler
+func adminAuth(token string, h http.Handler) http.Handler {
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("X-Admin-Token")
+		if auth := r.Header.Get("Authorization"); got == "" && strings.HasPrefix(auth, "Bearer ") {
+			got = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// isLocalAddr 判断监听地址是否只能本机访问
+//   参数
+//     addr: 监听地址
+//   返回
+//     unix socket或本机地址返回true，否则返回false
+func isLocalAddr(addr string) bool {
+	if strings.HasPrefix(addr, adminUnixPrefix) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// adminListen 监听管理端口
+//   参数
+//     addr: 监听地址
+//   返回
+//     监听对象、错误信息
+func adminListen(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, adminUnixPrefix) {
+		return net.Listen("tcp", addr)
+	}
+
+	// 删除上次异常退出时留下的socket文件
+	path := strings.TrimPrefix(addr, adminUnixPrefix)
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	os.Chmod(path, 0660)
+	return ln, nil
+}
+
+// initAdmin 按配置开启管理端口
+//   参数
+//     cfg: 管理端口配置
+//   返回
+//     成功返回nil，失败返回错误信息
+func initAdmin(cfg AdminConfig) error {
+	if !cfg.On {
+		return nil
+	}
+	addr := cfg.Addr
+	if addr == "" {
+		addr = "127.0.0.1:8090"
+	}
+	if cfg.Token == "" && !isLocalAddr(addr) {
+		return fmt.Errorf("admin: addr [%s] is not local, token is required", addr)
+	}
+
+	ln, err := adminListen(addr)
+	if err != nil {
+		return fmt.Errorf("admin: listen [%s] err [%s]", addr, err.Error())
+	}
+	srv := &http.Server{Handler: adminAuth(cfg.Token, AdminHandler())}
+	adminMu.Lock()
+	adminServer = srv
+	adminMu.Unlock()
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			Flogger.Errorf("admin: serve [%s] err [%s]", addr, err.Error())
+		}
+	}()
+	Flogger.Infof("admin: listen [%s]", addr)
+	return nil
+}
+
+// unInitAdmin 关闭管理端口，重启时新进程需要监听同一地址
+//   参数
+//     void
+//   返回
+//     void
+func unInitAdmin() {
+	adminMu.Lock()
+	defer adminMu.Unlock()
+	if adminServer != nil {
+		adminServer.Close()
+		adminServer = nil
+	}
+}
+
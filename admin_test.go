@@ -0,0 +1,151 @@
+package bingo
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lixy529/gotools/logs"
+)
+
+// TestMaskConfig 测试屏蔽配置里的敏感信息
+func TestMaskConfig(t *testing.T) {
+	tests := []struct {
+		key, val, expected string
+	}{
+		{"port", "8080", "8080"},
+		{"password", "123456", adminMask},
+		{"admin_token", "abc", adminMask},
+		{"master", "root:root123@tcp(127.0.0.1:3309)/passport?charset=utf8", "root:******@tcp(127.0.0.1:3309)/passport?charset=utf8"},
+		{"addr", "redis://:pwd@127.0.0.1:6379", "redis://:******@127.0.0.1:6379"},
+		{"config", `{"addr":"127.0.0.1:6379","Password":"pwd","db":0}`, `{"addr":"127.0.0.1:6379","Password":"******","db":0}`},
+	}
+	for _, test := range tests {
+		if got := maskConfig(test.key, test.val); got != test.expected {
+			t.Errorf("maskConfig [%s] failed. Got %s, expected %s.", test.key, got, test.expected)
+		}
+	}
+}
+
+// TestAdminAuth 测试token校验和监听地址检查
+func TestAdminAuth(t *testing.T) {
+	h := adminAuth("secret", AdminHandler())
+	tests := []struct {
+		header, value string
+		code          int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"X-Admin-Token", "wrong", http.StatusUnauthorized},
+		{"X-Admin-Token", "secret", http.StatusOK},
+		{"Authorization", "Bearer secret", http.StatusOK},
+		{"Authorization", "secret", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/routes", nil)
+		if test.header != "" {
+			r.Header.Set(test.header, test.value)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("adminAuth [%s: %s] failed. Got %d, expected %d.", test.header, test.value, w.Code, test.code)
+		}
+	}
+
+	for addr, local := range map[string]bool{"127.0.0.1:8090": true, "localhost:8090": true, "[::1]:8090": true, "unix:/tmp/a.sock": true, ":8090": false, "0.0.0.0:8090": false, "10.0.0.1:8090": false} {
+		if isLocalAddr(addr) != local {
+			t.Errorf("isLocalAddr [%s] failed. Expected %v.", addr, local)
+		}
+	}
+	if err := initAdmin(AdminConfig{On: true, Addr: ":0"}); err == nil {
+		unInitAdmin()
+		t.Errorf("initAdmin failed. Got nil, expected error for public addr without token.")
+	}
+}
+
+// TestAdminLogLevel 测试运行时修改日志级别
+func TestAdminLogLevel(t *testing.T) {
+	old := Glogger
+	Glogger = newLevelLogger(&logs.ConsoleLogs{Level: logs.LevelInfo})
+	defer func() { Glogger = old }()
+
+	// 修改级别时请求并发写日志
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		l := NewReqLogger(Glogger, "1")
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				Glogger.Debug("admin")
+				l.Debugf("admin %d", 1)
+			}
+		}
+	}()
+
+	h := AdminHandler()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/loglevel?logger=business&level=debug", nil))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/loglevel?logger=business&level=error", nil))
+	close(stop)
+	<-done
+	levels := map[string]string{}
+	if err := json.Unmarshal(w.Body.Bytes(), &levels); err != nil {
+		t.Fatalf("Unmarshal [%s] failed. err: %s", w.Body.String(), err.Error())
+	}
+	if level, _ := logLevel(Glogger); levels["business"] != logs.ErrorName || level != logs.LevelError {
+		t.Errorf("loglevel failed. Got %v.", levels)
+	}
+	if l := Glogger.(*levelLogger); l.enabled(logs.LevelWarn) || l.Logger.(*logs.ConsoleLogs).Level != logs.LevelDebug {
+		t.Errorf("level logger failed. Got %d.", l.Logger.(*logs.ConsoleLogs).Level)
+	}
+
+	for url, code := range map[string]int{"/loglevel?logger=none&level=debug": http.StatusNotFound, "/loglevel?logger=business&level=trace": http.StatusBadRequest} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", url, nil))
+		if w.Code != code {
+			t.Errorf("loglevel [%s] failed. Got %d, expected %d.", url, w.Code, code)
+		}
+	}
+}
+
+// TestAdminUnix 测试监听unix socket
+func TestAdminUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bingo_admin")
+	if err != nil {
+		t.Fatalf("TempDir failed. err: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "admin.sock")
+
+	if err := initAdmin(AdminConfig{On: true, Addr: adminUnixPrefix + sock}); err != nil {
+		t.Fatalf("initAdmin failed. err: %s", err.Error())
+	}
+	defer unInitAdmin()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", sock)
+		},
+	}}
+	rsp, err := client.Get("http://admin/goroutines?debug=1")
+	if err != nil {
+		t.Fatalf("Get failed. err: %s", err.Error())
+	}
+	body, _ := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK || !strings.HasPrefix(string(body), "goroutines: ") {
+		t.Errorf("goroutines failed. Got %d %s.", rsp.StatusCode, body)
+	}
+}
@@ -31,6 +31,11 @@ func init() {
 	ObjApp = NewApp()
 	Router = NewRouterTab()
 	isShell = false
+
+	// 内置脚本：输出路由表
+	Router.AddShell("bingo:routes", func() {
+		Router.PrintRoutes(os.Stdout)
+	})
 }
 
 type App struct {
@@ -54,7 +59,11 @@ func (app *App) Run() {
 	if AppCfg.ServerCfg.IsFcgi {
 		// fastcgi启动
 		Flogger.Info("Server start use fcgi.")
-		err := gracefcgi.NewServer(AppCfg.ServerCfg.Addr, AppCfg.ServerCfg.Port, Router, AppCfg.ServerCfg.ShutTimeout).ListenAndServe()
+		srv := gracefcgi.NewServer(AppCfg.ServerCfg.Addr, AppCfg.ServerCfg.Port, Router, AppCfg.ServerCfg.ShutTimeout)
+		srv.OnShutdown = onShutdown
+		srv.OnRestartFailed = onRestartFailed
+		srv.DrainDelay = healthDrainDelay(AppCfg.HealthCfg)
+		err := srv.ListenAndServe()
 		if err != nil {
 			Flogger.Errorf("Start server by fcgi failed. err: %s", err.Error())
 			log.Printf("Start server by fcgi failed. err: %s", err.Error())
@@ -63,6 +72,9 @@ func (app *App) Run() {
 		// grace启动
 		Flogger.Info("Server start use grace.")
 		srv := gracehttp.NewServer(addr, Router, AppCfg.ServerCfg.ReqTimeout, AppCfg.ServerCfg.WriteTimeout, AppCfg.ServerCfg.ShutTimeout)
+		srv.OnShutdown = onShutdown
+		srv.OnRestartFailed = onRestartFailed
+		srv.DrainDelay = healthDrainDelay(AppCfg.HealthCfg)
 		if AppCfg.ServerCfg.Secure {
 			err := srv.ListenAndServeTLS(AppCfg.ServerCfg.CertFile, AppCfg.ServerCfg.KeyFile)
 			if err != nil {
@@ -134,6 +146,37 @@ func (app *App) beforeRun() {
 	// 设置请求的超时时间
 	Router.SetReqTimeout(AppCfg.ServerCfg.ReqTimeout)
 
+	// 设置全局的准入控制，代码里已设置时不覆盖
+	if !Router.limitSet {
+		Router.SetLimit(AppCfg.ServerCfg.MaxInflight, AppCfg.ServerCfg.MaxQueue, AppCfg.ServerCfg.QueueTimeout*time.Millisecond)
+	}
+
+	// 设置全局的跨域配置，代码里已设置时不覆盖
+	if Router.cors == nil {
+		Router.SetCors(AppCfg.CorsCfg)
+	}
+
+	// 设置请求ID的Header名称，代码里已设置时不覆盖
+	if Router.reqIdHeader == "" {
+		Router.SetRequestIdHeader(AppCfg.ServerCfg.RequestIdHeader)
+	}
+
+	// 设置访问日志的格式，代码里已设置时不覆盖
+	if Router.access == nil {
+		Router.SetAccessLog(AppCfg.AccessCfg)
+	}
+
+	// 设置错误上报，代码里用AddReporter添加的上报同时生效
+	if err := Router.initReporters(AppCfg.ReportCfg); err != nil {
+		Flogger.Errorf("beforeRun: %s", err.Error())
+	}
+
+	// 开启监控指标和健康检查，shell形式启动时不开启
+	if !isShell {
+		Router.initMetrics(AppCfg.MetricCfg)
+		Router.initHealth(AppCfg.HealthCfg)
+	}
+
 	for _, f := range inits {
 		if err := f(); err != nil {
 			Flogger.Errorf("beforeRun: %s", err.Error())
@@ -141,9 +184,44 @@ func (app *App) beforeRun() {
 		}
 	}
 
+	// 开启管理端口和单独监听的指标服务，失败时不影响业务端口
+	if !isShell {
+		if err := initAdmin(AppCfg.AdminCfg); err != nil {
+			Flogger.Errorf("beforeRun: %s", err.Error())
+		}
+		if err := initMetricsServer(AppCfg.MetricCfg); err != nil {
+			Flogger.Errorf("beforeRun: %s", err.Error())
+		}
+	}
+
+	// 初始化完成，就绪检查开始返回成功
+	SetHealthState(HealthReady)
+
 	return
 }
 
+// onShutdown 收到关闭或重启信号后调用
+// 就绪检查开始返回失败，关闭管理端口和单独监听的指标服务，重启时新进程可以监听同一地址
+func onShutdown() {
+	stopHealth()
+	unInitAdmin()
+	unInitMetrics()
+}
+
+// onRestartFailed 重启时新进程启动失败后调用，老进程继续服务
+// 恢复就绪状态，重新打开管理端口和单独监听的指标服务
+func onRestartFailed() {
+	if !isShell {
+		if err := initAdmin(AppCfg.AdminCfg); err != nil {
+			Flogger.Errorf("onRestartFailed: %s", err.Error())
+		}
+		if err := initMetricsServer(AppCfg.MetricCfg); err != nil {
+			Flogger.Errorf("onRestartFailed: %s", err.Error())
+		}
+	}
+	SetHealthState(HealthReady)
+}
+
 // AfterRun  运行run后销毁函数
 func (app *App) afterRun() {
 	// 资源释放
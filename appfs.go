@@ -0,0 +1,174 @@
+// 应用文件系统
+// 静态文件、模板、语言包都从应用文件系统读取，可以通过SetFS使用go:embed打包到程序里
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// appFSys 通过SetFS设置的文件系统，为nil时使用AppRoot下的文件
+var appFSys fs.FS
+
+// SetFS 设置应用文件系统，需要在Run之前调用
+// 文件系统的根目录对应AppRoot，如：static/js/a.js、views/demo/index.html、lang/zh-cn.json
+// dev模式下AppRoot下存在的文件优先，修改后不需要重新编译
+//   参数
+//     fsys: 文件系统，如：embed.FS，为nil时恢复使用AppRoot下的文件
+//   返回
+//     void
+func SetFS(fsys fs.FS) {
+	appFSys = fsys
+}
+
+// appFS 返回当前生效的应用文件系统
+//   参数
+//     void
+//   返回
+//     文件系统
+func appFS() fs.FS {
+	disk := rootFS(AppRoot)
+	if appFSys == nil {
+		return disk
+	}
+	if AppCfg != nil && AppCfg.RunMode == DEV {
+		return overlayFS{disk, appFSys}
+	}
+	return appFSys
+}
+
+// appRel 把AppRoot下的目录转成应用文件系统里的路径
+//   参数
+//     dir: 磁盘目录，如：/data/demo/views
+//   返回
+//     应用文件系统里的路径，如：views，不在AppRoot下时返回false
+func appRel(dir string) (string, bool) {
+	rel, err := filepath.Rel(AppRoot, dir)
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if !fs.ValidPath(rel) {
+		return "", false
+	}
+	return rel, true
+}
+
+// rootFiles 检查过没有通过软链接指到根目录外的文件，key为拼接根目录后的路径，value为文件的os.FileInfo
+var rootFiles sync.Map
+
+// rootFS 以目录为根的磁盘文件系统
+// 与os.DirFS不同，去掉软链接后在根目录以外的文件不允许访问
+type rootFS string
+
+// Open 打开文件，实现fs.FS接口
+//   参数
+//     name: 文件路径，如：static/js/a.js
+//   返回
+//     文件、错误信息
+func (root rootFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	// 检查过的文件直接打开，打开的还是同一个文件时不再去掉软链接检查
+	file := filepath.Join(string(root), filepath.FromSlash(name))
+	if v, ok := rootFiles.Load(file); ok {
+		if f, err := os.Open(file); err == nil {
+			if fi, err := f.Stat(); err == nil && os.SameFile(fi, v.(os.FileInfo)) {
+				return f, nil
+			}
+			f.Close()
+		}
+		rootFiles.Delete(file)
+	}
+
+	real, err := root.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(real)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := f.Stat(); err == nil {
+		rootFiles.Store(file, fi)
+	}
+	return f, nil
+}
+
+// resolve 去掉软链接，返回文件的真实路径，去掉软链接后不在根目录下时返回错误
+//   参数
+//     name: 文件路径
+//   返回
+//     真实路径、错误信息
+func (root rootFS) resolve(name string) (string, error) {
+	dir, err := filepath.EvalSymlinks(string(root))
+	if err != nil {
+		return "", &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	real, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		return "", &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	dir, _ = filepath.Abs(dir)
+	real, _ = filepath.Abs(real)
+	if real != dir && !strings.HasPrefix(real, strings.TrimRight(dir, string(filepath.Separator))+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: errors.New("outside of the root")}
+	}
+	return real, nil
+}
+
+// overlayFS 两层文件系统，上层存在的文件优先，目录合并两层的内容
+type overlayFS struct {
+	upper fs.FS // 上层，如：磁盘
+	lower fs.FS // 下层，如：embed.FS
+}
+
+// Open 打开文件，实现fs.FS接口
+//   参数
+//     name: 文件路径
+//   返回
+//     文件、错误信息
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if f, err := o.upper.Open(name); err == nil {
+		return f, nil
+	}
+	return o.lower.Open(name)
+}
+
+// ReadDir 读取目录，实现fs.ReadDirFS接口，同名的文件使用上层的
+//   参数
+//     name: 目录路径
+//   返回
+//     按文件名排序的目录内容、错误信息
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, uerr := fs.ReadDir(o.upper, name)
+	lower, lerr := fs.ReadDir(o.lower, name)
+	if uerr != nil && lerr != nil {
+		return nil, uerr
+	}
+
+	seen := make(map[string]bool, len(upper))
+	entries := make([]fs.DirEntry, 0, len(upper)+len(lower))
+	for _, e := range upper {
+		seen[e.Name()] = true
+		entries = append(entries, e)
+	}
+	for _, e := range lower {
+		if !seen[e.Name()] {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
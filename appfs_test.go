@@ -0,0 +1,140 @@
+package bingo
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// TestAppFS 测试应用文件系统
+func TestAppFS(t *testing.T) {
+	appRoot := AppRoot
+	runMode := AppCfg.RunMode
+	webCfg := AppCfg.WebCfg
+	defer func() {
+		AppRoot = appRoot
+		AppCfg.RunMode = runMode
+		AppCfg.WebCfg = webCfg
+		SetFS(nil)
+	}()
+
+	root, err := ioutil.TempDir("", "approot")
+	if err != nil {
+		t.Fatalf("TempDir failed, err: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+	os.MkdirAll(filepath.Join(root, "static"), 0755)
+	os.MkdirAll(filepath.Join(root, "views"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "static", "a.txt"), []byte("disk"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "views", "a.html"), []byte("disk a"), 0644)
+
+	AppRoot = root
+	AppCfg.WebCfg.StaticDir = []string{"/static"}
+	SetFS(fstest.MapFS{
+		"static/a.txt":   &fstest.MapFile{Data: []byte("embed")},
+		"static/b.txt":   &fstest.MapFile{Data: []byte("embed b")},
+		"views/a.html":   &fstest.MapFile{Data: []byte("embed a")},
+		"views/b/c.html": &fstest.MapFile{Data: []byte("embed c")},
+	})
+
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	cases := []struct {
+		runMode string
+		url     string
+		code    int
+		body    string
+	}{
+		{DEV, "/static/a.txt", 200, "disk"},
+		{DEV, "/static/b.txt", 200, "embed b"},
+		{PROD, "/static/a.txt", 200, "embed"},
+		{PROD, "/static/none.txt", 404, ""},
+	}
+	for _, c := range cases {
+		AppCfg.RunMode = c.runMode
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest("GET", c.url, nil))
+		if w.Code != c.code || (c.body != "" && w.Body.String() != c.body) {
+			t.Errorf("%s %s failed, code: %d, body: %s", c.runMode, c.url, w.Code, w.Body.String())
+		}
+	}
+
+	// dev模式下模板目录合并磁盘和打包的文件
+	AppCfg.RunMode = DEV
+	tp := NewTemplateFS(appFS(), "views", ".html")
+	if err = tp.buildViews(); err != nil {
+		t.Fatalf("buildViews failed, err: %s", err.Error())
+	}
+	for name, body := range map[string]string{"a.html": "disk a", "b/c.html": "embed c"} {
+		w := httptest.NewRecorder()
+		if err = tp.ViewTemp.ExecuteTemplate(w, name, nil); err != nil || w.Body.String() != body {
+			t.Errorf("ExecuteTemplate %s failed, body: %s, err: %v", name, w.Body.String(), err)
+		}
+	}
+}
+
+// TestRootFS 测试磁盘文件系统不能访问根目录以外的文件
+func TestRootFS(t *testing.T) {
+	root, err := ioutil.TempDir("", "root")
+	if err != nil {
+		t.Fatalf("TempDir failed, err: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+	outside, err := ioutil.TempDir("", "outside")
+	if err != nil {
+		t.Fatalf("TempDir failed, err: %s", err.Error())
+	}
+	defer os.RemoveAll(outside)
+
+	ioutil.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+	os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "secret.txt"))
+	os.Symlink(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt"))
+
+	fsys := rootFS(root)
+	for name, ok := range map[string]bool{"a.txt": true, "b.txt": true, "secret.txt": false, "../a.txt": false} {
+		_, err := fs.ReadFile(fsys, name)
+		if (err == nil) != ok {
+			t.Errorf("ReadFile %s failed, err: %v", name, err)
+		}
+	}
+
+	// 检查过的文件缓存检查结果，软链接改到根目录外后重新检查
+	if _, ok := rootFiles.Load(filepath.Join(root, "b.txt")); !ok {
+		t.Errorf("rootFiles failed. b.txt is not cached.")
+	}
+	os.Remove(filepath.Join(root, "b.txt"))
+	os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "b.txt"))
+	if b, err := fs.ReadFile(fsys, "b.txt"); err == nil {
+		t.Errorf("ReadFile b.txt after relink failed. Got %s, expected error.", b)
+	}
+}
+
+// TestAppRel 测试磁盘目录转成应用文件系统里的路径
+func TestAppRel(t *testing.T) {
+	appRoot := AppRoot
+	defer func() {
+		AppRoot = appRoot
+	}()
+	AppRoot = "/data/demo"
+
+	cases := []struct {
+		dir string
+		rel string
+		ok  bool
+	}{
+		{"/data/demo/views", "views", true},
+		{"/data/demo/a/lang", "a/lang", true},
+		{"/data/demo", ".", true},
+		{"/data/views", "", false},
+	}
+	for _, c := range cases {
+		if rel, ok := appRel(c.dir); rel != c.rel || ok != c.ok {
+			t.Errorf("appRel(%s) = %s, %v, want %s, %v", c.dir, rel, ok, c.rel, c.ok)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/lixy529/gotools/config"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -51,8 +52,15 @@ type AppConfig struct {
 	DbConfigs []DbConfig
 	CacheCfgs []CacheConfig
 	MongoCfgs []MongoConfig
-	Log       LogConfig  // 业务使用的日志配置
-	LangCfg   LangConfig // 语言包配置
+	Log       LogConfig       // 业务使用的日志配置
+	LangCfg   LangConfig      // 语言包配置
+	CorsCfg   CorsConfig      // 跨域配置
+	ReportCfg ReportConfig    // 错误上报配置
+	AccessCfg AccessLogConfig // 访问日志配置
+	MetricCfg MetricsConfig   // 监控指标配置
+	HealthCfg HealthConfig    // 健康检查配置
+	AdminCfg  AdminConfig     // 管理端口配置
+	TraceCfg  TraceConfig     // 链路追踪配置
 	MqConfigs map[string]*MqConfig
 }
 
@@ -72,16 +80,23 @@ type ServerConfig struct {
 	Addr       string
 	Port       int
 	ReqTimeout time.Duration // 请求超时时间，单位秒
-	MaxGoCnt   int           // 最大协程数，<=0 不限制
+	MaxGoCnt   int           // 已废弃，同MaxInflight
 	CertFile   string
 	KeyFile    string
 
+	MaxInflight  int           // 同时处理的最大请求数，<=0 不限制，没有配置时使用max_gocnt
+	MaxQueue     int           // 达到最大请求数时排队的最大请求数，<=0 不排队
+	QueueTimeout time.Duration // 排队的超时时间，单位毫秒，默认100
+	RetryAfter   int           // 拒绝请求时Retry-After头的值，单位秒，默认1
+
 	ForwardName string // 有代理转发时需要设置，获取真实的客户端IP
 	ForwardRev  bool   // true-按倒序排，false-按顺序排
 
-	Url404 string // 404跳转url地址
-	Url500 string // 500跳转url地址
-	Url502 string // 502跳转url地址
+	RequestIdHeader string // 请求ID的Header名称，默认X-Request-Id
+
+	Url404 string // 404跳转url地址，没有设置错误处理时使用，见RouterTab.SetErrorHandler
+	Url500 string // 500跳转url地址，没有设置错误处理时使用
+	Url502 string // 502跳转url地址，没有设置错误处理时使用
 }
 
 // WebConfig web相关配置
@@ -89,6 +104,10 @@ type WebConfig struct {
 	StaticDir []string // 静态文件目录
 	ViewsDir  string   // 模板文件目录，默认views
 	ViewsExt  string   // 模板文件扩展名，默认html
+
+	StaticCache map[string]string // 静态文件的Cache-Control，key为目录，按最长前缀匹配
+	StaticIndex bool              // 访问静态目录时是否输出目录下的index.html，默认true
+	StaticSpa   map[string]string // SPA回退，key为路径前缀，value为相对AppRoot的入口文件
 }
 
 // SessionConfig session相关配置
@@ -211,6 +230,11 @@ func newAppConfig() (*AppConfig, error) {
 			CertFile:   GlobalCfg.GetString("server", "cert_file", ""),
 			KeyFile:    GlobalCfg.GetString("server", "key_file", ""),
 
+			MaxInflight:  GlobalCfg.GetInt("server", "max_inflight", GlobalCfg.GetInt("server", "max_gocnt", 0)),
+			MaxQueue:     GlobalCfg.GetInt("server", "max_queue", 0),
+			QueueTimeout: time.Duration(GlobalCfg.GetInt("server", "queue_timeout", 100)),
+			RetryAfter:   GlobalCfg.GetInt("server", "retry_after", 1),
+
 			GzipStatus: gzipStatus,
 			GzipLevel:  gzipLevel,
 			GzipMinLen: gzipMinLen,
@@ -218,6 +242,8 @@ func newAppConfig() (*AppConfig, error) {
 			ForwardName: GlobalCfg.GetString("server", "forward_name", ""),
 			ForwardRev:  GlobalCfg.GetBool("server", "forward_rev", true),
 
+			RequestIdHeader: GlobalCfg.GetString("server", "request_id_header", defaultRequestIdHeader),
+
 			Url404: GlobalCfg.GetString("server", "url_404", ""),
 			Url500: GlobalCfg.GetString("server", "url_500", ""),
 			Url502: GlobalCfg.GetString("server", "url_502", ""),
@@ -227,6 +253,10 @@ func newAppConfig() (*AppConfig, error) {
 			StaticDir: strings.Split(GlobalCfg.GetString("web", "static_dir", "prod"), ","),
 			ViewsDir:  getViewsDir(),
 			ViewsExt:  GlobalCfg.GetString("web", "views_ext", ".html"),
+
+			StaticCache: getPathMap("static_cache"),
+			StaticIndex: GlobalCfg.GetBool("web", "static_index", true),
+			StaticSpa:   getPathMap("static_spa"),
 		},
 
 		SessCfg: SessionConfig{
@@ -247,6 +277,45 @@ func newAppConfig() (*AppConfig, error) {
 		LangCfg: LangConfig{
 			LangPath: getLangPath(),
 		},
+		CorsCfg: getCorsCfg(),
+		ReportCfg: ReportConfig{
+			File:           GlobalCfg.GetString("report", "file", ""),
+			Webhook:        GlobalCfg.GetString("report", "webhook", ""),
+			WebhookTimeout: time.Duration(GlobalCfg.GetInt("report", "webhook_timeout", 3000)),
+			Window:         time.Duration(GlobalCfg.GetInt("report", "window", 60)),
+		},
+		AccessCfg: AccessLogConfig{
+			Format:    GlobalCfg.GetString("access_log", "format", AccessFormatBingo),
+			File:      GlobalCfg.GetString("access_log", "file", ""),
+			SkipPaths: splitList(GlobalCfg.GetString("access_log", "skip_paths", "")),
+			Sample:    GlobalCfg.GetFloat64("access_log", "sample", 1),
+		},
+		MetricCfg: MetricsConfig{
+			On:      GlobalCfg.GetBool("metrics", "on", false),
+			Path:    GlobalCfg.GetString("metrics", "path", "/metrics"),
+			Addr:    GlobalCfg.GetString("metrics", "addr", ""),
+			Buckets: getBuckets(GlobalCfg.GetString("metrics", "buckets", "")),
+		},
+		HealthCfg: HealthConfig{
+			On:         GlobalCfg.GetBool("health", "on", false),
+			LivePath:   GlobalCfg.GetString("health", "live_path", "/healthz"),
+			ReadyPath:  GlobalCfg.GetString("health", "ready_path", "/readyz"),
+			Timeout:    time.Duration(GlobalCfg.GetInt("health", "timeout", 1000)),
+			DrainDelay: time.Duration(GlobalCfg.GetInt("health", "drain_delay", 0)),
+		},
+		AdminCfg: AdminConfig{
+			On:    GlobalCfg.GetBool("admin", "on", false),
+			Addr:  GlobalCfg.GetString("admin", "addr", "127.0.0.1:8090"),
+			Token: GlobalCfg.GetString("admin", "token", ""),
+		},
+		TraceCfg: TraceConfig{
+			On:          GlobalCfg.GetBool("trace", "on", false),
+			Sample:      GlobalCfg.GetFloat64("trace", "sample", 1),
+			File:        GlobalCfg.GetString("trace", "file", ""),
+			Otlp:        GlobalCfg.GetString("trace", "otlp", ""),
+			OtlpTimeout: time.Duration(GlobalCfg.GetInt("trace", "otlp_timeout", 3000)),
+			Service:     GlobalCfg.GetString("trace", "service", ""),
+		},
 	}, nil
 }
 
@@ -282,6 +351,33 @@ func getViewsDir() string {
 	return path.Join(AppRoot, v)
 }
 
+// getPathMap 解析web段中按路径配置的项
+// 格式为：路径=值;路径=值，如：/static=public, max-age=86400;/data=no-cache
+//   参数
+//     key: 配置项名称
+//   返回
+//     路径对应的值，没有配置时返回nil
+func getPathMap(key string) map[string]string {
+	v := GlobalCfg.GetString("web", key, "")
+	if v == "" {
+		return nil
+	}
+
+	m := make(map[string]string)
+	for _, item := range strings.Split(v, ";") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		p, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if p == "" || val == "" {
+			continue
+		}
+		m[p] = val
+	}
+	return m
+}
+
 // getDbConfig 获取db配置
 //   参数
 //
@@ -424,6 +520,54 @@ func getLangPath() string {
 	return langPath
 }
 
+// getCorsCfg 返回跨域配置，取配置文件的[cors]段
+//   参数
+//     void
+//   返回
+//     跨域配置，没有配置allow_origins时不处理跨域
+func getCorsCfg() CorsConfig {
+	return CorsConfig{
+		AllowOrigins:     splitList(GlobalCfg.GetString("cors", "allow_origins", "")),
+		AllowMethods:     splitList(GlobalCfg.GetString("cors", "allow_methods", "")),
+		AllowHeaders:     splitList(GlobalCfg.GetString("cors", "allow_headers", "")),
+		ExposeHeaders:    splitList(GlobalCfg.GetString("cors", "expose_headers", "")),
+		AllowCredentials: GlobalCfg.GetBool("cors", "allow_credentials", false),
+		MaxAge:           GlobalCfg.GetInt("cors", "max_age", 0),
+	}
+}
+
+// getBuckets 返回请求耗时分布区间
+//   参数
+//     v: 配置值，如：0.01,0.1,1，单位秒
+//   返回
+//     分布区间，没有配置或配置错误时返回nil，使用默认的区间
+func getBuckets(v string) []float64 {
+	var buckets []float64
+	for _, item := range splitList(v) {
+		f, err := strconv.ParseFloat(item, 64)
+		if err != nil || f <= 0 {
+			return nil
+		}
+		buckets = append(buckets, f)
+	}
+	return buckets
+}
+
+// splitList 按逗号拆分配置，去掉空白和空项
+//   参数
+//     v: 配置值，如：GET, POST
+//   返回
+//     拆分后的列表
+func splitList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 // GetString 根据key值获取对应的value值，返回结果为string型
 // 如果key值不存在就返回默认值
 //   参数
@@ -4,6 +4,7 @@
 package bingo
 
 import (
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -15,6 +16,7 @@ import (
 	"strings"
 	"bytes"
 	"encoding/json"
+	"github.com/lixy529/gotools/logs"
 	"github.com/lixy529/gotools/utils"
 	"html/template"
 	"encoding/xml"
@@ -341,7 +343,8 @@ func (c *Controller) ParamString(key string, def ...string) string {
 //     value值
 func (c *Controller) ParamInt(key string, def ...int) (int, error) {
 	v, _ := c.Req.pathParam[key]
-	if len(v) == 0 && len(def) > 0 {
+	if len(v) == 0 {
+		def = append(def, 0)
 		return def[0], nil
 	}
 
@@ -671,7 +674,9 @@ func (c *Controller) Assign(key string, val interface{}) {
 //   返回
 //     void
 func (c *Controller) Display(tplFile string) {
+	_, span := startSpan(c.Context(), "template "+tplFile, SpanInternal)
 	err := GTemplate.ViewTemp.ExecuteTemplate(c.Rsp.w, tplFile, c.tplData)
+	span.end(err)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -891,6 +896,72 @@ func (c *Controller) Redirect(urlStr string, status ...int) {
 	http.Redirect(c.Rsp.w, c.Req.r, urlStr, code)
 }
 
+// Context 返回请求的context，请求超时或客户端断开连接时会被取消
+// 耗时的操作应该使用它，如：Model的FetchOneContext、ExecContext等
+//   参数
+//     void
+//   返回
+//     请求的context
+func (c *Controller) Context() context.Context {
+	return c.Req.Context()
+}
+
+// ContextSetter 可以设置请求context的对象，bingo.Model和内嵌了bingo.Model的模型都实现了此接口
+type ContextSetter interface {
+	SetContext(ctx context.Context)
+}
+
+// InitModel 把请求的context设置到模型里，返回模型本身
+// 模型由业务代码直接创建，框架无法自动设置，开启链路追踪时只有调用了InitModel或SetContext的模型才记录数据库和缓存操作的span，如：
+//   m := &models.DemoModel{}
+//   c.InitModel(m)
+//   参数
+//     m: 模型，如：&models.DemoModel{}
+//   返回
+//     模型
+func (c *Controller) InitModel(m ContextSetter) ContextSetter {
+	m.SetContext(c.Context())
+	return m
+}
+
+// RequestId 返回请求ID，访问日志和错误上报里使用同一个ID
+//   参数
+//     void
+//   返回
+//     请求ID
+func (c *Controller) RequestId() string {
+	return c.Req.RequestId()
+}
+
+// Logger 返回请求相关的业务日志，每条日志前加上请求ID
+//   参数
+//     void
+//   返回
+//     日志对象
+func (c *Controller) Logger() logs.Logger {
+	return NewReqLogger(Glogger, c.RequestId())
+}
+
+// URLFor 根据路由名称生成url，参数同RouterTab.URLFor
+//   参数
+//     name:   路由名称
+//     params: 参数
+//   返回
+//     成功返回url，失败返回错误信息
+func (c *Controller) URLFor(name string, params ...interface{}) (string, error) {
+	return Router.URLFor(name, params...)
+}
+
+// GetError 返回错误信息，只在错误处理的控制器方法里有值
+//   参数
+//     void
+//   返回
+//     错误信息，不是错误处理时返回nil
+func (c *Controller) GetError() *HttpError {
+	herr, _ := c.Req.Context().Value(httpErrorKey{}).(*HttpError)
+	return herr
+}
+
 // Error 输出error http code
 //   参数
 //     errCode: 错误code
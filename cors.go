@@ -0,0 +1,271 @@
+// 跨域处理
+// 全局的配置取配置文件的[cors]段，分组可以用SetCors覆盖，预检请求在分发到控制器之前应答
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultCorsMethods 默认允许的跨域请求方法
+var defaultCorsMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// CorsConfig 跨域配置
+type CorsConfig struct {
+	AllowOrigins     []string // 允许的来源，支持*、通配子域名(如：https://*.example.com)、~开头的正则(如：~^https://.*\.example\.com$)
+	AllowMethods     []string // 允许的请求方法，默认GET、HEAD、POST、PUT、PATCH、DELETE
+	AllowHeaders     []string // 允许的请求头，为空时允许预检请求里的所有请求头
+	ExposeHeaders    []string // 允许浏览器读取的应答头
+	AllowCredentials bool     // 是否允许携带cookie
+	MaxAge           int      // 预检结果的缓存时间，单位秒，<=0 不设置
+}
+
+// cors 编译后的跨域配置
+type cors struct {
+	anyOrigin bool             // 是否允许所有来源
+	origins   map[string]bool  // 精确匹配的来源，已转成小写
+	wildcards [][2]string      // 通配子域名的来源，*前后两部分
+	regexps   []*regexp.Regexp // 正则匹配的来源
+	methods   string           // Access-Control-Allow-Methods
+	headers   string           // Access-Control-Allow-Headers，为空时回显请求头
+	expose    string           // Access-Control-Expose-Headers
+	cfg       CorsConfig
+}
+
+// newCors 编译跨域配置，正则错误时panic
+//   参数
+//     cfg: 跨域配置
+//   返回
+//     cors对象地址
+func newCors(cfg CorsConfig) *cors {
+	c := &cors{origins: make(map[string]bool), cfg: cfg}
+	for _, origin := range cfg.AllowOrigins {
+		origin = strings.TrimSpace(origin)
+		switch {
+		case origin == "":
+		case origin == "*":
+			c.anyOrigin = true
+		case strings.HasPrefix(origin, "~"):
+			re, err := regexp.Compile(origin[1:])
+			if err != nil {
+				panic(fmt.Sprintf("cors: origin [%s] is error, %s", origin, err.Error()))
+			}
+			c.regexps = append(c.regexps, re)
+		case strings.Contains(origin, "*"):
+			pos := strings.Index(origin, "*")
+			c.wildcards = append(c.wildcards, [2]string{strings.ToLower(origin[:pos]), strings.ToLower(origin[pos+1:])})
+		default:
+			c.origins[strings.ToLower(origin)] = true
+		}
+	}
+
+	methods := cfg.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCorsMethods
+	}
+	c.methods = strings.ToUpper(strings.Join(methods, ", "))
+	c.headers = strings.Join(cfg.AllowHeaders, ", ")
+	c.expose = strings.Join(cfg.ExposeHeaders, ", ")
+	return c
+}
+
+// allowOrigin 判断是否允许来源
+//   参数
+//     origin: 请求的Origin头
+//   返回
+//     允许返回true，否则返回false
+func (c *cors) allowOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if c.origins[lower] {
+		return true
+	}
+	for _, w := range c.wildcards {
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			return true
+		}
+	}
+	for _, re := range c.regexps {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowMethod 判断预检请求的方法是否允许
+//   参数
+//     method: 预检请求的Access-Control-Request-Method头
+//   返回
+//     允许返回true，否则返回false
+func (c *cors) allowMethod(method string) bool {
+	for _, m := range strings.Split(c.methods, ", ") {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// setOrigin 设置允许来源的应答头
+//   参数
+//     h:      应答头
+//     origin: 请求的Origin头
+//   返回
+//     void
+func (c *cors) setOrigin(h http.Header, origin string) {
+	if c.anyOrigin && !c.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	h.Add("Vary", "Origin")
+	if c.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// handle 处理跨域请求，来源不允许时不设置跨域应答头
+//   参数
+//     w:         ResponseWriter对象
+//     r:         Request对象
+//     preflight: 是否是预检请求
+//   返回
+//     void
+func (c *cors) handle(w http.ResponseWriter, r *http.Request, preflight bool) {
+	h := w.Header()
+	origin := r.Header.Get("Origin")
+	if !preflight {
+		if c.allowOrigin(origin) {
+			c.setOrigin(h, origin)
+			if c.expose != "" {
+				h.Set("Access-Control-Expose-Headers", c.expose)
+			}
+		} else if !c.anyOrigin {
+			h.Add("Vary", "Origin")
+		}
+		return
+	}
+
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if !c.allowOrigin(origin) || !c.allowMethod(method) {
+		return
+	}
+
+	c.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", c.methods)
+	if c.headers != "" {
+		h.Set("Access-Control-Allow-Headers", c.headers)
+	} else if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+		h.Set("Access-Control-Allow-Headers", reqHeaders)
+	}
+	if c.cfg.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(c.cfg.MaxAge))
+	}
+}
+
+// SetCors 设置全局的跨域配置，默认使用配置文件的[cors]段
+//   参数
+//     cfg: 跨域配置，AllowOrigins为空时关闭跨域处理
+//   返回
+//     void
+func (rt *RouterTab) SetCors(cfg CorsConfig) {
+	if len(cfg.AllowOrigins) == 0 {
+		rt.cors = nil
+		return
+	}
+	rt.cors = newCors(cfg)
+}
+
+// SetCors 设置分组的跨域配置，覆盖全局和上级分组的配置，对分组和下级分组里的路由生效
+//   参数
+//     cfg: 跨域配置，AllowOrigins为空时分组不允许跨域
+//   返回
+//     void
+func (g *RouterGroup) SetCors(cfg CorsConfig) {
+	g.cors = newCors(cfg)
+}
+
+// getCors 返回分组的跨域配置，没有设置时取上级分组的
+//   参数
+//     void
+//   返回
+//     跨域配置，都没有设置时返回nil
+func (g *RouterGroup) getCors() *cors {
+	for ; g != nil; g = g.parent {
+		if g.cors != nil {
+			return g.cors
+		}
+	}
+	return nil
+}
+
+// hasCors 分组或上级分组是否设置了跨域配置
+//   参数
+//     void
+//   返回
+//     有返回true，否则返回false
+func (g *RouterGroup) hasCors() bool {
+	return g.getCors() != nil
+}
+
+// isPreflight 是否跨域的预检请求
+//   参数
+//     r: Request对象
+//   返回
+//     是返回true，否则返回false
+func isPreflight(r *http.Request) bool {
+	return r.Method == "OPTIONS" && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// handleCors 处理跨域请求，按请求匹配到的路由所在的分组取配置
+// 预检请求匹配到路由时直接应答204，没有匹配到路由时按普通请求处理
+//   参数
+//     w:         ResponseWriter对象
+//     r:         Request对象
+//     host:      请求域名
+//     realPath:  请求路径
+//     routeInfo: dispatch匹配到的路由，预检请求按Access-Control-Request-Method匹配，没有匹配到时为nil
+//   返回
+//     已应答预检请求返回true，否则返回false
+func (rt *RouterTab) handleCors(w http.ResponseWriter, r *http.Request, host, realPath string, routeInfo *RouterInfo) bool {
+	if r.Header.Get("Origin") == "" {
+		return false
+	}
+
+	var g *RouterGroup
+	if routeInfo != nil {
+		g = routeInfo.group
+	} else {
+		g = rt.matchGroup(host, realPath, (*RouterGroup).hasCors)
+	}
+	c := g.getCors()
+	if c == nil {
+		c = rt.cors
+	}
+	if c == nil {
+		return false
+	}
+
+	if isPreflight(r) {
+		if routeInfo == nil {
+			// 请求方法没有对应的路由，不允许跨域
+			return false
+		}
+		c.handle(w, r, true)
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+	c.handle(w, r, false)
+	return false
+}
@@ -0,0 +1,104 @@
+package bingo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCorsOrigin 测试来源匹配
+func TestCorsOrigin(t *testing.T) {
+	c := newCors(CorsConfig{AllowOrigins: []string{"https://www.a.com", "https://*.b.com", `~^https://[a-z]+\.c\.net$`}})
+	cases := map[string]bool{
+		"https://www.a.com":    true,
+		"https://WWW.A.COM":    true,
+		"http://www.a.com":     false,
+		"https://x.b.com":      true,
+		"https://x.y.b.com":    true,
+		"https://b.com":        false,
+		"https://.b.com":       false,
+		"https://abc.c.net":    true,
+		"https://abc.c.net.cn": false,
+		"":                     false,
+	}
+	for origin, ok := range cases {
+		if c.allowOrigin(origin) != ok {
+			t.Errorf("allowOrigin(%s) failed. Got %v, expected %v.", origin, !ok, ok)
+		}
+	}
+
+	mustPanic(t, "bad regexp", func() {
+		newCors(CorsConfig{AllowOrigins: []string{"~("}})
+	})
+}
+
+// TestCors 测试跨域请求和预检请求
+func TestCors(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.SetCors(CorsConfig{AllowOrigins: []string{"*"}, ExposeHeaders: []string{"X-Total"}})
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+	rt.HandleFunc("/pub", ok, WithMethods("GET", "POST"))
+	api := rt.Group("/api")
+	api.SetCors(CorsConfig{
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowMethods:     []string{"GET", "PUT"},
+		AllowHeaders:     []string{"Content-Type", "X-Token"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+	api.HandleFunc("/user", ok, WithMethods("GET", "PUT"))
+	internal := api.Group("/internal")
+	internal.SetCors(CorsConfig{})
+	internal.HandleFunc("/stat", ok)
+
+	cases := []struct {
+		method  string
+		url     string
+		origin  string
+		acrm    string
+		status  int
+		headers map[string]string
+	}{
+		{"GET", "/pub", "https://any.com", "", 200, map[string]string{
+			"Access-Control-Allow-Origin": "*", "Access-Control-Expose-Headers": "X-Total"}},
+		{"OPTIONS", "/pub", "https://any.com", "POST", 204, map[string]string{
+			"Access-Control-Allow-Origin": "*", "Access-Control-Allow-Methods": "GET, HEAD, POST, PUT, PATCH, DELETE"}},
+		{"GET", "/pub", "", "", 200, map[string]string{"Access-Control-Allow-Origin": ""}},
+		{"OPTIONS", "/api/user", "https://app.example.com", "PUT", 204, map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Allow-Methods":     "GET, PUT",
+			"Access-Control-Allow-Headers":     "Content-Type, X-Token",
+			"Access-Control-Max-Age":           "600",
+		}},
+		{"OPTIONS", "/api/user", "https://app.example.com", "DELETE", 204, map[string]string{"Access-Control-Allow-Origin": ""}},
+		{"OPTIONS", "/api/user", "https://evil.com", "PUT", 204, map[string]string{"Access-Control-Allow-Origin": ""}},
+		{"PUT", "/api/user", "https://app.example.com", "", 200, map[string]string{
+			"Access-Control-Allow-Origin": "https://app.example.com", "Vary": "Origin"}},
+		{"GET", "/api/none", "https://app.example.com", "", 404, map[string]string{
+			"Access-Control-Allow-Origin": "https://app.example.com"}},
+		{"GET", "/api/internal/stat", "https://app.example.com", "", 200, map[string]string{"Access-Control-Allow-Origin": ""}},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(c.method, c.url, nil)
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		if c.acrm != "" {
+			r.Header.Set("Access-Control-Request-Method", c.acrm)
+		}
+		rt.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s %s %s failed. Got status %d, expected %d.", c.method, c.url, c.origin, w.Code, c.status)
+		}
+		for k, v := range c.headers {
+			if got := w.Header().Get(k); got != v {
+				t.Errorf("%s %s %s failed. Got %s [%s], expected [%s].", c.method, c.url, c.origin, k, got, v)
+			}
+		}
+	}
+}
@@ -268,6 +268,7 @@ func (c *DemoController) CacheAction() {
 		return
 	}
 	m := &models.DemoModel{}
+	c.InitModel(m)
 	val, err := m.CacheTest(cacheName)
 	if err != nil {
 		c.WriteString("Cache error," + err.Error() + "<br />")
@@ -280,6 +281,7 @@ func (c *DemoController) CacheAction() {
 // dbTestAction db测试
 func (c *DemoController) DbTestAction() {
 	m := &models.DemoModel{}
+	c.InitModel(m)
 
 	// 插入
 	uid, err := m.InsertInfo("letv", 100, "北京市")
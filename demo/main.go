@@ -4,11 +4,26 @@
 package main
 
 import (
+	"embed"
+	"os"
+
 	"github.com/lixy529/bingo"
 	_ "github.com/lixy529/bingo/demo/routers"
 	_ "github.com/lixy529/bingo/session/memcache"
 )
 
+// assets 打包到程序里的模板和语言包，dev模式下优先使用APPROOT下的文件
+//
+//go:embed views lang
+var assets embed.FS
+
 func main() {
+	bingo.SetFS(assets)
+
+	// 带参数时以脚本形式启动，如：./demo bingo:routes
+	if len(os.Args) > 1 {
+		bingo.ObjApp.RunShell(os.Args[1])
+		return
+	}
 	bingo.ObjApp.Run()
 }
@@ -10,7 +10,6 @@ func init() {
 	// 固定路由
 	bingo.Router.AddFixed("/demo", &controllers.DemoController{}, "IndexAction")
 	bingo.Router.AddFixed("/demo/demo1", &controllers.DemoController{}, "Demo1Action")
-	bingo.Router.AddFixed("/v2.1/demo/demo2", &controllers.DemoController{}, "Demo2Action")
 
 	// 正则路由
 	bingo.Router.AddRegular("^/$", &controllers.DemoController{}, "IndexAction")
@@ -18,7 +17,11 @@ func init() {
 
 	// 自动路由
 	bingo.Router.AddAuto(&controllers.DemoController{})
-	bingo.Router.AddAuto(&api.UserController{}, true, "v2.1")
+
+	// 路由分组
+	v21 := bingo.Router.Group("/v2.1")
+	v21.AddFixed("/demo/demo2", &controllers.DemoController{}, "Demo2Action")
+	v21.AddAuto(&api.UserController{}, true)
 
 	// 脚本路由
 	bingo.Router.AddShell("index", controllers.IndexAction,)
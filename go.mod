@@ -1,9 +1,10 @@
 module github.com/lixy529/bingo
 
-go 1.13
+go 1.16
 
 require (
 	github.com/bradfitz/gomemcache v0.0.0-20190913173617-a41fca850d0b
+	github.com/go-redis/redis v6.15.6+incompatible
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/lixy529/gotools v0.0.1
 )
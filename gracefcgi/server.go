@@ -29,6 +29,10 @@ type Server struct {
 	endRunning chan bool
 	isStop     bool
 	isRestart  bool
+
+	OnShutdown      func()        // Called after receiving a stop or restart signal, before the old process stops serving.
+	OnRestartFailed func()        // Called when the new process fails to start, the old process continues to serve.
+	DrainDelay      time.Duration // Wait after OnShutdown before the old process stops serving, so load balancers can stop sending traffic.
 }
 
 func NewServer(addr string, port int, handler http.Handler, shutTimeout time.Duration) *Server {
@@ -125,11 +129,28 @@ func (srv *Server) Start() error {
 	go srv.handleSignals()
 	go srv.Serve(srv.listener, srv.handler)
 
-	<-srv.endRunning
-
-	// Start a sub process.
-	if srv.isRestart {
-		srv.startNewProcess()
+	// Start a sub process, if it fails, the old process continues to serve and waits for the next signal.
+	for {
+		<-srv.endRunning
+		if srv.OnShutdown != nil {
+			srv.OnShutdown()
+		}
+		if !srv.isRestart {
+			break
+		}
+		err := srv.startNewProcess()
+		if err == nil {
+			break
+		}
+		log.Printf("GraceFcgi: Start new process failed[%v], pid[%d] continue serve.\n", err, os.Getpid())
+		srv.isStop = false
+		if srv.OnRestartFailed != nil {
+			srv.OnRestartFailed()
+		}
+		go srv.handleSignals()
+	}
+	if srv.DrainDelay > 0 {
+		time.Sleep(srv.DrainDelay)
 	}
 
 	// Waiting...
@@ -24,6 +24,10 @@ type Server struct {
 	isRestart   bool
 	isHttps     bool
 	err         error
+
+	OnShutdown      func()        // Called after receiving a stop or restart signal, before the listener is closed.
+	OnRestartFailed func()        // Called when the new process fails to start, the old process continues to serve.
+	DrainDelay      time.Duration // Wait after OnShutdown before the listener is closed, so load balancers can stop sending traffic.
 }
 
 // NewServer return Server object.
@@ -126,16 +130,29 @@ func (srv *Server) Serve() error {
 		}
 		srv.endRunning <- true
 	}()
-	<-srv.endRunning
-
 	// If it is restarted, start a new process first, then close the old process, otherwise close the old process directly.
+	// If the new process fails to start, the old process continues to serve and waits for the next signal.
 	pid := os.Getpid()
-	if srv.isRestart {
+	for {
+		<-srv.endRunning
+		if srv.OnShutdown != nil {
+			srv.OnShutdown()
+		}
+		if !srv.isRestart {
+			break
+		}
 		err := srv.startNewProcess()
-		if err != nil {
-			log.Printf("GraceHttp: Start new process failed[%v], pid[%d] continue serve.\n", err, pid)
-			return err
+		if err == nil {
+			break
+		}
+		log.Printf("GraceHttp: Start new process failed[%v], pid[%d] continue serve.\n", err, pid)
+		if srv.OnRestartFailed != nil {
+			srv.OnRestartFailed()
 		}
+		go srv.handleSignals()
+	}
+	if srv.DrainDelay > 0 {
+		time.Sleep(srv.DrainDelay)
 	}
 	ctx, _ := context.WithTimeout(context.Background(), srv.shutTimeout)
 	srv.httpServer.Shutdown(ctx)
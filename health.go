@@ -0,0 +1,330 @@
+// 健康检查
+// /healthz 存活检查，进程能处理请求就返回200
+// /readyz  就绪检查，初始化完成后并发执行数据库、缓存、session和用户添加的检查，全部通过返回200，否则返回503
+// 服务关闭或重启时就绪检查直接返回503，等待drain_delay后再关闭监听，负载均衡可以提前摘掉流量，配置取配置文件的[health]段
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lixy529/gotools/cache"
+)
+
+// 服务的状态
+const (
+	HealthStarting = "starting" // 初始化中
+	HealthReady    = "ready"    // 可以处理请求
+	HealthStopping = "stopping" // 关闭或重启中
+)
+
+// 检查结果
+const (
+	HealthOk   = "ok"
+	HealthFail = "fail"
+)
+
+// defaultHealthTimeout 默认的单项检查超时时间
+const defaultHealthTimeout = time.Second
+
+// healthCacheKey 检查缓存时读取的key
+const healthCacheKey = "bingo:healthz"
+
+// HealthConfig 健康检查配置
+type HealthConfig struct {
+	On         bool          // 是否开启
+	LivePath   string        // 存活检查的路径，默认/healthz
+	ReadyPath  string        // 就绪检查的路径，默认/readyz
+	Timeout    time.Duration // 单项检查的超时时间，单位毫秒，默认1000
+	DrainDelay time.Duration // 就绪检查开始失败后等待多久再关闭监听，单位毫秒，默认0
+}
+
+// HealthCheckFunc 检查函数，ctx超时后应尽快返回，返回nil表示通过
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthResult 单项检查的结果
+type HealthResult struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"` // ok | fail
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// HealthReport 健康检查的应答
+type HealthReport struct {
+	Status string         `json:"status"` // ok | fail
+	State  string         `json:"state"`  // starting | ready | stopping
+	Uptime float64        `json:"uptime"` // 进程运行的秒数
+	Checks []HealthResult `json:"checks,omitempty"`
+}
+
+// healthCheck 一项检查
+type healthCheck struct {
+	name    string
+	check   HealthCheckFunc
+	timeout time.Duration // 为0时使用默认的超时时间
+}
+
+var (
+	healthState   atomic.Value // 服务的状态
+	healthTimeout = int64(defaultHealthTimeout)
+	healthMu      sync.RWMutex
+	healthChecks  []*healthCheck // 用户添加的检查
+)
+
+func init() {
+	healthState.Store(HealthStarting)
+}
+
+// AddHealthCheck 添加就绪检查，同名的检查会被替换
+//   参数
+//     name:    检查名称，如：mq
+//     check:   检查函数
+//     timeout: 超时时间，为0时使用配置的超时时间
+//   返回
+//     void
+func AddHealthCheck(name string, check HealthCheckFunc, timeout time.Duration) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	hc := &healthCheck{name: name, check: check, timeout: timeout}
+	for i, c := range healthChecks {
+		if c.name == name {
+			healthChecks[i] = hc
+			return
+		}
+	}
+	healthChecks = append(healthChecks, hc)
+}
+
+// SetHealthState 设置服务的状态，不是ready时就绪检查返回503
+// 框架在初始化完成后设为ready，收到关闭或重启信号后设为stopping
+//   参数
+//     state: starting | ready | stopping
+//   返回
+//     void
+func SetHealthState(state string) {
+	healthState.Store(state)
+}
+
+// HealthState 返回服务的状态
+//   参数
+//     void
+//   返回
+//     starting | ready | stopping
+func HealthState() string {
+	return healthState.Load().(string)
+}
+
+// stopHealth 收到关闭或重启信号后调用，就绪检查开始返回失败
+//   参数
+//     void
+//   返回
+//     void
+func stopHealth() {
+	SetHealthState(HealthStopping)
+}
+
+// healthDrainDelay 返回就绪检查开始失败后到关闭监听的等待时间，没有开启健康检查时为0
+//   参数
+//     cfg: 健康检查配置
+//   返回
+//     等待时间
+func healthDrainDelay(cfg HealthConfig) time.Duration {
+	if !cfg.On || cfg.DrainDelay <= 0 {
+		return 0
+	}
+	return cfg.DrainDelay * time.Millisecond
+}
+
+// LivenessHandler 返回存活检查的http.Handler
+//   参数
+//     void
+//   返回
+//     http.Handler
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, &HealthReport{Status: HealthOk, State: HealthState()})
+	})
+}
+
+// ReadinessHandler 返回就绪检查的http.Handler
+//   参数
+//     void
+//   返回
+//     http.Handler
+func ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := &HealthReport{Status: HealthOk, State: HealthState()}
+		if report.State != HealthReady {
+			report.Status = HealthFail
+		} else {
+			report.Checks = runHealthChecks(r.Context())
+			for _, res := range report.Checks {
+				if res.Status != HealthOk {
+					report.Status = HealthFail
+					break
+				}
+			}
+		}
+		writeHealth(w, report)
+	})
+}
+
+// writeHealth 输出检查结果，失败时返回503
+//   参数
+//     w:      ResponseWriter对象
+//     report: 检查结果
+//   返回
+//     void
+func writeHealth(w http.ResponseWriter, report *HealthReport) {
+	report.Uptime = time.Since(processStart).Seconds()
+	b, _ := json.Marshal(report)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != HealthOk {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(b)
+}
+
+// runHealthChecks 并发执行所有检查
+//   参数
+//     ctx: 请求的context
+//   返回
+//     检查结果，顺序同检查的顺序
+func runHealthChecks(ctx context.Context) []HealthResult {
+	checks := builtinChecks()
+	healthMu.RLock()
+	checks = append(checks, healthChecks...)
+	healthMu.RUnlock()
+
+	results := make([]HealthResult, len(checks))
+	var wg sync.WaitGroup
+	for i, hc := range checks {
+		wg.Add(1)
+		go func(i int, hc *healthCheck) {
+			defer wg.Done()
+			results[i] = hc.run(ctx)
+		}(i, hc)
+	}
+	wg.Wait()
+	return results
+}
+
+// run 执行检查，超时或panic时返回失败
+// 检查函数不响应ctx时不等待其返回
+//   参数
+//     ctx: 请求的context
+//   返回
+//     检查结果
+func (hc *healthCheck) run(ctx context.Context) HealthResult {
+	timeout := hc.timeout
+	if timeout <= 0 {
+		timeout = time.Duration(atomic.LoadInt64(&healthTimeout))
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				done <- fmt.Errorf("panic: %v", err)
+			}
+		}()
+		done <- hc.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timeout after %s", timeout)
+	}
+
+	res := HealthResult{Name: hc.name, Status: HealthOk, DurationMs: float64(time.Since(start)) / float64(time.Millisecond)}
+	if err != nil {
+		res.Status = HealthFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// builtinChecks 返回框架组件的检查，包括数据库的主从库、各个缓存和session
+//   参数
+//     void
+//   返回
+//     检查列表
+func builtinChecks() []*healthCheck {
+	var checks []*healthCheck
+	if GlobalDb != nil {
+		for _, cfg := range AppCfg.DbConfigs {
+			h := GlobalDb.Db(cfg.dbName)
+			if h == nil {
+				continue
+			}
+			if db := h.GetMaster(); db != nil {
+				checks = append(checks, &healthCheck{name: "db:" + cfg.dbName + ":master", check: db.PingContext})
+			}
+			for i := range cfg.slaves {
+				if db := h.GetSlave(i); db != nil {
+					checks = append(checks, &healthCheck{name: fmt.Sprintf("db:%s:slave%d", cfg.dbName, i), check: db.PingContext})
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(cache.Adapters))
+	for name := range cache.Adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := cache.Adapters[name]
+		checks = append(checks, &healthCheck{name: "cache:" + name, check: func(ctx context.Context) error {
+			_, err := c.IsExist(healthCacheKey)
+			return err
+		}})
+	}
+
+	if GlobalSession != nil {
+		checks = append(checks, &healthCheck{name: "session", check: func(ctx context.Context) error {
+			return GlobalSession.Ping()
+		}})
+	}
+	return checks
+}
+
+// initHealth 按配置开启健康检查，挂在Router上，不经过准入控制、全局中间件和请求超时
+//   参数
+//     cfg: 健康检查配置
+//   返回
+//     void
+func (rt *RouterTab) initHealth(cfg HealthConfig) {
+	if !cfg.On {
+		return
+	}
+	if cfg.Timeout > 0 {
+		atomic.StoreInt64(&healthTimeout, int64(cfg.Timeout*time.Millisecond))
+	}
+
+	livePath, readyPath := cfg.LivePath, cfg.ReadyPath
+	if livePath == "" {
+		livePath = "/healthz"
+	}
+	if readyPath == "" {
+		readyPath = "/readyz"
+	}
+	rt.handleProbe(livePath, LivenessHandler())
+	rt.handleProbe(readyPath, ReadinessHandler())
+}
+
@@ -0,0 +1,161 @@
+package bingo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestHealth 测试存活检查、就绪检查和状态切换
+func TestHealth(t *testing.T) {
+	defer SetHealthState(HealthStarting)
+	defer func() { healthChecks = nil }()
+
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.initHealth(HealthConfig{On: true, Timeout: 50})
+	defer func() { healthTimeout = int64(defaultHealthTimeout) }()
+
+	get := func(url string) (int, *HealthReport) {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		report := &HealthReport{}
+		if err := json.Unmarshal(w.Body.Bytes(), report); err != nil {
+			t.Fatalf("Unmarshal [%s] failed. err: %s", w.Body.String(), err.Error())
+		}
+		return w.Code, report
+	}
+
+	// 初始化中，存活检查通过，就绪检查失败
+	SetHealthState(HealthStarting)
+	if code, report := get("/healthz"); code != http.StatusOK || report.Status != HealthOk || report.State != HealthStarting {
+		t.Errorf("healthz failed. Got %d %+v.", code, report)
+	}
+	if code, report := get("/readyz"); code != http.StatusServiceUnavailable || report.State != HealthStarting {
+		t.Errorf("readyz starting failed. Got %d %+v.", code, report)
+	}
+
+	// 就绪，检查全部通过
+	SetHealthState(HealthReady)
+	AddHealthCheck("mq", func(ctx context.Context) error { return nil }, 0)
+	code, report := get("/readyz")
+	if code != http.StatusOK || report.Status != HealthOk || len(report.Checks) != 1 || report.Checks[0].Name != "mq" {
+		t.Errorf("readyz failed. Got %d %+v.", code, report)
+	}
+
+	// 检查失败、超时、panic，同名检查被替换
+	AddHealthCheck("mq", func(ctx context.Context) error { return errors.New("mq down") }, 0)
+	AddHealthCheck("slow", func(ctx context.Context) error { time.Sleep(time.Second); return nil }, 0)
+	AddHealthCheck("panic", func(ctx context.Context) error { panic("oops") }, 0)
+	AddHealthCheck("ok", func(ctx context.Context) error { return nil }, time.Second)
+	start := time.Now()
+	code, report = get("/readyz")
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("readyz timeout failed. Took %s.", time.Since(start))
+	}
+	if code != http.StatusServiceUnavailable || report.Status != HealthFail || len(report.Checks) != 4 {
+		t.Fatalf("readyz fail failed. Got %d %+v.", code, report)
+	}
+	expected := []HealthResult{
+		{Name: "mq", Status: HealthFail, Error: "mq down"},
+		{Name: "slow", Status: HealthFail, Error: "timeout after 50ms"},
+		{Name: "panic", Status: HealthFail, Error: "panic: oops"},
+		{Name: "ok", Status: HealthOk},
+	}
+	for i, res := range expected {
+		got := report.Checks[i]
+		if got.Name != res.Name || got.Status != res.Status || got.Error != res.Error {
+			t.Errorf("readyz check failed. Got %+v, expected %+v.", got, res)
+		}
+	}
+
+	// 关闭中，就绪检查直接失败
+	stopHealth()
+	if code, report := get("/readyz"); code != http.StatusServiceUnavailable || report.State != HealthStopping || len(report.Checks) != 0 {
+		t.Errorf("readyz stopping failed. Got %d %+v.", code, report)
+	}
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("healthz stopping failed. Got %d.", code)
+	}
+}
+
+// TestHealthRestart 测试关闭时的等待时间和重启失败后恢复就绪
+func TestHealthRestart(t *testing.T) {
+	defer SetHealthState(HealthStarting)
+
+	for cfg, expected := range map[HealthConfig]time.Duration{
+		{On: true, DrainDelay: 500}:  500 * time.Millisecond,
+		{On: false, DrainDelay: 500}: 0,
+		{On: true}:                   0,
+	} {
+		if got := healthDrainDelay(cfg); got != expected {
+			t.Errorf("healthDrainDelay [%+v] failed. Got %s, expected %s.", cfg, got, expected)
+		}
+	}
+
+	SetHealthState(HealthReady)
+	stopHealth()
+	onRestartFailed()
+	if state := HealthState(); state != HealthReady {
+		t.Errorf("onRestartFailed failed. Got %s, expected %s.", state, HealthReady)
+	}
+}
+
+// TestHealthProbe 测试限流和全局中间件拒绝请求时健康检查和指标仍然可以访问
+func TestHealthProbe(t *testing.T) {
+	defer SetHealthState(HealthStarting)
+	SetHealthState(HealthReady)
+
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.SetLimit(1, 0, 0)
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+		})
+	})
+	rt.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	rt.initHealth(HealthConfig{On: true})
+	rt.initMetrics(MetricsConfig{On: true})
+
+	serve := func(method, url string) int {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w.Code
+	}
+
+	// 全局中间件拒绝
+	if code := serve("GET", "/hello"); code != http.StatusForbidden {
+		t.Errorf("hello failed. Got %d, expected %d.", code, http.StatusForbidden)
+	}
+	for _, url := range []string{"/healthz", "/readyz", "/metrics"} {
+		if code := serve("GET", url); code != http.StatusOK {
+			t.Errorf("%s with middleware failed. Got %d, expected %d.", url, code, http.StatusOK)
+		}
+	}
+
+	// 达到最大请求数
+	if !rt.limiter.acquire(context.Background()) {
+		t.Fatalf("acquire failed. Got false, expected true.")
+	}
+	defer rt.limiter.release()
+	if code := serve("GET", "/hello"); code != http.StatusServiceUnavailable {
+		t.Errorf("hello failed. Got %d, expected %d.", code, http.StatusServiceUnavailable)
+	}
+	for _, url := range []string{"/healthz", "/readyz", "/metrics"} {
+		if code := serve("HEAD", url); code != http.StatusOK {
+			t.Errorf("%s when limited failed. Got %d, expected %d.", url, code, http.StatusOK)
+		}
+	}
+
+	// 其他请求方法按普通请求处理
+	if code := serve("POST", "/healthz"); code != http.StatusServiceUnavailable {
+		t.Errorf("POST healthz failed. Got %d, expected %d.", code, http.StatusServiceUnavailable)
+	}
+}
@@ -30,6 +30,7 @@ var (
 	GlobalDb      *db.DbBase
 	Flogger       logs.Logger
 	Glogger       logs.Logger
+	Alogger       logs.Logger // 访问日志，没有配置单独的文件时同Flogger
 	GTemplate     *Template
 	GLang         *lang.Lang
 	inits         = make([]initfunc, 0)
@@ -44,6 +45,8 @@ var (
 func init() {
 	// 初始化
 	AddInitFunc(initFrameLog)
+	AddInitFunc(initAccessLog)
+	AddInitFunc(initTrace)
 	AddInitFunc(initPidFile)
 	AddInitFunc(initBusLog)
 	AddInitFunc(initSession)
@@ -54,7 +57,11 @@ func init() {
 	AddInitFunc(initGzip)
 
 	// 反初始化
+	AddUnInitFunc(unInitAdmin)
+	AddUnInitFunc(unInitMetrics)
 	AddUnInitFunc(unInitDb)
+	AddUnInitFunc(unInitTrace)
+	AddUnInitFunc(unInitAccessLog)
 	AddUnInitFunc(unInitFrameLog)
 	AddUnInitFunc(unInitPidFile)
 }
@@ -197,7 +204,34 @@ func initFrameLog() error {
 		return errors.New("Flogger is nil")
 	}
 
-	return Flogger.Init(logCfg)
+	if err := Flogger.Init(logCfg); err != nil {
+		return err
+	}
+	Flogger = newLevelLogger(Flogger)
+	return nil
+}
+
+// initAccessLog 初始化访问日志，没有配置单独的文件时写框架日志
+//   参数
+//     void
+//   返回
+//     成功返回nil，失败返回错误信息
+func initAccessLog() error {
+	if isShell {
+		return nil
+	}
+
+	if AppCfg.AccessCfg.File == "" {
+		Alogger = Flogger
+		return nil
+	}
+
+	l, err := newAccessLogger(AppCfg.AccessCfg.File)
+	if err != nil {
+		return fmt.Errorf("access log init err: %s", err.Error())
+	}
+	Alogger = newLevelLogger(l)
+	return nil
 }
 
 // initBusLog 初始化业务使用的日志
@@ -226,6 +260,7 @@ func initBusLog() error {
 			Flogger.Errorf("GLogger Init err: %s", err.Error())
 		}
 	}
+	Glogger = newLevelLogger(Glogger)
 
 	return nil
 }
@@ -240,7 +275,12 @@ func initViews() error {
 		return nil
 	}
 
-	GTemplate = NewTemplate(AppCfg.WebCfg.ViewsDir, AppCfg.WebCfg.ViewsExt)
+	// 设置了应用文件系统时从文件系统读取模板，见SetFS
+	if dir, ok := appRel(AppCfg.WebCfg.ViewsDir); appFSys != nil && ok {
+		GTemplate = NewTemplateFS(appFS(), dir, AppCfg.WebCfg.ViewsExt)
+	} else {
+		GTemplate = NewTemplate(AppCfg.WebCfg.ViewsDir, AppCfg.WebCfg.ViewsExt)
+	}
 	if GTemplate == nil {
 		return errors.New("Template is nil")
 	}
@@ -262,8 +302,13 @@ func initLang() error {
 		return nil
 	}
 
+	// 设置了应用文件系统时从文件系统读取语言包，见SetFS
 	var err error
-	GLang, err = lang.NewLang(AppCfg.LangCfg.LangPath)
+	if dir, ok := appRel(AppCfg.LangCfg.LangPath); appFSys != nil && ok {
+		GLang, err = lang.NewLangFS(appFS(), dir)
+	} else {
+		GLang, err = lang.NewLang(AppCfg.LangCfg.LangPath)
+	}
 	if err != nil {
 		return err
 	}
@@ -309,6 +354,17 @@ func unInitFrameLog() {
 	}
 }
 
+// unInitAccessLog 关闭访问日志
+//   参数
+//     void
+//   返回
+//     void
+func unInitAccessLog() {
+	if Alogger != nil && Alogger != Flogger {
+		Alogger.Destroy()
+	}
+}
+
 // unInitPidFile 删除pid文件
 //   参数
 //     void
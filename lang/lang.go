@@ -5,7 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
+	"os"
 	"path"
 	"strings"
 )
@@ -14,6 +15,7 @@ var LANG_EXT = ".json"
 
 // Lang language package struct.
 type Lang struct {
+	fsys     fs.FS                             // File system of language package files, nil means the local disk.
 	langPath string                            // Root directory of language package files.
 	data     map[string]map[string]interface{} // Language package content.
 }
@@ -33,6 +35,24 @@ func NewLang(root string) (*Lang, error) {
 	return l, err
 }
 
+// NewLangFS return Lang object, the language package files are read from fsys.
+// root is a slash-separated path in fsys, eg: "lang".
+// It can be used with go:embed to ship language packages in the binary.
+func NewLangFS(fsys fs.FS, root string) (*Lang, error) {
+	l := &Lang{
+		fsys:     fsys,
+		langPath: root,
+		data:     make(map[string]map[string]interface{}),
+	}
+
+	err := l.loadLang()
+	if err != nil {
+		return nil, err
+	}
+
+	return l, err
+}
+
 // loadLang load language package files.
 // The subdirectory is key, eg:zh-cn,en-us.
 func (l *Lang) loadLang() error {
@@ -40,14 +60,23 @@ func (l *Lang) loadLang() error {
 		return errors.New("Lang: Root path is empty")
 	}
 
-	ok, err := utils.IsDir(l.langPath)
-	if err != nil {
-		return fmt.Errorf("Lang: err [%s]", err.Error())
-	} else if !ok {
-		return errors.New("Lang: Root path is't directory")
+	if l.fsys == nil {
+		ok, err := utils.IsDir(l.langPath)
+		if err != nil {
+			return fmt.Errorf("Lang: err [%s]", err.Error())
+		} else if !ok {
+			return errors.New("Lang: Root path is't directory")
+		}
+	} else {
+		fi, err := fs.Stat(l.fsys, l.langPath)
+		if err != nil {
+			return fmt.Errorf("Lang: err [%s]", err.Error())
+		} else if !fi.IsDir() {
+			return errors.New("Lang: Root path is't directory")
+		}
 	}
 
-	fis, err := ioutil.ReadDir(l.langPath)
+	fis, err := l.readDir()
 	if err != nil {
 		return err
 	}
@@ -70,12 +99,27 @@ func (l *Lang) loadLang() error {
 	return err
 }
 
+// readDir read the root directory of language package files.
+func (l *Lang) readDir() ([]fs.DirEntry, error) {
+	if l.fsys == nil {
+		return os.ReadDir(l.langPath)
+	}
+	return fs.ReadDir(l.fsys, l.langPath)
+}
+
+// readFile read a language package file.
+func (l *Lang) readFile(fileName string) ([]byte, error) {
+	if l.fsys == nil {
+		return os.ReadFile(path.Join(l.langPath, fileName))
+	}
+	return fs.ReadFile(l.fsys, path.Join(l.langPath, fileName))
+}
+
 // parseFile parse language package files.
 func (l *Lang) parseFile(fileName string) error {
 	n := len(fileName)
 	lang := fileName[0:n-5]
-	file := path.Join(l.langPath, fileName)
-	data, err := ioutil.ReadFile(file)
+	data, err := l.readFile(fileName)
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package lang
 
 import (
 	"testing"
+	"testing/fstest"
 )
 
 // TestLang test language package.
@@ -64,3 +65,26 @@ func TestLang(t *testing.T) {
 		return
 	}
 }
+
+// TestLangFS test language package from fs.FS.
+func TestLangFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lang/en-us.json": &fstest.MapFile{Data: []byte(`{"name":"Nick"}`)},
+		"lang/zh-cn.json": &fstest.MapFile{Data: []byte(`{"name":"李四"}`)},
+		"lang/README.md":  &fstest.MapFile{Data: []byte(`readme`)},
+	}
+	l, err := NewLangFS(fsys, "lang")
+	if err != nil {
+		t.Fatalf("NewLangFS failed, err: %s", err.Error())
+	}
+	if name := l.String("zh-cn", "name"); name != "李四" {
+		t.Errorf("String err, got [%s], expected [李四]", name)
+	}
+	if name := l.String("en-us", "name"); name != "Nick" {
+		t.Errorf("String err, got [%s], expected [Nick]", name)
+	}
+
+	if _, err = NewLangFS(fsys, "none"); err == nil {
+		t.Errorf("NewLangFS should fail on missing root")
+	}
+}
@@ -0,0 +1,220 @@
+// 准入控制
+// 限制同时处理的请求数，超过时在有界队列里排队，队列已满或排队超时返回503并设置Retry-After
+// 全局的限制在ServeHTTP里生效，分组的限制在匹配到路由后生效，分组和上级分组的限制都要满足
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// defaultQueueTimeout 默认的排队超时时间
+const defaultQueueTimeout = 100 * time.Millisecond
+
+// LimitStats 准入控制的统计信息，用于监控
+type LimitStats struct {
+	Name         string        // 名称，全局为"*"，分组为域名限定加路径前缀，如：api.example.com/v2
+	MaxInflight  int           // 同时处理的最大请求数
+	MaxQueue     int           // 排队的最大请求数
+	QueueTimeout time.Duration // 排队的超时时间
+	Inflight     int64         // 正在处理的请求数
+	Waiting      int64         // 正在排队的请求数
+	Admitted     uint64        // 累计放行的请求数
+	Queued       uint64        // 累计排过队的请求数
+	Rejected     uint64        // 累计拒绝的请求数，包括队列已满和排队超时
+	Canceled     uint64        // 累计排队时客户端断开的请求数
+}
+
+// limiter 基于信号量的请求数限制
+type limiter struct {
+	sem          chan struct{} // 信号量，容量为同时处理的最大请求数
+	maxQueue     int64         // 排队的最大请求数
+	queueTimeout time.Duration // 排队的超时时间
+
+	waiting  int64 // 正在排队的请求数，原子操作
+	admitted uint64
+	queued   uint64
+	rejected uint64
+	canceled uint64
+}
+
+// newLimiter 实例化limiter
+//   参数
+//     maxInflight:  同时处理的最大请求数，<=0 不限制
+//     maxQueue:     排队的最大请求数，<=0 不排队
+//     queueTimeout: 排队的超时时间，<=0 使用默认值100毫秒
+//   返回
+//     limiter对象地址，不限制时返回nil
+func newLimiter(maxInflight, maxQueue int, queueTimeout time.Duration) *limiter {
+	if maxInflight <= 0 {
+		return nil
+	}
+	if maxQueue < 0 {
+		maxQueue = 0
+	}
+	if queueTimeout <= 0 {
+		queueTimeout = defaultQueueTimeout
+	}
+	return &limiter{
+		sem:          make(chan struct{}, maxInflight),
+		maxQueue:     int64(maxQueue),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// acquire 申请处理请求，没有空闲时排队等待
+//   参数
+//     ctx: 请求的context，客户端断开时停止排队
+//   返回
+//     申请成功返回true，否则返回false，成功后需要调用release
+func (l *limiter) acquire(ctx context.Context) bool {
+	select {
+	case l.sem <- struct{}{}:
+		atomic.AddUint64(&l.admitted, 1)
+		return true
+	default:
+	}
+
+	// 排队
+	if atomic.AddInt64(&l.waiting, 1) > l.maxQueue {
+		atomic.AddInt64(&l.waiting, -1)
+		atomic.AddUint64(&l.rejected, 1)
+		return false
+	}
+	defer atomic.AddInt64(&l.waiting, -1)
+	atomic.AddUint64(&l.queued, 1)
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.sem <- struct{}{}:
+		atomic.AddUint64(&l.admitted, 1)
+		return true
+	case <-timer.C:
+		atomic.AddUint64(&l.rejected, 1)
+	case <-ctx.Done():
+		atomic.AddUint64(&l.canceled, 1)
+	}
+	return false
+}
+
+// release 请求处理结束，释放信号量
+//   参数
+//     void
+//   返回
+//     void
+func (l *limiter) release() {
+	<-l.sem
+}
+
+// stats 返回统计信息
+//   参数
+//     name: 名称
+//   返回
+//     统计信息
+func (l *limiter) stats(name string) LimitStats {
+	return LimitStats{
+		Name:         name,
+		MaxInflight:  cap(l.sem),
+		MaxQueue:     int(l.maxQueue),
+		QueueTimeout: l.queueTimeout,
+		Inflight:     int64(len(l.sem)),
+		Waiting:      atomic.LoadInt64(&l.waiting),
+		Admitted:     atomic.LoadUint64(&l.admitted),
+		Queued:       atomic.LoadUint64(&l.queued),
+		Rejected:     atomic.LoadUint64(&l.rejected),
+		Canceled:     atomic.LoadUint64(&l.canceled),
+	}
+}
+
+// SetLimit 设置全局的准入控制，需要在Run之前调用，默认使用配置文件里的max_inflight、max_queue、queue_timeout
+// 代码里设置后不再使用配置文件，maxInflight<=0 可以关闭配置文件里开启的准入控制
+//   参数
+//     maxInflight:  同时处理的最大请求数，<=0 不限制
+//     maxQueue:     达到最大请求数时排队的最大请求数，<=0 不排队
+//     queueTimeout: 排队的超时时间，<=0 使用默认值100毫秒
+//   返回
+//     void
+func (rt *RouterTab) SetLimit(maxInflight, maxQueue int, queueTimeout time.Duration) {
+	rt.limiter = newLimiter(maxInflight, maxQueue, queueTimeout)
+	rt.limitSet = true
+}
+
+// SetLimit 设置分组的准入控制，对分组和下级分组里的路由生效，需要在Run之前调用
+//   参数
+//     maxInflight:  同时处理的最大请求数，<=0 不限制
+//     maxQueue:     达到最大请求数时排队的最大请求数，<=0 不排队
+//     queueTimeout: 排队的超时时间，<=0 使用默认值100毫秒
+//   返回
+//     void
+func (g *RouterGroup) SetLimit(maxInflight, maxQueue int, queueTimeout time.Duration) {
+	g.limiter = newLimiter(maxInflight, maxQueue, queueTimeout)
+}
+
+// LimitStats 返回准入控制的统计信息，全局的在前，分组的按添加顺序排列，没有设置限制的不返回
+//   参数
+//     void
+//   返回
+//     统计信息列表
+func (rt *RouterTab) LimitStats() []LimitStats {
+	var list []LimitStats
+	if rt.limiter != nil {
+		list = append(list, rt.limiter.stats("*"))
+	}
+	for _, g := range rt.groups {
+		if g.limiter != nil {
+			list = append(list, g.limiter.stats(g.host.String()+g.prefix))
+		}
+	}
+	return list
+}
+
+// allLimiters 返回分组及所有上级分组的准入控制，上级分组的在前
+//   参数
+//     void
+//   返回
+//     准入控制列表
+func (g *RouterGroup) allLimiters() []*limiter {
+	var list []*limiter
+	for ; g != nil; g = g.parent {
+		if g.limiter != nil {
+			list = append([]*limiter{g.limiter}, list...)
+		}
+	}
+	return list
+}
+
+// admit 准入检查，拒绝时输出503或记录客户端断开
+//   参数
+//     w: ResponseWriter对象
+//     r: Request对象
+//     l: 准入控制，为nil时不限制
+//     g: 路由分组，用于查找错误处理
+//   返回
+//     放行返回true，拒绝返回false，放行后需要调用l.release
+func (rt *RouterTab) admit(w http.ResponseWriter, r *http.Request, l *limiter, g *RouterGroup) bool {
+	if l == nil || l.acquire(r.Context()) {
+		return true
+	}
+
+	if r.Context().Err() != nil {
+		// 排队时客户端断开连接，不再输出
+		return false
+	}
+
+	// 503
+	flogger(r).Errorf("path[%s] err[too many requests] inflight[%d] waiting[%d]", rt.uri(r), len(l.sem), atomic.LoadInt64(&l.waiting))
+	retryAfter := AppCfg.ServerCfg.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	rt.report(r, nil, ReportError, http.StatusServiceUnavailable, nil, "")
+	rt.handleError(w, r, http.StatusServiceUnavailable, g, nil, "")
+	return false
+}
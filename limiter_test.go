@@ -0,0 +1,121 @@
+package bingo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestLimiter 测试信号量和排队
+func TestLimiter(t *testing.T) {
+	if l := newLimiter(0, 10, 0); l != nil {
+		t.Errorf("newLimiter failed. Got limiter, expected nil.")
+	}
+	rt := NewRouterTab()
+	if rt.SetLimit(0, 0, 0); rt.limiter != nil || !rt.limitSet {
+		t.Errorf("SetLimit 0 failed. Got %v %v, expected nil true.", rt.limiter, rt.limitSet)
+	}
+
+	l := newLimiter(1, 1, 50*time.Millisecond)
+	ctx := context.Background()
+	if !l.acquire(ctx) {
+		t.Fatalf("acquire failed. Got false, expected true.")
+	}
+
+	// 排队等到释放
+	done := make(chan bool)
+	go func() {
+		done <- l.acquire(ctx)
+	}()
+	waitFor(t, func() bool { return l.stats("").Waiting == 1 })
+
+	// 队列已满
+	if l.acquire(ctx) {
+		t.Errorf("acquire failed. Got true, expected false when queue is full.")
+	}
+	l.release()
+	if !<-done {
+		t.Errorf("acquire failed. Got false, expected true after release.")
+	}
+
+	// 排队超时
+	if l.acquire(ctx) {
+		t.Errorf("acquire failed. Got true, expected false after queue timeout.")
+	}
+
+	// 排队时取消
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if l.acquire(cctx) {
+		t.Errorf("acquire failed. Got true, expected false when canceled.")
+	}
+	l.release()
+
+	s := l.stats("test")
+	if s.Inflight != 0 || s.Waiting != 0 || s.Admitted != 2 || s.Queued != 3 || s.Rejected != 2 || s.Canceled != 1 {
+		t.Errorf("stats failed. Got %+v.", s)
+	}
+}
+
+// TestRouterLimit 测试全局和分组的准入控制
+func TestRouterLimit(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.SetLimit(10, 0, 0)
+	block := make(chan struct{})
+	g := rt.Group("/slow")
+	g.SetLimit(1, 0, 0)
+	g.HandleFunc("/wait", func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		w.Write([]byte("ok"))
+	})
+	rt.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	serve := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() {
+		first <- serve("/slow/wait")
+	}()
+	waitFor(t, func() bool { return rt.LimitStats()[1].Inflight == 1 })
+
+	w := serve("/slow/wait")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("shed failed. Got status %d, header %v.", w.Code, w.Header())
+	}
+	if w = serve("/fast"); w.Code != 200 {
+		t.Errorf("fast failed. Got status %d, expected 200.", w.Code)
+	}
+
+	close(block)
+	if w = <-first; w.Code != 200 || w.Body.String() != "ok" {
+		t.Errorf("wait failed. Got status %d, body %s.", w.Code, w.Body.String())
+	}
+
+	stats := rt.LimitStats()
+	if len(stats) != 2 || stats[0].Name != "*" || stats[1].Name != "/slow" {
+		t.Fatalf("LimitStats failed. Got %+v.", stats)
+	}
+	if stats[0].Inflight != 0 || stats[0].Admitted != 3 || stats[1].Admitted != 1 || stats[1].Rejected != 1 {
+		t.Errorf("LimitStats failed. Got %+v.", stats)
+	}
+}
+
+// waitFor 等待条件成立，最多1秒
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("waitFor timeout")
+}
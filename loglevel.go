@@ -0,0 +1,175 @@
+// 日志级别
+// gotools的日志在WriteMsg里直接读Level字段，运行时修改会和写日志的请求并发读写
+// 框架的日志初始化后用levelLogger包装，级别保存在原子变量里，原日志的级别固定为LevelDebug，由包装判断是否输出
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"sync/atomic"
+
+	"github.com/lixy529/gotools/logs"
+)
+
+// levelLogger 级别可以并发修改的日志
+// 各方法直接调用原日志的WriteMsg，showcall记录的调用位置不变
+type levelLogger struct {
+	logs.Logger
+	level int32 // 日志级别，原子读写
+}
+
+// newLevelLogger 包装日志，只支持gotools自带的日志，不支持时直接返回原日志
+// 只能在初始化时调用，会修改原日志的级别
+//   参数
+//     l: 日志对象
+//   返回
+//     日志对象
+func newLevelLogger(l logs.Logger) logs.Logger {
+	if _, ok := l.(*levelLogger); ok {
+		return l
+	}
+
+	level, ok := rawLogLevel(l)
+	if !ok {
+		return l
+	}
+	setRawLogLevel(l, logs.LevelDebug)
+	return &levelLogger{Logger: l, level: int32(level)}
+}
+
+// enabled 返回该级别的日志是否输出
+//   参数
+//     level: 日志级别
+//   返回
+//     输出返回true，否则返回false
+func (l *levelLogger) enabled(level int) bool {
+	return level >= int(atomic.LoadInt32(&l.level))
+}
+
+func (l *levelLogger) WriteMsg(level int, fmtStr string, v ...interface{}) error {
+	if !l.enabled(level) {
+		return nil
+	}
+	return l.Logger.WriteMsg(level, fmtStr, v...)
+}
+
+func (l *levelLogger) Debug(v ...interface{}) {
+	if l.enabled(logs.LevelDebug) {
+		l.Logger.WriteMsg(logs.LevelDebug, msgFormat(len(v)), v...)
+	}
+}
+
+func (l *levelLogger) Info(v ...interface{}) {
+	if l.enabled(logs.LevelInfo) {
+		l.Logger.WriteMsg(logs.LevelInfo, msgFormat(len(v)), v...)
+	}
+}
+
+func (l *levelLogger) Warn(v ...interface{}) {
+	if l.enabled(logs.LevelWarn) {
+		l.Logger.WriteMsg(logs.LevelWarn, msgFormat(len(v)), v...)
+	}
+}
+
+func (l *levelLogger) Error(v ...interface{}) {
+	if l.enabled(logs.LevelError) {
+		l.Logger.WriteMsg(logs.LevelError, msgFormat(len(v)), v...)
+	}
+}
+
+func (l *levelLogger) Fatal(v ...interface{}) {
+	if l.enabled(logs.LevelFatal) {
+		l.Logger.WriteMsg(logs.LevelFatal, msgFormat(len(v)), v...)
+	}
+}
+
+func (l *levelLogger) Debugf(fmtStr string, v ...interface{}) {
+	if l.enabled(logs.LevelDebug) {
+		l.Logger.WriteMsg(logs.LevelDebug, fmtStr, v...)
+	}
+}
+
+func (l *levelLogger) Infof(fmtStr string, v ...interface{}) {
+	if l.enabled(logs.LevelInfo) {
+		l.Logger.WriteMsg(logs.LevelInfo, fmtStr, v...)
+	}
+}
+
+func (l *levelLogger) Warnf(fmtStr string, v ...interface{}) {
+	if l.enabled(logs.LevelWarn) {
+		l.Logger.WriteMsg(logs.LevelWarn, fmtStr, v...)
+	}
+}
+
+func (l *levelLogger) Errorf(fmtStr string, v ...interface{}) {
+	if l.enabled(logs.LevelError) {
+		l.Logger.WriteMsg(logs.LevelError, fmtStr, v...)
+	}
+}
+
+func (l *levelLogger) Fatalf(fmtStr string, v ...interface{}) {
+	if l.enabled(logs.LevelFatal) {
+		l.Logger.WriteMsg(logs.LevelFatal, fmtStr, v...)
+	}
+}
+
+// logLevel 返回日志的级别
+//   参数
+//     l: 日志对象
+//   返回
+//     日志级别、是否支持
+func logLevel(l logs.Logger) (int, bool) {
+	if ll, ok := l.(*levelLogger); ok {
+		return int(atomic.LoadInt32(&ll.level)), true
+	}
+	return 0, false
+}
+
+// setLogLevel 修改日志的级别，只支持newLevelLogger包装过的日志
+//   参数
+//     l:     日志对象
+//     level: 日志级别
+//   返回
+//     支持返回true，否则返回false
+func setLogLevel(l logs.Logger, level int) bool {
+	ll, ok := l.(*levelLogger)
+	if !ok {
+		return false
+	}
+	atomic.StoreInt32(&ll.level, int32(level))
+	return true
+}
+
+// rawLogLevel 返回gotools自带日志的级别
+//   参数
+//     l: 日志对象
+//   返回
+//     日志级别、是否支持
+func rawLogLevel(l logs.Logger) (int, bool) {
+	switch v := l.(type) {
+	case *logs.FileLogs:
+		return v.Level, true
+	case *logs.ConsoleLogs:
+		return v.Level, true
+	case *logs.SyslogNgLogs:
+		return v.Level, true
+	}
+	return 0, false
+}
+
+// setRawLogLevel 修改gotools自带日志的级别，只能在初始化时调用
+//   参数
+//     l:     日志对象
+//     level: 日志级别
+//   返回
+//     void
+func setRawLogLevel(l logs.Logger, level int) {
+	switch v := l.(type) {
+	case *logs.FileLogs:
+		v.Level = level
+	case *logs.ConsoleLogs:
+		v.Level = level
+	case *logs.SyslogNgLogs:
+		v.Level = level
+	}
+}
@@ -0,0 +1,673 @@
+// 监控指标
+// 按路由请求路径统计请求数、耗时分布和处理中的请求数，另外输出准入控制、数据库连接池、session、缓存和Go运行时的指标
+// 以Prometheus文本格式输出，可以挂在路由上，也可以单独监听一个端口，配置取配置文件的[metrics]段
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lixy529/gotools/cache"
+)
+
+// metricsContentType Prometheus文本格式
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// unmatchedRoute 没有匹配到路由的请求(静态文件、404等)使用的路由标签
+const unmatchedRoute = "-"
+
+// DefaultBuckets 默认的请求耗时分布区间，单位秒
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// processStart 进程启动时间
+var processStart = time.Now()
+
+// MetricsConfig 监控指标配置
+type MetricsConfig struct {
+	On      bool      // 是否开启
+	Path    string    // 输出指标的路径，默认/metrics
+	Addr    string    // 单独监听的地址，如：127.0.0.1:9100，为空时挂在路由上
+	Buckets []float64 // 请求耗时分布区间，单位秒，为空时使用DefaultBuckets
+}
+
+// metrics 请求相关的指标
+type metrics struct {
+	requests *metricVec // 请求数，标签: route, controller, method, status
+	duration *metricVec // 请求耗时分布，标签: route, controller, method
+	inflight *metricVec // 处理中的请求数，标签: route, controller
+	buckets  []float64
+}
+
+// metricVec 一组同名指标，按标签值区分
+type metricVec struct {
+	mu     sync.Mutex
+	values map[string]*metricValue // key为标签值拼接
+}
+
+// metricValue 一个指标的值，计数和gauge使用n，分布使用counts、sum、count
+type metricValue struct {
+	labels []string
+	n      int64
+	mu     sync.Mutex
+	counts []uint64 // 每个区间的计数，不累加
+	sum    float64
+	count  uint64
+}
+
+// get 返回标签值对应的指标，不存在时创建
+//   参数
+//     labels: 标签值
+//   返回
+//     指标
+func (v *metricVec) get(labels ...string) *metricValue {
+	key := strings.Join(labels, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.values == nil {
+		v.values = make(map[string]*metricValue)
+	}
+	mv, ok := v.values[key]
+	if !ok {
+		mv = &metricValue{labels: labels}
+		v.values[key] = mv
+	}
+	return mv
+}
+
+// sorted 返回按标签值排序的指标，输出稳定
+//   参数
+//     void
+//   返回
+//     指标列表
+func (v *metricVec) sorted() []*metricValue {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]*metricValue, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, v.values[k])
+	}
+	v.mu.Unlock()
+	return list
+}
+
+// observe 记录一次分布的值
+//   参数
+//     buckets: 分布区间
+//     val:     值
+//   返回
+//     void
+func (mv *metricValue) observe(buckets []float64, val float64) {
+	mv.mu.Lock()
+	defer mv.mu.Unlock()
+	if mv.counts == nil {
+		mv.counts = make([]uint64, len(buckets))
+	}
+	for i, b := range buckets {
+		if val <= b {
+			mv.counts[i]++
+			break
+		}
+	}
+	mv.sum += val
+	mv.count++
+}
+
+// EnableMetrics 开启请求相关的指标统计，输出见MetricsHandler
+//   参数
+//     buckets: 请求耗时分布区间，单位秒，为空时使用DefaultBuckets
+//   返回
+//     void
+func (rt *RouterTab) EnableMetrics(buckets ...float64) {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	rt.metrics = &metrics{
+		requests: &metricVec{},
+		duration: &metricVec{},
+		inflight: &metricVec{},
+		buckets:  buckets,
+	}
+}
+
+// routeLabels 返回路由的标签值
+//   参数
+//     ri: 匹配到的路由，没有匹配到时为nil
+//   返回
+//     路由请求路径、控制器和方法，如：controllers.DemoController.IndexAction
+func routeLabels(ri *RouterInfo) (string, string) {
+	if ri == nil {
+		return unmatchedRoute, unmatchedRoute
+	}
+	return ri.pattern, ri.desc()
+}
+
+// trackInflight 匹配到路由后增加处理中的请求数
+//   参数
+//     ri: 匹配到的路由
+//   返回
+//     请求结束后调用的函数，没有开启统计时为nil
+func (rt *RouterTab) trackInflight(ri *RouterInfo) func() {
+	if rt.metrics == nil {
+		return nil
+	}
+	route, controller := routeLabels(ri)
+	mv := rt.metrics.inflight.get(route, controller)
+	atomic.AddInt64(&mv.n, 1)
+	return func() {
+		atomic.AddInt64(&mv.n, -1)
+	}
+}
+
+// observe 请求结束后记录请求数和耗时
+//   参数
+//     aw:    accessWriter对象
+//     r:     Request对象
+//     start: 请求开始的时间
+//   返回
+//     void
+func (rt *RouterTab) observe(aw *accessWriter, r *http.Request, start time.Time) {
+	m := rt.metrics
+	if m == nil {
+		return
+	}
+	route, controller := routeLabels(aw.route)
+	method := methodLabel(r.Method)
+	status := strconv.Itoa(aw.finalStatus(r))
+	atomic.AddInt64(&m.requests.get(route, controller, method, status).n, 1)
+	m.duration.get(route, controller, method).observe(m.buckets, time.Since(start).Seconds())
+}
+
+// methodLabel 返回请求方法的标签，不支持的方法统一为OTHER，避免客户端随意构造方法名产生大量时间序列
+//   参数
+//     method: 请求方法
+//   返回
+//     标签值
+func methodLabel(method string) string {
+	for _, v := range httpMethods {
+		if v == method {
+			return method
+		}
+	}
+	return "OTHER"
+}
+
+// cacheStats 缓存的命中统计，标签: cache, result
+var cacheStats = &metricVec{}
+
+// metricCache 统计命中率的缓存适配器，见Model.Cache
+type metricCache struct {
+	cache.Cache
+	name string
+}
+
+// newMetricCache 包装缓存适配器，统计Get、HGet、MGet、HMGet的命中和未命中
+//   参数
+//     c:    缓存适配器
+//     name: 缓存适配器名称
+//   返回
+//     缓存适配器
+func newMetricCache(c cache.Cache, name string) cache.Cache {
+	return &metricCache{Cache: c, name: name}
+}
+
+// record 记录命中结果
+//   参数
+//     result: hit | miss | error
+//     n:      次数
+//   返回
+//     void
+func (c *metricCache) record(result string, n int) {
+	if n > 0 {
+		atomic.AddInt64(&cacheStats.get(c.name, result).n, int64(n))
+	}
+}
+
+func (c *metricCache) Get(key string, val interface{}) (error, bool) {
+	err, ok := c.Cache.Get(key, val)
+	c.recordOne(err, ok)
+	return err, ok
+}
+
+func (c *metricCache) HGet(key string, field string, val interface{}) (error, bool) {
+	err, ok := c.Cache.HGet(key, field, val)
+	c.recordOne(err, ok)
+	return err, ok
+}
+
+func (c *metricCache) MGet(keys ...string) (map[string]interface{}, error) {
+	res, err := c.Cache.MGet(keys...)
+	c.recordMulti(len(keys), len(res), err)
+	return res, err
+}
+
+func (c *metricCache) HMGet(key string, fields ...string) (map[string]interface{}, error) {
+	res, err := c.Cache.HMGet(key, fields...)
+	c.recordMulti(len(fields), len(res), err)
+	return res, err
+}
+
+// recordOne 记录单个key的命中结果，key存在但解析失败也算命中
+//   参数
+//     err: 错误信息
+//     ok:  key是否存在
+//   返回
+//     void
+func (c *metricCache) recordOne(err error, ok bool) {
+	switch {
+	case ok:
+		c.record("hit", 1)
+	case err != nil:
+		c.record("error", 1)
+	default:
+		c.record("miss", 1)
+	}
+}
+
+// recordMulti 记录批量读取的命中结果
+//   参数
+//     total: 读取的key数
+//     hits:  命中的key数
+//     err:   错误信息
+//   返回
+//     void
+func (c *metricCache) recordMulti(total, hits int, err error) {
+	if err != nil {
+		c.record("error", total)
+		return
+	}
+	c.record("hit", hits)
+	c.record("miss", total-hits)
+}
+
+// MetricsHandler 返回输出监控指标的http.Handler，Prometheus文本格式
+//   参数
+//     void
+//   返回
+//     http.Handler
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := &bytes.Buffer{}
+		Router.writeMetrics(buf)
+		w.Header().Set("Content-Type", metricsContentType)
+		w.Write(buf.Bytes())
+	})
+}
+
+// writeMetrics 输出所有的指标
+//   参数
+//     buf: 输出缓冲
+//   返回
+//     void
+func (rt *RouterTab) writeMetrics(buf *bytes.Buffer) {
+	if m := rt.metrics; m != nil {
+		writeVec(buf, "bingo_http_requests_total", "Total number of HTTP requests.", "counter",
+			[]string{"route", "controller", "method", "status"}, m.requests)
+		writeHistogram(buf, "bingo_http_request_duration_seconds", "HTTP request latency in seconds.",
+			[]string{"route", "controller", "method"}, m.buckets, m.duration)
+		writeVec(buf, "bingo_http_requests_in_flight", "Number of HTTP requests being served.", "gauge",
+			[]string{"route", "controller"}, m.inflight)
+	}
+
+	rt.writeLimitMetrics(buf)
+	writeDbMetrics(buf)
+	writeSessionMetrics(buf)
+	writeVec(buf, "bingo_cache_requests_total", "Total number of cache reads by result.", "counter",
+		[]string{"cache", "result"}, cacheStats)
+	writeRuntimeMetrics(buf)
+}
+
+// writeLimitMetrics 输出准入控制的指标
+//   参数
+//     buf: 输出缓冲
+//   返回
+//     void
+func (rt *RouterTab) writeLimitMetrics(buf *bytes.Buffer) {
+	stats := rt.LimitStats()
+	if len(stats) == 0 {
+		return
+	}
+	gauges := []struct {
+		name, help string
+		val        func(s LimitStats) int64
+	}{
+		{"bingo_limit_inflight", "Number of requests holding an admission slot.", func(s LimitStats) int64 { return int64(s.Inflight) }},
+		{"bingo_limit_waiting", "Number of requests waiting in the admission queue.", func(s LimitStats) int64 { return s.Waiting }},
+		{"bingo_limit_max_inflight", "Maximum number of requests served concurrently.", func(s LimitStats) int64 { return int64(s.MaxInflight) }},
+	}
+	for _, g := range gauges {
+		writeHeader(buf, g.name, g.help, "gauge")
+		for _, s := range stats {
+			writeSample(buf, g.name, []string{"name"}, []string{s.Name}, float64(g.val(s)))
+		}
+	}
+
+	writeHeader(buf, "bingo_limit_requests_total", "Total number of admission decisions by result.", "counter")
+	for _, s := range stats {
+		for _, res := range []struct {
+			result string
+			n      uint64
+		}{{"admitted", s.Admitted}, {"queued", s.Queued}, {"rejected", s.Rejected}, {"canceled", s.Canceled}} {
+			writeSample(buf, "bingo_limit_requests_total", []string{"name", "result"}, []string{s.Name, res.result}, float64(res.n))
+		}
+	}
+}
+
+// writeDbMetrics 输出数据库连接池的指标
+//   参数
+//     buf: 输出缓冲
+//   返回
+//     void
+func writeDbMetrics(buf *bytes.Buffer) {
+	if GlobalDb == nil {
+		return
+	}
+
+	type dbStat struct {
+		labels []string
+		stats  sql.DBStats
+	}
+	var list []dbStat
+	for _, cfg := range AppCfg.DbConfigs {
+		h := GlobalDb.Db(cfg.dbName)
+		if h == nil {
+			continue
+		}
+		if db := h.GetMaster(); db != nil {
+			list = append(list, dbStat{[]string{cfg.dbName, "master"}, db.Stats()})
+		}
+		for i := range cfg.slaves {
+			if db := h.GetSlave(i); db != nil {
+				list = append(list, dbStat{[]string{cfg.dbName, "slave" + strconv.Itoa(i)}, db.Stats()})
+			}
+		}
+	}
+	if len(list) == 0 {
+		return
+	}
+
+	labels := []string{"db", "instance"}
+	metrics := []struct {
+		name, help, typ string
+		val             func(s sql.DBStats) float64
+	}{
+		{"bingo_db_max_open_connections", "Maximum number of open connections to the database.", "gauge", func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"bingo_db_open_connections", "Number of established connections.", "gauge", func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"bingo_db_in_use_connections", "Number of connections currently in use.", "gauge", func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"bingo_db_idle_connections", "Number of idle connections.", "gauge", func(s sql.DBStats) float64 { return float64(s.Idle) }},
+		{"bingo_db_wait_count_total", "Total number of connections waited for.", "counter", func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"bingo_db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", "counter", func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"bingo_db_max_idle_closed_total", "Total number of connections closed due to SetMaxIdleConns.", "counter", func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"bingo_db_max_lifetime_closed_total", "Total number of connections closed due to SetConnMaxLifetime.", "counter", func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+	for _, m := range metrics {
+		writeHeader(buf, m.name, m.help, m.typ)
+		for _, d := range list {
+			writeSample(buf, m.name, labels, d.labels, m.val(d.stats))
+		}
+	}
+}
+
+// writeSessionMetrics 输出session的指标
+//   参数
+//     buf: 输出缓冲
+//   返回
+//     void
+func writeSessionMetrics(buf *bytes.Buffer) {
+	if GlobalSession == nil {
+		return
+	}
+	stats := GlobalSession.Stats()
+	writeHeader(buf, "bingo_sessions_started_total", "Total number of sessions started with a new session id.", "counter")
+	writeSample(buf, "bingo_sessions_started_total", nil, nil, float64(stats.Started))
+	writeHeader(buf, "bingo_sessions_destroyed_total", "Total number of sessions destroyed.", "counter")
+	writeSample(buf, "bingo_sessions_destroyed_total", nil, nil, float64(stats.Destroyed))
+	if stats.Active >= 0 {
+		writeHeader(buf, "bingo_sessions_active", "Number of live sessions.", "gauge")
+		writeSample(buf, "bingo_sessions_active", nil, nil, float64(stats.Active))
+	}
+}
+
+// writeRuntimeMetrics 输出Go运行时的指标
+//   参数
+//     buf: 输出缓冲
+//   返回
+//     void
+func writeRuntimeMetrics(buf *bytes.Buffer) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	writeHeader(buf, "go_info", "Information about the Go environment.", "gauge")
+	writeSample(buf, "go_info", []string{"version"}, []string{runtime.Version()}, 1)
+	gauges := []struct {
+		name, help, typ string
+		val             float64
+	}{
+		{"go_goroutines", "Number of goroutines that currently exist.", "gauge", float64(runtime.NumGoroutine())},
+		{"go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", "gauge", float64(ms.Alloc)},
+		{"go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", "counter", float64(ms.TotalAlloc)},
+		{"go_memstats_sys_bytes", "Number of bytes obtained from system.", "gauge", float64(ms.Sys)},
+		{"go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", "gauge", float64(ms.HeapAlloc)},
+		{"go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", "gauge", float64(ms.HeapInuse)},
+		{"go_memstats_heap_objects", "Number of allocated objects.", "gauge", float64(ms.HeapObjects)},
+		{"go_memstats_mallocs_total", "Total number of mallocs.", "counter", float64(ms.Mallocs)},
+		{"go_memstats_frees_total", "Total number of frees.", "counter", float64(ms.Frees)},
+		{"go_gc_cycles_total", "Number of completed GC cycles.", "counter", float64(ms.NumGC)},
+		{"go_gc_pause_seconds_total", "Total GC stop-the-world pause time in seconds.", "counter", float64(ms.PauseTotalNs) / 1e9},
+		{"process_start_time_seconds", "Start time of the process since unix epoch in seconds.", "gauge", float64(processStart.UnixNano()) / 1e9},
+	}
+	for _, g := range gauges {
+		writeHeader(buf, g.name, g.help, g.typ)
+		writeSample(buf, g.name, nil, nil, g.val)
+	}
+}
+
+// writeVec 输出一组计数或gauge指标，没有值时不输出
+//   参数
+//     buf:    输出缓冲
+//     name:   指标名称
+//     help:   说明
+//     typ:    counter | gauge
+//     labels: 标签名称
+//     v:      指标
+//   返回
+//     void
+func writeVec(buf *bytes.Buffer, name, help, typ string, labels []string, v *metricVec) {
+	list := v.sorted()
+	if len(list) == 0 {
+		return
+	}
+	writeHeader(buf, name, help, typ)
+	for _, mv := range list {
+		writeSample(buf, name, labels, mv.labels, float64(atomic.LoadInt64(&mv.n)))
+	}
+}
+
+// writeHistogram 输出一组分布指标，区间计数按Prometheus的要求累加输出
+//   参数
+//     buf:     输出缓冲
+//     name:    指标名称
+//     help:    说明
+//     labels:  标签名称
+//     buckets: 分布区间
+//     v:       指标
+//   返回
+//     void
+func writeHistogram(buf *bytes.Buffer, name, help string, labels []string, buckets []float64, v *metricVec) {
+	list := v.sorted()
+	if len(list) == 0 {
+		return
+	}
+	writeHeader(buf, name, help, "histogram")
+	bucketLabels := append(append([]string{}, labels...), "le")
+	for _, mv := range list {
+		mv.mu.Lock()
+		var cum uint64
+		for i, b := range buckets {
+			if mv.counts != nil {
+				cum += mv.counts[i]
+			}
+			writeSample(buf, name+"_bucket", bucketLabels, append(append([]string{}, mv.labels...), formatFloat(b)), float64(cum))
+		}
+		writeSample(buf, name+"_bucket", bucketLabels, append(append([]string{}, mv.labels...), "+Inf"), float64(mv.count))
+		writeSample(buf, name+"_sum", labels, mv.labels, mv.sum)
+		writeSample(buf, name+"_count", labels, mv.labels, float64(mv.count))
+		mv.mu.Unlock()
+	}
+}
+
+// writeHeader 输出指标的HELP和TYPE
+//   参数
+//     buf:  输出缓冲
+//     name: 指标名称
+//     help: 说明
+//     typ:  指标类型
+//   返回
+//     void
+func writeHeader(buf *bytes.Buffer, name, help, typ string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// writeSample 输出一个指标值
+//   参数
+//     buf:    输出缓冲
+//     name:   指标名称
+//     labels: 标签名称
+//     values: 标签值
+//     val:    指标值
+//   返回
+//     void
+func writeSample(buf *bytes.Buffer, name string, labels, values []string, val float64) {
+	buf.WriteString(name)
+	if len(labels) > 0 {
+		buf.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(l)
+			buf.WriteString(`="`)
+			buf.WriteString(labelEscaper.Replace(values[i]))
+			buf.WriteByte('"')
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(formatFloat(val))
+	buf.WriteByte('\n')
+}
+
+// labelEscaper 标签值的转义
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatFloat 格式化指标值
+//   参数
+//     f: 指标值
+//   返回
+//     字符串
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	metricsMu     sync.Mutex
+	metricsServer *http.Server // 单独监听的指标服务，没有单独监听时为nil
+)
+
+// metricsPath 返回指标的路径
+//   参数
+//     cfg: 监控指标配置
+//   返回
+//     路径，默认/metrics
+func metricsPath(cfg MetricsConfig) string {
+	if cfg.Path == "" {
+		return "/metrics"
+	}
+	return cfg.Path
+}
+
+// initMetrics 按配置开启监控指标，没有单独监听时在业务端口上输出，不经过准入控制和全局中间件
+//   参数
+//     cfg: 监控指标配置
+//   返回
+//     void
+func (rt *RouterTab) initMetrics(cfg MetricsConfig) {
+	if !cfg.On {
+		return
+	}
+	if rt.metrics == nil {
+		rt.EnableMetrics(cfg.Buckets...)
+	}
+	if cfg.Addr == "" {
+		rt.handleProbe(metricsPath(cfg), MetricsHandler())
+	}
+}
+
+// initMetricsServer 按配置单独监听指标服务，重启失败后重新监听
+//   参数
+//     cfg: 监控指标配置
+//   返回
+//     成功返回nil，失败返回错误信息
+func initMetricsServer(cfg MetricsConfig) error {
+	if !cfg.On || cfg.Addr == "" {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("metrics: listen [%s] err [%s]", cfg.Addr, err.Error())
+	}
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath(cfg), MetricsHandler())
+	srv := &http.Server{Handler: mux}
+	metricsMu.Lock()
+	metricsServer = srv
+	metricsMu.Unlock()
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			Flogger.Errorf("metrics: serve [%s] err [%s]", cfg.Addr, err.Error())
+		}
+	}()
+	return nil
+}
+
+// unInitMetrics 关闭单独监听的指标服务，重启时新进程需要监听同一地址
+//   参数
+//     void
+//   返回
+//     void
+func unInitMetrics() {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if metricsServer != nil {
+		metricsServer.Close()
+		metricsServer = nil
+	}
+}
@@ -0,0 +1,176 @@
+package bingo
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lixy529/gotools/cache"
+)
+
+// TestMetrics 测试按路由统计请求数、耗时分布和处理中的请求数
+func TestMetrics(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.EnableMetrics(0.5, 0.1)
+	rt.HandleFunc("/user/:id", func(w http.ResponseWriter, r *http.Request) {
+		buf := &bytes.Buffer{}
+		rt.writeMetrics(buf)
+		w.Write(buf.Bytes())
+	})
+	rt.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "fail", http.StatusInternalServerError)
+	})
+
+	for _, url := range []string{"/user/1", "/user/2", "/fail", "/none"} {
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("FOO", "/none", nil))
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest("GET", "/user/3", nil))
+	body := w.Body.String()
+
+	// 处理中的请求数在输出时包含当前请求
+	for _, line := range [][2]string{
+		{`bingo_http_requests_total{route="/user/:id",controller="`, `",method="GET",status="200"} 2`},
+		{`bingo_http_requests_total{route="/fail",controller="`, `",method="GET",status="500"} 1`},
+		{`bingo_http_requests_total{route="-",controller="-",method="GET",status="404"}`, ` 1`},
+		{`bingo_http_requests_total{route="-",controller="-",method="OTHER",status="404"}`, ` 1`},
+		{`bingo_http_request_duration_seconds_bucket{route="/user/:id",controller="`, `",method="GET",le="0.1"} 2`},
+		{`bingo_http_request_duration_seconds_bucket{route="/user/:id",controller="`, `",method="GET",le="+Inf"} 2`},
+		{`bingo_http_request_duration_seconds_count{route="/user/:id",controller="`, `",method="GET"} 2`},
+		{`bingo_http_requests_in_flight{route="/user/:id",controller="`, `"} 1`},
+		{"# TYPE bingo_http_request_duration_seconds", " histogram"},
+		{"# TYPE go_goroutines", " gauge"},
+	} {
+		if !hasMetric(body, line[0], line[1]) {
+			t.Errorf("metrics failed. Not found [%s...%s] in:\n%s", line[0], line[1], body)
+		}
+	}
+	if strings.Contains(body, "/user/1") || strings.Contains(body, `method="FOO"`) {
+		t.Errorf("metrics failed. Got raw path or method in labels.")
+	}
+
+	// 请求结束后处理中的请求数归零
+	buf := &bytes.Buffer{}
+	rt.writeMetrics(buf)
+	if !hasMetric(buf.String(), `bingo_http_requests_in_flight{route="/user/:id",controller="`, `"} 0`) {
+		t.Errorf("in flight failed. Got:\n%s", buf.String())
+	}
+}
+
+// hasMetric 判断是否有以prefix开头、suffix结尾的指标行
+func hasMetric(body, prefix, suffix string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, prefix) && strings.HasSuffix(line, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// testCache 测试用的缓存，只实现读取
+type testCache struct {
+	cache.Cache
+	data map[string]string
+}
+
+func (c *testCache) Get(key string, val interface{}) (error, bool) {
+	if key == "err" {
+		return errors.New("conn refused"), false
+	}
+	v, ok := c.data[key]
+	if ok {
+		*(val.(*string)) = v
+	}
+	return nil, ok
+}
+
+func (c *testCache) MGet(keys ...string) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+	for _, k := range keys {
+		if v, ok := c.data[k]; ok {
+			res[k] = v
+		}
+	}
+	return res, nil
+}
+
+// TestMetricCache 测试缓存命中统计
+func TestMetricCache(t *testing.T) {
+	c := newMetricCache(&testCache{data: map[string]string{"a": "1", "b": "2"}}, "metric_test")
+	var v string
+	c.Get("a", &v)
+	c.Get("none", &v)
+	c.Get("err", &v)
+	c.MGet("a", "b", "c")
+
+	expected := map[string]int64{"hit": 3, "miss": 2, "error": 1}
+	for result, n := range expected {
+		if got := cacheStats.get("metric_test", result).n; got != n {
+			t.Errorf("cache [%s] failed. Got %d, expected %d.", result, got, n)
+		}
+	}
+	if v != "1" {
+		t.Errorf("cache Get failed. Got %s, expected 1.", v)
+	}
+
+	// 没有开启指标时返回原适配器
+	raw := &testCache{data: map[string]string{}}
+	cache.Adapters["metric_test"] = raw
+	defer delete(cache.Adapters, "metric_test")
+	old := Router.metrics
+	defer func() { Router.metrics = old }()
+	Router.metrics = nil
+	if got, err := (&Model{}).Cache("metric_test"); err != nil || got != cache.Cache(raw) {
+		t.Errorf("Model.Cache without metrics failed. Got %T, err: %v.", got, err)
+	}
+	Router.metrics = &metrics{}
+	if got, _ := (&Model{}).Cache("metric_test"); got == cache.Cache(raw) {
+		t.Errorf("Model.Cache with metrics failed. Got %T.", got)
+	}
+}
+
+// TestWriteSample 测试指标的输出格式
+func TestWriteSample(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writeSample(buf, "m", []string{"a", "b"}, []string{`x"y`, "1\\2\n"}, 1.5)
+	writeSample(buf, "n", nil, nil, 3)
+	expected := "m{a=\"x\\\"y\",b=\"1\\\\2\\n\"} 1.5\nn 3\n"
+	if buf.String() != expected {
+		t.Errorf("writeSample failed. Got %q, expected %q.", buf.String(), expected)
+	}
+}
+
+// TestMetricsServer 测试单独监听的指标服务，关闭后可以重新监听同一地址
+func TestMetricsServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed. err: %s", err.Error())
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := MetricsConfig{On: true, Addr: addr}
+	for i := 0; i < 2; i++ {
+		if err := initMetricsServer(cfg); err != nil {
+			t.Fatalf("initMetricsServer %d failed. err: %s", i, err.Error())
+		}
+		rsp, err := http.Get("http://" + addr + "/metrics")
+		if err != nil {
+			t.Fatalf("Get %d failed. err: %s", i, err.Error())
+		}
+		rsp.Body.Close()
+		if rsp.StatusCode != http.StatusOK {
+			t.Errorf("metrics server %d failed. Got %d.", i, rsp.StatusCode)
+		}
+		unInitMetrics()
+	}
+	if err := initMetricsServer(MetricsConfig{On: true}); err != nil || metricsServer != nil {
+		t.Errorf("initMetricsServer without addr failed. Got %v.", err)
+	}
+}
@@ -0,0 +1,50 @@
+// 中间件相关
+// 中间件与net/http的中间件写法一致，可以直接复用已有的中间件
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"net/http"
+)
+
+// MiddlewareFunc 中间件，包装下一个处理函数并返回新的处理函数
+type MiddlewareFunc func(http.Handler) http.Handler
+
+// RouteOption 路由选项，添加路由时传入
+type RouteOption func(*RouterInfo)
+
+// Use 添加全局中间件，对所有请求生效，包括静态文件和未匹配到路由的请求
+// 先添加的中间件在外层，先执行
+//   参数
+//     mws: 中间件
+//   返回
+//     void
+func (rt *RouterTab) Use(mws ...MiddlewareFunc) {
+	rt.middlewares = append(rt.middlewares, mws...)
+	rt.handler = chainMiddleware(rt.middlewares, http.HandlerFunc(rt.dispatch))
+}
+
+// WithMiddleware 路由选项，设置路由的中间件，只对匹配到此路由的请求生效
+//   参数
+//     mws: 中间件
+//   返回
+//     路由选项
+func WithMiddleware(mws ...MiddlewareFunc) RouteOption {
+	return func(ri *RouterInfo) {
+		ri.middlewares = append(ri.middlewares, mws...)
+	}
+}
+
+// chainMiddleware 用中间件包装处理函数，第一个中间件在最外层
+//   参数
+//     mws: 中间件
+//     h:   处理函数
+//   返回
+//     包装后的处理函数
+func chainMiddleware(mws []MiddlewareFunc, h http.Handler) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
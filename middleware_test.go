@@ -0,0 +1,92 @@
+// 中间件测试
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// headerMiddleware 测试用中间件，在X-Trace头里追加name
+func headerMiddleware(name string) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TestMiddleware 测试全局中间件和路由中间件
+func TestMiddleware(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.Use(headerMiddleware("g1"), headerMiddleware("g2"))
+	rt.AddFixed("/user", &testController{}, "IndexAction", WithMiddleware(headerMiddleware("r1")))
+	rt.AddFixed("/order", &testController{}, "IndexAction")
+	rt.AddAuto(&testController{}, WithMiddleware(headerMiddleware("a1")))
+
+	// 中间件拦截请求
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		})
+	}
+	rt.AddRegular("^/admin", &testController{}, "IndexAction", WithMiddleware(deny))
+
+	cases := []struct {
+		url    string
+		status int
+		trace  string
+	}{
+		{"/user", http.StatusOK, "g1,g2,r1"},
+		{"/order", http.StatusOK, "g1,g2"},
+		{"/test/index", http.StatusOK, "g1,g2,a1"},
+		{"/none", http.StatusNotFound, "g1,g2"},
+		{"/admin", http.StatusForbidden, "g1,g2"},
+	}
+	for _, c := range cases {
+		w := doRequest(rt, "GET", c.url)
+		if w.Code != c.status {
+			t.Errorf("GET %s status failed. Got %d, expected %d.", c.url, w.Code, c.status)
+		}
+		if trace := strings.Join(w.Header()["X-Trace"], ","); trace != c.trace {
+			t.Errorf("GET %s trace failed. Got %s, expected %s.", c.url, trace, c.trace)
+		}
+	}
+}
+
+// TestMiddlewareChain 测试路由的中间件链只创建一次，请求之间的路由信息和path参数不串
+func TestMiddlewareChain(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	built := 0
+	count := func(next http.Handler) http.Handler {
+		built++
+		return next
+	}
+	g := rt.Group("/api", count)
+	g.HandleFunc("/user/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(RoutePattern(r) + "|" + PathParam(r, "id")))
+	}, WithMiddleware(count))
+	g.HandleFunc("/order/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(RoutePattern(r) + "|" + PathParam(r, "id")))
+	})
+
+	for i, c := range []struct{ url, body string }{
+		{"/api/user/1", "/api/user/:id|1"},
+		{"/api/user/2", "/api/user/:id|2"},
+		{"/api/order/3", "/api/order/:id|3"},
+		{"/api/order/4", "/api/order/:id|4"},
+	} {
+		w := doRequest(rt, "GET", c.url)
+		if w.Code != http.StatusOK || w.Body.String() != c.body {
+			t.Errorf("GET %s %d failed. Got %d %q, expected %q.", c.url, i, w.Code, w.Body.String(), c.body)
+		}
+	}
+	if built != 3 {
+		t.Errorf("middleware built failed. Got %d, expected 3.", built)
+	}
+}
@@ -4,15 +4,30 @@
 package bingo
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/lixy529/gotools/cache"
 	"github.com/lixy529/gotools/db"
 )
 
+// Model 模型的基类，业务模型内嵌使用
+// 开启链路追踪时，不带context的FetchAll、Exec、Cache等方法只有在设置了请求的context后才记录span，
+// 在控制器里用Controller.InitModel或SetContext设置，带Context后缀的方法使用传入的ctx
 type Model struct {
-	db *db.DbHandle
-	tx *sql.Tx
+	db  *db.DbHandle
+	tx  *sql.Tx
+	ctx context.Context // 请求的context，开启链路追踪时不带context的方法也记录span
+}
+
+// SetContext 设置请求的context，开启链路追踪时FetchAll、Cache等不带context的方法也记录span
+// 没有设置时这些方法不记录span，控制器里可以直接用Controller.InitModel
+//   参数
+//     ctx: 请求的context，如：Controller.Context()
+//   返回
+//     void
+func (m *Model) SetContext(ctx context.Context) {
+	m.ctx = ctx
 }
 
 // Db 设置Db
@@ -40,7 +55,10 @@ func (m *Model) FetchOne(sqlStr string, args ...interface{}) (map[string]string,
 		m.Db()
 	}
 
-	return m.db.FetchOne(sqlStr, args...)
+	span := traceDb(m.ctx, "FetchOne", sqlStr)
+	res, err := m.db.FetchOne(sqlStr, args...)
+	span.end(err)
+	return res, err
 }
 
 // FetchOneMaster 查询主库，返回第一行
@@ -54,7 +72,10 @@ func (m *Model) FetchOneMaster(sqlStr string, args ...interface{}) (map[string]s
 		m.Db()
 	}
 
-	return m.db.FetchOneMaster(sqlStr, args...)
+	span := traceDb(m.ctx, "FetchOneMaster", sqlStr)
+	res, err := m.db.FetchOneMaster(sqlStr, args...)
+	span.end(err)
+	return res, err
 }
 
 // FetchAll 查询从库，返回所有行
@@ -68,7 +89,10 @@ func (m *Model) FetchAll(sqlStr string, args ...interface{}) (*[]map[string]stri
 		m.Db()
 	}
 
-	return m.db.FetchAll(sqlStr, args...)
+	span := traceDb(m.ctx, "FetchAll", sqlStr)
+	res, err := m.db.FetchAll(sqlStr, args...)
+	span.end(err)
+	return res, err
 }
 
 // FetchAllMaster 查询主库，返回所有行
@@ -82,7 +106,10 @@ func (m *Model) FetchAllMaster(sqlStr string, args ...interface{}) (*[]map[strin
 		m.Db()
 	}
 
-	return m.db.FetchAllMaster(sqlStr, args...)
+	span := traceDb(m.ctx, "FetchAllMaster", sqlStr)
+	res, err := m.db.FetchAllMaster(sqlStr, args...)
+	span.end(err)
+	return res, err
 }
 
 // Insert 插入操作，不支持事务
@@ -96,7 +123,10 @@ func (m *Model) Insert(sqlStr string, args ...interface{}) (int64, error) {
 		m.Db()
 	}
 
-	return m.db.Insert(sqlStr, args...)
+	span := traceDb(m.ctx, "Insert", sqlStr)
+	res, err := m.db.Insert(sqlStr, args...)
+	span.end(err)
+	return res, err
 }
 
 // Exec 更新和删除操作
@@ -110,7 +140,10 @@ func (m *Model) Exec(sqlStr string, args ...interface{}) (int64, error) {
 		m.Db()
 	}
 
-	return m.db.Exec(sqlStr, args...)
+	span := traceDb(m.ctx, "Exec", sqlStr)
+	res, err := m.db.Exec(sqlStr, args...)
+	span.end(err)
+	return res, err
 }
 
 // FetchOne 查询从库，返回第一行，支持事务
@@ -124,7 +157,10 @@ func (m *Model) TxFetchOne(sqlStr string, args ...interface{}) (map[string]strin
 		return nil, errors.New("Model: Tx is nil")
 	}
 
-	return m.db.TxFetchOne(m.tx, sqlStr, args...)
+	span := traceDb(m.ctx, "TxFetchOne", sqlStr)
+	res, err := m.db.TxFetchOne(m.tx, sqlStr, args...)
+	span.end(err)
+	return res, err
 }
 
 // FetchAll 查询从库，返回所有行
@@ -138,7 +174,10 @@ func (m *Model) TxFetchAll(sqlStr string, args ...interface{}) (*[]map[string]st
 		return nil, errors.New("Model: Tx is nil")
 	}
 
-	return m.db.TxFetchAll(m.tx, sqlStr, args...)
+	span := traceDb(m.ctx, "TxFetchAll", sqlStr)
+	res, err := m.db.TxFetchAll(m.tx, sqlStr, args...)
+	span.end(err)
+	return res, err
 }
 
 // TxInsert 插入操作，支持事务
@@ -152,7 +191,10 @@ func (m *Model) TxInsert(sqlStr string, args ...interface{}) (int64, error) {
 		return 0, errors.New("Model: Tx is nil")
 	}
 
-	return m.db.TxInsert(m.tx, sqlStr, args...)
+	span := traceDb(m.ctx, "TxInsert", sqlStr)
+	res, err := m.db.TxInsert(m.tx, sqlStr, args...)
+	span.end(err)
+	return res, err
 }
 
 // TxExec 更新和删除操作，支持事务
@@ -167,7 +209,10 @@ func (m *Model) TxExec(sqlStr string, args ...interface{}) (int64, error) {
 		return 0, errors.New("Model: Tx is nil")
 	}
 
-	return m.db.TxExec(m.tx, sqlStr, args...)
+	span := traceDb(m.ctx, "TxExec", sqlStr)
+	res, err := m.db.TxExec(m.tx, sqlStr, args...)
+	span.end(err)
+	return res, err
 }
 
 // Begin 开始事务
@@ -206,11 +251,372 @@ func (m *Model) Rollback() error {
 	return err
 }
 
-// Cache 返回一个缓存适配器
+// sqlQueryer 可以执行sql的对象，*sql.DB和*sql.Tx都实现了此接口
+type sqlQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// FetchOneContext 查询从库，返回第一行，ctx取消时中断查询
+//   参数
+//     ctx:    context，如：Controller.Context()
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (m *Model) FetchOneContext(ctx context.Context, sqlStr string, args ...interface{}) (map[string]string, error) {
+	q, err := m.slave()
+	if err != nil {
+		return nil, err
+	}
+
+	return queryOneContext(ctx, q, sqlStr, args...)
+}
+
+// FetchOneMasterContext 查询主库，返回第一行，ctx取消时中断查询
+//   参数
+//     ctx:    context
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (m *Model) FetchOneMasterContext(ctx context.Context, sqlStr string, args ...interface{}) (map[string]string, error) {
+	q, err := m.master()
+	if err != nil {
+		return nil, err
+	}
+
+	return queryOneContext(ctx, q, sqlStr, args...)
+}
+
+// FetchAllContext 查询从库，返回所有行，ctx取消时中断查询
+//   参数
+//     ctx:    context
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (m *Model) FetchAllContext(ctx context.Context, sqlStr string, args ...interface{}) (*[]map[string]string, error) {
+	q, err := m.slave()
+	if err != nil {
+		return nil, err
+	}
+
+	return queryAllContext(ctx, q, sqlStr, args...)
+}
+
+// FetchAllMasterContext 查询主库，返回所有行，ctx取消时中断查询
+//   参数
+//     ctx:    context
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (m *Model) FetchAllMasterContext(ctx context.Context, sqlStr string, args ...interface{}) (*[]map[string]string, error) {
+	q, err := m.master()
+	if err != nil {
+		return nil, err
+	}
+
+	return queryAllContext(ctx, q, sqlStr, args...)
+}
+
+// InsertContext 插入操作，不支持事务，ctx取消时中断执行
+//   参数
+//     ctx:    context
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回自增ID，失败返回错误信息
+func (m *Model) InsertContext(ctx context.Context, sqlStr string, args ...interface{}) (int64, error) {
+	q, err := m.master()
+	if err != nil {
+		return -1, err
+	}
+
+	res, err := execContext(ctx, q, sqlStr, args...)
+	if err != nil {
+		return -1, err
+	}
+	return res.LastInsertId()
+}
+
+// ExecContext 更新和删除操作，ctx取消时中断执行
+//   参数
+//     ctx:    context
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回更新或删除行数，失败返回错误信息
+func (m *Model) ExecContext(ctx context.Context, sqlStr string, args ...interface{}) (int64, error) {
+	q, err := m.master()
+	if err != nil {
+		return -1, err
+	}
+
+	res, err := execContext(ctx, q, sqlStr, args...)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+
+// TxFetchOneContext 查询，返回第一行，支持事务，ctx取消时中断查询
+//   参数
+//     ctx:    context
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (m *Model) TxFetchOneContext(ctx context.Context, sqlStr string, args ...interface{}) (map[string]string, error) {
+	if m.tx == nil {
+		return nil, errors.New("Model: Tx is nil")
+	}
+
+	return queryOneContext(ctx, m.tx, sqlStr, args...)
+}
+
+// TxFetchAllContext 查询，返回所有行，支持事务，ctx取消时中断查询
+//   参数
+//     ctx:    context
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (m *Model) TxFetchAllContext(ctx context.Context, sqlStr string, args ...interface{}) (*[]map[string]string, error) {
+	if m.tx == nil {
+		return nil, errors.New("Model: Tx is nil")
+	}
+
+	return queryAllContext(ctx, m.tx, sqlStr, args...)
+}
+
+// TxInsertContext 插入操作，支持事务，ctx取消时中断执行
+//   参数
+//     ctx:    context
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回自增ID，失败返回错误信息
+func (m *Model) TxInsertContext(ctx context.Context, sqlStr string, args ...interface{}) (int64, error) {
+	if m.tx == nil {
+		return -1, errors.New("Model: Tx is nil")
+	}
+
+	res, err := execContext(ctx, m.tx, sqlStr, args...)
+	if err != nil {
+		return -1, err
+	}
+	return res.LastInsertId()
+}
+
+// TxExecContext 更新和删除操作，支持事务，ctx取消时中断执行
+//   参数
+//     ctx:    context
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回更新或删除行数，失败返回错误信息
+func (m *Model) TxExecContext(ctx context.Context, sqlStr string, args ...interface{}) (int64, error) {
+	if m.tx == nil {
+		return -1, errors.New("Model: Tx is nil")
+	}
+
+	res, err := execContext(ctx, m.tx, sqlStr, args...)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+
+// BeginContext 开始事务，ctx取消时事务自动回滚
+//   参数
+//     ctx: context
+//   返回
+//     成功时返回事务，失败返回错误信息
+func (m *Model) BeginContext(ctx context.Context) (*sql.Tx, error) {
+	if m.db == nil {
+		m.Db()
+	}
+	if m.db == nil || m.db.GetMaster() == nil {
+		return nil, errors.New("Model: Master DB is nil")
+	}
+	var err error
+	m.tx, err = m.db.GetMaster().BeginTx(ctx, nil)
+	return m.tx, err
+}
+
+// master 返回主库
+//   参数
+//     void
+//   返回
+//     成功时返回主库，失败返回错误信息
+func (m *Model) master() (sqlQueryer, error) {
+	if m.db == nil {
+		m.Db()
+	}
+	if m.db == nil {
+		return nil, errors.New("Model: Db is nil")
+	}
+	if db := m.db.GetMaster(); db != nil {
+		return db, nil
+	}
+	return nil, errors.New("Model: Master DB is nil")
+}
+
+// slave 返回从库
+//   参数
+//     void
+//   返回
+//     成功时返回从库，失败返回错误信息
+func (m *Model) slave() (sqlQueryer, error) {
+	if m.db == nil {
+		m.Db()
+	}
+	if m.db == nil {
+		return nil, errors.New("Model: Db is nil")
+	}
+	if db := m.db.GetSlave(); db != nil {
+		return db, nil
+	}
+	return nil, errors.New("Model: Slave DB is nil")
+}
+
+// queryOneContext 查询，返回第一行
+//   参数
+//     ctx:    context
+//     q:      主库、从库或事务
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回查询结果，没有数据时返回空map，失败返回错误信息
+func queryOneContext(ctx context.Context, q sqlQueryer, sqlStr string, args ...interface{}) (map[string]string, error) {
+	list, err := queryRowsContext(ctx, q, 1, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return map[string]string{}, nil
+	}
+	return list[0], nil
+}
+
+// queryAllContext 查询，返回所有行
+//   参数
+//     ctx:    context
+//     q:      主库、从库或事务
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func queryAllContext(ctx context.Context, q sqlQueryer, sqlStr string, args ...interface{}) (*[]map[string]string, error) {
+	list, err := queryRowsContext(ctx, q, 0, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// queryRowsContext 查询，每一行转成map，NULL转成空串
+//   参数
+//     ctx:    context
+//     q:      主库、从库或事务
+//     limit:  最多返回的行数，0为不限制
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func queryRowsContext(ctx context.Context, q sqlQueryer, limit int, sqlStr string, args ...interface{}) (res []map[string]string, err error) {
+	span := traceDb(ctx, "Query", sqlStr)
+	defer func() { span.end(err) }()
+
+	rows, err := q.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	colCnt := len(columns)
+	values := make([]sql.RawBytes, colCnt)
+	scanArgs := make([]interface{}, colCnt)
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	res = make([]map[string]string, 0)
+	for rows.Next() {
+		if err = rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+
+		mapVal := make(map[string]string, colCnt)
+		for i, col := range values {
+			mapVal[columns[i]] = string(col)
+		}
+		res = append(res, mapVal)
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// execContext 执行更新、删除和插入
+//   参数
+//     ctx:    context
+//     q:      主库或事务
+//     sqlStr: Sql串
+//     args:   参数
+//   返回
+//     成功时返回执行结果，失败返回错误信息
+func execContext(ctx context.Context, q sqlQueryer, sqlStr string, args ...interface{}) (sql.Result, error) {
+	span := traceDb(ctx, "Exec", sqlStr)
+	res, err := q.ExecContext(ctx, sqlStr, args...)
+	span.end(err)
+	return res, err
+}
+
+// Cache 返回一个缓存适配器，设置了context并开启链路追踪时每次操作记录span
 //   参数
 //     adapterName: 缓存适配器名称，如：redis、memcache
 //   返回
 //     成功时返回缓存适配器对象，失败返回错误信息
 func (m *Model) Cache(adapterName ...string) (cache.Cache, error) {
-	return cache.GetCache(adapterName...)
+	c, err := cache.GetCache(adapterName...)
+	if err != nil {
+		return nil, err
+	}
+
+	// 开启指标时统计命中率，开启链路追踪时记录span，没有指定名称时查找适配器的名称
+	metric, trace := Router.metrics != nil, m.ctx != nil && SpanFromContext(m.ctx) != nil
+	if !metric && !trace {
+		return c, nil
+	}
+	name := ""
+	if len(adapterName) > 0 {
+		name = adapterName[0]
+	} else {
+		for k, v := range cache.Adapters {
+			if v == c {
+				name = k
+				break
+			}
+		}
+	}
+	if metric {
+		c = newMetricCache(c, name)
+	}
+	if trace {
+		c = newTraceCache(c, name, m.ctx)
+	}
+	return c, nil
 }
@@ -0,0 +1,591 @@
+// 限流
+// 以中间件的形式使用，可以按客户端IP、请求头、session用户或自定义的key限流，支持滑动窗口和令牌桶两种算法
+// 计数可以存在进程内存里，也可以存在initCache里注册的缓存里，多个实例共享限额
+// 应答带上RateLimit-Limit、RateLimit-Remaining、RateLimit-Reset头，超过限额时返回429并设置Retry-After，如：
+//   api := Router.Group("/api", bingo.RateLimit(bingo.RateLimitConfig{Limit: 100, Window: time.Minute}))
+//   api.Post("/login", &api.UserController{}, "LoginAction", bingo.WithMiddleware(bingo.RateLimit(bingo.RateLimitConfig{
+//       Limit: 5, Window: time.Minute, Key: bingo.RateKeyHeader("X-Api-Key"), Store: bingo.NewCacheRateStore("redis"),
+//   })))
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/go-redis/redis"
+	"github.com/lixy529/gotools/cache"
+)
+
+// 限流算法
+const (
+	RateSlidingWindow = "sliding_window" // 滑动窗口，按前一个窗口的计数加权估算，默认
+	RateTokenBucket   = "token_bucket"   // 令牌桶，允许突发Limit个请求，只支持内存存储
+)
+
+// RateKeyFunc 返回限流的key，返回空时使用客户端IP
+type RateKeyFunc func(r *http.Request) string
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	Limit     int64         // 窗口内允许的请求数，令牌桶为桶的容量
+	Window    time.Duration // 窗口大小，令牌桶为从空到补满的时间，默认1分钟
+	Algorithm string        // 限流算法，默认RateSlidingWindow
+	Key       RateKeyFunc   // 限流的key，默认RateKeyIP
+	PerRoute  bool          // 是否每个路由单独计数，用于分组的中间件
+	Name      string        // 限流规则名称，多个规则使用同一存储时区分计数，默认为rl
+	Store     RateStore     // 存储，默认为进程内存
+}
+
+// RateResult 一次限流检查的结果
+type RateResult struct {
+	Allowed   bool          // 是否放行
+	Limit     int64         // 限额
+	Remaining int64         // 剩余的请求数
+	Reset     time.Duration // 限额恢复的时间
+	Retry     time.Duration // 被拒绝时多久之后可以重试
+}
+
+// RateStore 限流计数的存储
+type RateStore interface {
+	// Incr 计数加1，key不存在时新建，ttl后过期，返回加1后的值
+	Incr(key string, ttl time.Duration) (int64, error)
+	// Get 返回计数，key不存在时返回0
+	Get(key string) (int64, error)
+}
+
+// bucketStore 支持令牌桶的存储
+type bucketStore interface {
+	take(key string, limit int64, window time.Duration, now time.Time) RateResult
+}
+
+// defaultRateStore 默认的内存存储，所有没有指定存储的规则共用
+var defaultRateStore = NewMemoryRateStore()
+
+// RateLimit 返回限流中间件，超过限额时按所属路由表和分组的错误处理输出429
+//   参数
+//     cfg: 限流配置
+//   返回
+//     中间件
+func RateLimit(cfg RateLimitConfig) MiddlewareFunc {
+	if cfg.Limit <= 0 {
+		panic("ratelimit: limit must be greater than 0")
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = RateSlidingWindow
+	}
+	if cfg.Key == nil {
+		cfg.Key = RateKeyIP
+	}
+	if cfg.Name == "" {
+		cfg.Name = "rl"
+	}
+	if cfg.Store == nil {
+		cfg.Store = defaultRateStore
+	}
+	switch cfg.Algorithm {
+	case RateSlidingWindow:
+	case RateTokenBucket:
+		if _, ok := cfg.Store.(bucketStore); !ok {
+			panic(fmt.Sprintf("ratelimit: store %T does not support token bucket", cfg.Store))
+		}
+	default:
+		panic(fmt.Sprintf("ratelimit: unknown algorithm [%s]", cfg.Algorithm))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := cfg.allow(r, time.Now())
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.FormatInt(res.Limit, 10))
+			h.Set("RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+			h.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(res.Reset), 10))
+			if res.Allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// 429
+			h.Set("Retry-After", strconv.FormatInt(ceilSeconds(res.Retry), 10))
+			rt, g := requestErrorTarget(r)
+			rt.handleError(w, r, http.StatusTooManyRequests, g, nil, "")
+		})
+	}
+}
+
+// allow 限流检查，存储出错时放行
+//   参数
+//     r:   Request对象
+//     now: 当前时间
+//   返回
+//     检查结果
+func (cfg *RateLimitConfig) allow(r *http.Request, now time.Time) RateResult {
+	key := cfg.Key(r)
+	if key == "" {
+		key = RateKeyIP(r)
+	}
+	if cfg.PerRoute {
+		key = RoutePattern(r) + ":" + key
+	}
+	key = cfg.Name + ":" + key
+
+	if cfg.Algorithm == RateTokenBucket {
+		return cfg.Store.(bucketStore).take(key, cfg.Limit, cfg.Window, now)
+	}
+
+	res, err := slidingWindow(cfg.Store, key, cfg.Limit, cfg.Window, now)
+	if err != nil {
+		flogger(r).Errorf("path[%s] err[ratelimit: %s]", r.URL.Path, err.Error())
+		return RateResult{Allowed: true, Limit: cfg.Limit, Remaining: cfg.Limit}
+	}
+	return res
+}
+
+// slidingWindow 滑动窗口限流
+// 当前窗口的计数加上前一个窗口的计数按未过去的比例加权，作为最近一个窗口时长内的请求数
+// 先加1再按加1后的计数判断，多个请求并发时不会超过限额，被拒绝的请求也计入
+//   参数
+//     store:  存储
+//     key:    限流的key
+//     limit:  窗口内允许的请求数
+//     window: 窗口大小
+//     now:    当前时间
+//   返回
+//     检查结果、错误信息
+func slidingWindow(store RateStore, key string, limit int64, window time.Duration, now time.Time) (RateResult, error) {
+	idx := now.UnixNano() / int64(window)
+	elapsed := time.Duration(now.UnixNano() - idx*int64(window))
+	weight := 1 - float64(elapsed)/float64(window)
+
+	// 当前窗口的计数要保留到下一个窗口结束
+	cur, err := store.Incr(key+":"+strconv.FormatInt(idx, 10), 2*window)
+	if err != nil {
+		return RateResult{}, err
+	}
+	prev, err := store.Get(key + ":" + strconv.FormatInt(idx-1, 10))
+	if err != nil {
+		return RateResult{}, err
+	}
+
+	res := RateResult{Limit: limit, Reset: window - elapsed}
+	if float64(prev)*weight+float64(cur) > float64(limit) {
+		// 当前窗口结束后前一个窗口的计数不再计入
+		res.Retry = window - elapsed
+		if cur < limit && prev > 0 {
+			// 前一个窗口的权重降到可以放行下一个请求的时间
+			need := (float64(limit-cur-1) / float64(prev))
+			res.Retry = time.Duration((weight - need) * float64(window))
+		}
+		return res, nil
+	}
+
+	res.Allowed = true
+	res.Remaining = limit - int64(math.Ceil(float64(prev)*weight+float64(cur)))
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	return res, nil
+}
+
+// ceilSeconds 时间向上取整到秒
+//   参数
+//     d: 时间
+//   返回
+//     秒数
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// RateKeyIP 按客户端IP限流，IP的取法同Request.ClientIp
+//   参数
+//     r: Request对象
+//   返回
+//     客户端IP
+func RateKeyIP(r *http.Request) string {
+	req := &Request{}
+	req.reSet(r)
+	return req.ClientIp()
+}
+
+// RateKeyHeader 按请求头限流，如：X-Api-Key，请求头为空时按客户端IP限流
+//   参数
+//     name: 请求头名称
+//   返回
+//     限流的key函数
+func RateKeyHeader(name string) RateKeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// RateKeySession 按session里的用户限流，如：RateKeySession("uid")，未登录时按客户端IP限流
+//   参数
+//     key: 用户标识在session里的key
+//   返回
+//     限流的key函数
+func RateKeySession(key string) RateKeyFunc {
+	return func(r *http.Request) string {
+		if GlobalSession == nil {
+			return ""
+		}
+		cookie, err := r.Cookie(AppCfg.SessCfg.CookieName)
+		if err != nil || cookie.Value == "" {
+			return ""
+		}
+		// 只读取已有的session，不存在的ID不创建，伪造的cookie按IP限流
+		sess, err := GlobalSession.Get(cookie.Value)
+		if err != nil || sess == nil {
+			return ""
+		}
+		if v := sess.Get(key); v != nil {
+			if s := fmt.Sprint(v); s != "" {
+				return "u:" + s
+			}
+		}
+		return ""
+	}
+}
+
+// MemoryRateStore 进程内存存储，只对当前实例生效，支持滑动窗口和令牌桶
+type MemoryRateStore struct {
+	mu        sync.Mutex
+	counters  map[string]*rateCounter
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+}
+
+// rateCounter 计数
+type rateCounter struct {
+	n      int64
+	expire time.Time
+}
+
+// rateBucket 令牌桶
+type rateBucket struct {
+	tokens float64   // 剩余的令牌数
+	last   time.Time // 最后一次补充令牌的时间
+	expire time.Time // 补满后即可删除
+}
+
+// NewMemoryRateStore 实例化进程内存存储
+//   参数
+//     void
+//   返回
+//     MemoryRateStore对象地址
+func NewMemoryRateStore() *MemoryRateStore {
+	return &MemoryRateStore{
+		counters:  make(map[string]*rateCounter),
+		buckets:   make(map[string]*rateBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Incr 计数加1，实现RateStore接口
+//   参数
+//     key: 计数的key
+//     ttl: 过期时间
+//   返回
+//     加1后的值、错误信息
+func (m *MemoryRateStore) Incr(key string, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	c, ok := m.counters[key]
+	if !ok || now.After(c.expire) {
+		c = &rateCounter{expire: now.Add(ttl)}
+		m.counters[key] = c
+	}
+	c.n++
+	return c.n, nil
+}
+
+// Get 返回计数，实现RateStore接口
+//   参数
+//     key: 计数的key
+//   返回
+//     计数、错误信息
+func (m *MemoryRateStore) Get(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.counters[key]
+	if !ok || time.Now().After(c.expire) {
+		return 0, nil
+	}
+	return c.n, nil
+}
+
+// take 从令牌桶里取一个令牌
+//   参数
+//     key:    令牌桶的key
+//     limit:  桶的容量
+//     window: 从空到补满的时间
+//     now:    当前时间
+//   返回
+//     检查结果
+func (m *MemoryRateStore) take(key string, limit int64, window time.Duration, now time.Time) RateResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	rate := float64(limit) / float64(window) // 每纳秒补充的令牌数
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &rateBucket{tokens: float64(limit), last: now}
+		m.buckets[key] = b
+	} else if now.After(b.last) {
+		b.tokens = math.Min(float64(limit), b.tokens+float64(now.Sub(b.last))*rate)
+		b.last = now
+	}
+
+	res := RateResult{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.Retry = time.Duration((1 - b.tokens) / rate)
+	}
+	res.Remaining = int64(b.tokens)
+	res.Reset = time.Duration((float64(limit) - b.tokens) / rate)
+	b.expire = now.Add(res.Reset)
+	return res
+}
+
+// sweep 清理过期的计数和令牌桶，每分钟最多执行一次，调用者需加锁
+//   参数
+//     now: 当前时间
+//   返回
+//     void
+func (m *MemoryRateStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for k, c := range m.counters {
+		if now.After(c.expire) {
+			delete(m.counters, k)
+		}
+	}
+	for k, b := range m.buckets {
+		if now.After(b.expire) {
+			delete(m.buckets, k)
+		}
+	}
+}
+
+// CacheRateStore 缓存存储，使用initCache里注册的缓存，多个实例共享计数，只支持滑动窗口，缓存只支持redis和memcache
+// redis使用pipeline直接读写计数并设置过期时间，不经过适配器的key前缀和编码，读写使用同一个key
+// memcache的适配器没有Add，按缓存配置直接使用客户端，不经过适配器的key前缀，key不存在时Add新建，并发新建冲突时重新加1
+type CacheRateStore struct {
+	name   string // 缓存名称，同配置文件里的缓存段名
+	kind   string // 缓存类型，redis或memcache
+	prefix string // key前缀
+
+	mu   sync.Mutex
+	memc memcClient // memcache客户端，第一次使用时按缓存配置创建
+}
+
+// memcClient 限流使用的memcache客户端接口
+type memcClient interface {
+	Add(item *memcache.Item) error
+	Increment(key string, delta uint64) (uint64, error)
+	Get(key string) (*memcache.Item, error)
+}
+
+// 缓存存储支持的缓存类型
+const (
+	rateCacheRedis    = "redis"
+	rateCacheMemcache = "memcache"
+)
+
+// NewCacheRateStore 实例化缓存存储，缓存不存在或不是redis、memcache时panic
+//   参数
+//     name: 缓存名称，同配置文件里的缓存段名，如：cache
+//   返回
+//     CacheRateStore对象地址
+func NewCacheRateStore(name string) *CacheRateStore {
+	kind := rateCacheKind(name)
+	if kind == "" {
+		panic(fmt.Sprintf("ratelimit: cache [%s] is not exists or is not redis or memcache", name))
+	}
+	return &CacheRateStore{name: name, kind: kind, prefix: "bingo:ratelimit:"}
+}
+
+// rateCacheKind 返回缓存的类型，先按配置文件查找，再按代码里注册的适配器是否支持pipeline判断
+//   参数
+//     name: 缓存名称
+//   返回
+//     缓存类型，不支持时返回空
+func rateCacheKind(name string) string {
+	for _, cfg := range AppCfg.CacheCfgs {
+		if cfg.cacheName != name {
+			continue
+		}
+		switch cfg.cacheType {
+		case "memcache":
+			return rateCacheMemcache
+		case "redisc", "redisd", "redism":
+			return rateCacheRedis
+		}
+		return ""
+	}
+
+	if c, ok := cache.Adapters[name]; ok && c != nil && c.Pipeline(false).Pipe != nil {
+		return rateCacheRedis
+	}
+	return ""
+}
+
+// adapter 返回缓存，缓存在Run时才初始化，所以每次使用时再取
+//   参数
+//     void
+//   返回
+//     缓存、错误信息
+func (s *CacheRateStore) adapter() (cache.Cache, error) {
+	c, ok := cache.Adapters[s.name]
+	if !ok || c == nil {
+		return nil, fmt.Errorf("cache [%s] is not exists", s.name)
+	}
+	return c, nil
+}
+
+// memcacheClient 返回memcache客户端
+//   参数
+//     void
+//   返回
+//     memcache客户端、错误信息
+func (s *CacheRateStore) memcacheClient() (memcClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.memc != nil {
+		return s.memc, nil
+	}
+
+	for _, cfg := range AppCfg.CacheCfgs {
+		if cfg.cacheName != s.name || cfg.cacheType != "memcache" {
+			continue
+		}
+
+		mapCfg := map[string]string{}
+		if err := json.Unmarshal([]byte(cfg.cacheConfig), &mapCfg); err != nil || mapCfg["addr"] == "" {
+			return nil, fmt.Errorf("cache [%s] config is invalid", s.name)
+		}
+		mc := memcache.New(strings.Split(mapCfg["addr"], ",")...)
+		if n, err := strconv.Atoi(mapCfg["maxIdle"]); err == nil && n > 0 {
+			mc.MaxIdleConns = n
+		}
+		if n, err := strconv.Atoi(mapCfg["ioTimeOut"]); err == nil && n > 0 {
+			mc.Timeout = time.Duration(n) * time.Millisecond
+		}
+		s.memc = mc
+		return s.memc, nil
+	}
+	return nil, fmt.Errorf("cache [%s] config is not exists", s.name)
+}
+
+// Incr 计数加1，实现RateStore接口
+//   参数
+//     key: 计数的key
+//     ttl: 过期时间
+//   返回
+//     加1后的值、错误信息
+func (s *CacheRateStore) Incr(key string, ttl time.Duration) (int64, error) {
+	key = s.prefix + key
+
+	// memcache，key不存在时Increment返回ErrCacheMiss，其它请求已经Add时重新加1
+	if s.kind == rateCacheMemcache {
+		mc, err := s.memcacheClient()
+		if err != nil {
+			return 0, err
+		}
+		for i := 0; i < 2; i++ {
+			n, err := mc.Increment(key, 1)
+			if err == nil {
+				return int64(n), nil
+			} else if err != memcache.ErrCacheMiss {
+				return 0, err
+			}
+
+			err = mc.Add(&memcache.Item{Key: key, Value: []byte("1"), Expiration: int32(ceilSeconds(ttl))})
+			if err == nil {
+				return 1, nil
+			} else if err != memcache.ErrNotStored {
+				return 0, err
+			}
+		}
+		return 0, fmt.Errorf("cache [%s] incr [%s] failed", s.name, key)
+	}
+
+	// redis
+	c, err := s.adapter()
+	if err != nil {
+		return 0, err
+	}
+	p := c.Pipeline(true)
+	if p.Pipe == nil {
+		return 0, fmt.Errorf("cache [%s] does not support ratelimit", s.name)
+	}
+	incr := p.Pipe.Incr(key)
+	p.Pipe.Expire(key, ttl)
+	if _, err := p.Pipe.Exec(); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// Get 返回计数，实现RateStore接口
+//   参数
+//     key: 计数的key
+//   返回
+//     计数、错误信息
+func (s *CacheRateStore) Get(key string) (int64, error) {
+	key = s.prefix + key
+
+	// memcache
+	if s.kind == rateCacheMemcache {
+		mc, err := s.memcacheClient()
+		if err != nil {
+			return 0, err
+		}
+		item, err := mc.Get(key)
+		if err == memcache.ErrCacheMiss {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+		return strconv.ParseInt(strings.TrimSpace(string(item.Value)), 10, 64)
+	}
+
+	// redis
+	c, err := s.adapter()
+	if err != nil {
+		return 0, err
+	}
+	p := c.Pipeline(false)
+	if p.Pipe == nil {
+		return 0, fmt.Errorf("cache [%s] does not support ratelimit", s.name)
+	}
+	get := p.Pipe.Get(key)
+	if _, err := p.Pipe.Exec(); err == redis.Nil {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return get.Int64()
+}
@@ -0,0 +1,298 @@
+package bingo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/go-redis/redis"
+	"github.com/lixy529/bingo/session"
+	_ "github.com/lixy529/bingo/session/memory"
+	"github.com/lixy529/gotools/cache"
+)
+
+// testMemc 测试用memcache客户端，key不存在时Increment返回ErrCacheMiss，key存在时Add返回ErrNotStored
+type testMemc struct {
+	mu   sync.Mutex
+	data map[string]uint64
+}
+
+func (c *testMemc) Add(item *memcache.Item) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.data[item.Key]; ok {
+		return memcache.ErrNotStored
+	}
+	n, _ := strconv.ParseUint(string(item.Value), 10, 64)
+	c.data[item.Key] = n
+	return nil
+}
+
+func (c *testMemc) Increment(key string, delta uint64) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.data[key]
+	if !ok {
+		return 0, memcache.ErrCacheMiss
+	}
+	c.data[key] = n + delta
+	return n + delta, nil
+}
+
+func (c *testMemc) Get(key string) (*memcache.Item, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.data[key]
+	if !ok {
+		return nil, memcache.ErrCacheMiss
+	}
+	return &memcache.Item{Key: key, Value: []byte(strconv.FormatUint(n, 10))}, nil
+}
+
+// redisCache 测试用缓存，行为同配置了key前缀的redis，只实现限流使用的pipeline
+// 适配器的Get、Incr等方法没有实现，限流经过适配器读写时会panic
+type redisCache struct {
+	cache.Cache
+	mu   sync.Mutex
+	data map[string]int64
+}
+
+func (c *redisCache) Pipeline(isTx bool) cache.Pipeliner {
+	return cache.Pipeliner{Pipe: &redisPipe{c: c}}
+}
+
+// redisPipe 测试用pipeline，命令加入时直接执行
+type redisPipe struct {
+	redis.Pipeliner
+	c   *redisCache
+	err error
+}
+
+func (p *redisPipe) Incr(key string) *redis.IntCmd {
+	p.c.mu.Lock()
+	defer p.c.mu.Unlock()
+	p.c.data[key]++
+	return redis.NewIntResult(p.c.data[key], nil)
+}
+
+func (p *redisPipe) Expire(key string, expiration time.Duration) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+func (p *redisPipe) Get(key string) *redis.StringCmd {
+	p.c.mu.Lock()
+	defer p.c.mu.Unlock()
+	n, ok := p.c.data[key]
+	if !ok {
+		p.err = redis.Nil
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(strconv.FormatInt(n, 10), nil)
+}
+
+func (p *redisPipe) Exec() ([]redis.Cmder, error) {
+	return nil, p.err
+}
+
+// testRateStores 返回测试用的内存、memcache和redis存储
+func testRateStores() []RateStore {
+	if rateCacheKind("ratelimit_memc") == "" {
+		AppCfg.CacheCfgs = append(AppCfg.CacheCfgs, CacheConfig{cacheName: "ratelimit_memc", cacheType: "memcache"})
+	}
+	memc := NewCacheRateStore("ratelimit_memc")
+	memc.memc = &testMemc{data: make(map[string]uint64)}
+	cache.Adapters["ratelimit_redis"] = &redisCache{data: make(map[string]int64)}
+	return []RateStore{NewMemoryRateStore(), memc, NewCacheRateStore("ratelimit_redis")}
+}
+
+// TestSlidingWindow 测试滑动窗口
+func TestSlidingWindow(t *testing.T) {
+	defer delete(cache.Adapters, "ratelimit_redis")
+
+	for _, store := range testRateStores() {
+		start := time.Unix(0, 0).Add(1000 * time.Minute)
+		for i := 0; i < 3; i++ {
+			res, err := slidingWindow(store, "k", 3, time.Minute, start)
+			if err != nil || !res.Allowed || res.Remaining != int64(2-i) {
+				t.Errorf("%T request %d failed. Got %+v, err: %v.", store, i, res, err)
+			}
+		}
+		res, _ := slidingWindow(store, "k", 3, time.Minute, start.Add(time.Second))
+		if res.Allowed || res.Retry != 59*time.Second {
+			t.Errorf("%T limit failed. Got %+v.", store, res)
+		}
+
+		// 下一个窗口过去一半，前一个窗口的4个请求按一半计入
+		next := start.Add(90 * time.Second)
+		res, _ = slidingWindow(store, "k", 3, time.Minute, next)
+		if !res.Allowed || res.Remaining != 0 {
+			t.Errorf("%T next window failed. Got %+v.", store, res)
+		}
+		res, _ = slidingWindow(store, "k", 3, time.Minute, next)
+		if res.Allowed || res.Retry != 30*time.Second {
+			t.Errorf("%T next window limit failed. Got %+v.", store, res)
+		}
+	}
+}
+
+// TestSlidingWindowConcurrent 测试并发请求不超过限额
+func TestSlidingWindowConcurrent(t *testing.T) {
+	defer delete(cache.Adapters, "ratelimit_redis")
+
+	now := time.Now()
+	for _, store := range testRateStores() {
+		var allowed int64
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if res, err := slidingWindow(store, "k", 10, time.Minute, now); err == nil && res.Allowed {
+					atomic.AddInt64(&allowed, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		if allowed != 10 {
+			t.Errorf("%T concurrent failed. Got %d, expected 10.", store, allowed)
+		}
+	}
+}
+
+// TestTokenBucket 测试令牌桶
+func TestTokenBucket(t *testing.T) {
+	store := NewMemoryRateStore()
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if res := store.take("k", 2, 2*time.Second, now); !res.Allowed {
+			t.Errorf("take %d failed. Got %+v.", i, res)
+		}
+	}
+	res := store.take("k", 2, 2*time.Second, now)
+	if res.Allowed || ceilSeconds(res.Retry) != 1 || ceilSeconds(res.Reset) != 2 {
+		t.Errorf("take limit failed. Got %+v.", res)
+	}
+	if res = store.take("k", 2, 2*time.Second, now.Add(time.Second)); !res.Allowed {
+		t.Errorf("take after refill failed. Got %+v.", res)
+	}
+
+	mustPanic(t, "token bucket with cache store", func() {
+		RateLimit(RateLimitConfig{Limit: 1, Algorithm: RateTokenBucket, Store: testRateStores()[2]})
+	})
+	mustPanic(t, "cache store without redis or memcache", func() {
+		NewCacheRateStore("none")
+	})
+	mustPanic(t, "unknown algorithm", func() {
+		RateLimit(RateLimitConfig{Limit: 1, Algorithm: "leaky"})
+	})
+}
+
+// TestRateLimit 测试限流中间件
+func TestRateLimit(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	g := rt.Group("/api", RateLimit(RateLimitConfig{Limit: 1, Window: time.Hour, PerRoute: true, Store: NewMemoryRateStore()}))
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+	g.HandleFunc("/a", ok)
+	g.HandleFunc("/b", ok)
+	rt.HandleFunc("/key", ok, WithMiddleware(RateLimit(RateLimitConfig{
+		Limit: 1, Window: time.Hour, Algorithm: RateTokenBucket, Key: RateKeyHeader("X-Api-Key"), Store: NewMemoryRateStore(),
+	})))
+
+	cases := []struct {
+		url    string
+		header string
+		value  string
+		status int
+	}{
+		{"/api/a", "", "", 200},
+		{"/api/a", "", "", 429},
+		{"/api/b", "", "", 200},
+		{"/api/a", "X-Forwarded-For", "8.8.8.8", 200},
+		{"/key", "X-Api-Key", "k1", 200},
+		{"/key", "X-Api-Key", "k1", 429},
+		{"/key", "X-Api-Key", "k2", 200},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", c.url, nil)
+		if c.header != "" {
+			r.Header.Set(c.header, c.value)
+		}
+		rt.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s %s failed. Got status %d, expected %d.", c.url, c.value, w.Code, c.status)
+		}
+		if w.Header().Get("RateLimit-Limit") != "1" || w.Header().Get("RateLimit-Reset") == "" {
+			t.Errorf("%s %s failed. Got header %v.", c.url, c.value, w.Header())
+		}
+		if c.status == 429 && w.Header().Get("Retry-After") == "" {
+			t.Errorf("%s %s failed. Retry-After is empty.", c.url, c.value)
+		}
+	}
+
+	// 429按所属路由表和分组的错误处理输出
+	g.SetErrorHandler(http.StatusTooManyRequests, &errController{}, "ApiAction")
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest("GET", "/api/a", nil))
+	if w.Code != 429 || w.Body.String() != http.StatusText(429)+"|" {
+		t.Errorf("group error handler failed. Got %d %q.", w.Code, w.Body.String())
+	}
+
+	rt2 := NewRouterTab()
+	rt2.SetReqTimeout(5)
+	rt2.Use(RateLimit(RateLimitConfig{Limit: 1, Window: time.Hour, Store: NewMemoryRateStore()}))
+	rt2.SetErrorHandler(http.StatusTooManyRequests, &errController{}, "NotFoundAction")
+	for i, status := range []int{404, 429} {
+		w := httptest.NewRecorder()
+		rt2.ServeHTTP(w, httptest.NewRequest("GET", "/none", nil))
+		if w.Code != status || (status == 429 && w.Body.String() != "nf:"+http.StatusText(429)) {
+			t.Errorf("router error handler %d failed. Got %d %q.", i, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestRateKeySession 测试按session限流，不存在的session不创建
+func TestRateKeySession(t *testing.T) {
+	mgr, err := session.NewManager("memory", "", "", 3600)
+	if err != nil {
+		t.Fatalf("NewManager failed. err: %s", err.Error())
+	}
+	old, oldName := GlobalSession, AppCfg.SessCfg.CookieName
+	GlobalSession, AppCfg.SessCfg.CookieName = mgr, "GOSESSIONID"
+	defer func() { GlobalSession, AppCfg.SessCfg.CookieName = old, oldName }()
+
+	sess, _ := mgr.GetSessData("rate-key-session")
+	sess.Set("uid", 100)
+	defer mgr.SessDestroy(httptest.NewRecorder(), sessRequest("rate-key-session"))
+
+	keyFunc := RateKeySession("uid")
+	if key := keyFunc(sessRequest("rate-key-session")); key != "u:100" {
+		t.Errorf("RateKeySession failed. Got %q, expected u:100.", key)
+	}
+
+	// 伪造的session ID按IP限流，不创建session
+	active := mgr.Stats().Active
+	for i := 0; i < 10; i++ {
+		if key := keyFunc(sessRequest("forged-" + strconv.Itoa(i))); key != "" {
+			t.Errorf("RateKeySession forged failed. Got %q, expected empty.", key)
+		}
+	}
+	if got := mgr.Stats().Active; got != active {
+		t.Errorf("RateKeySession created sessions. Got %d, expected %d.", got, active)
+	}
+}
+
+// sessRequest 返回带session cookie的请求
+func sessRequest(id string) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "GOSESSIONID", Value: id})
+	return r
+}
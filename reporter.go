@@ -0,0 +1,340 @@
+// 错误上报
+// panic、5xx应答和请求超时时调用上报接口，相同的错误在去重窗口内只上报一次
+// 内置本地JSON行文件和HTTP webhook两种实现，全局的配置取配置文件的[report]段
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 上报的错误种类
+const (
+	ReportPanic   = "panic"   // Action或中间件panic
+	ReportTimeout = "timeout" // 请求超时
+	ReportError   = "error"   // 其它5xx应答
+)
+
+// defaultReportWindow 默认的去重窗口
+const defaultReportWindow = time.Minute
+
+// reportDedupMax 去重记录超过这个数量时清理过期的记录
+const reportDedupMax = 1024
+
+// RedactHeaders 上报时隐藏值的请求头，不区分大小写
+var RedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token", "X-Csrf-Token"}
+
+// ErrorReport 上报的错误信息
+type ErrorReport struct {
+	Time       time.Time         `json:"time"`
+	Kind       string            `json:"kind"`   // 错误种类: panic | timeout | error
+	Status     int               `json:"status"` // http状态码
+	Message    string            `json:"message"`
+	Stack      string            `json:"stack,omitempty"`
+	Method     string            `json:"method"`
+	Url        string            `json:"url"`
+	Host       string            `json:"host"`
+	RemoteAddr string            `json:"remote_addr"`
+	Header     map[string]string `json:"header"`               // 请求头，敏感的请求头已隐藏
+	Route      string            `json:"route,omitempty"`      // 路由请求路径，如：/user/:id
+	Controller string            `json:"controller,omitempty"` // 控制器类型名或http.Handler的处理函数名
+	Action     string            `json:"action,omitempty"`
+	RequestId  string            `json:"request_id,omitempty"`
+	Suppressed int               `json:"suppressed,omitempty"` // 上次上报后在去重窗口内被忽略的次数
+}
+
+// ErrorReporter 错误上报接口，在单独的协程里调用
+type ErrorReporter interface {
+	Report(rep *ErrorReport) error
+}
+
+// ReportFunc 函数形式的错误上报
+type ReportFunc func(rep *ErrorReport) error
+
+// Report 实现ErrorReporter接口
+//   参数
+//     rep: 错误信息
+//   返回
+//     成功返回nil，失败返回错误信息
+func (f ReportFunc) Report(rep *ErrorReport) error {
+	return f(rep)
+}
+
+// reportDedup 错误去重
+type reportDedup struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries map[string]*dedupEntry // key为错误的摘要
+}
+
+// dedupEntry 去重记录
+type dedupEntry struct {
+	last       time.Time // 最后一次上报的时间
+	suppressed int       // 最后一次上报后被忽略的次数
+}
+
+// allow 判断错误是否需要上报
+//   参数
+//     key: 错误的摘要
+//     now: 当前时间
+//   返回
+//     是否上报、上次上报后被忽略的次数
+func (d *reportDedup) allow(key string, now time.Time) (bool, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.window <= 0 {
+		return true, 0
+	}
+
+	if e, ok := d.entries[key]; ok && now.Sub(e.last) < d.window {
+		e.suppressed++
+		return false, 0
+	}
+
+	if len(d.entries) >= reportDedupMax {
+		for k, e := range d.entries {
+			if now.Sub(e.last) >= d.window {
+				delete(d.entries, k)
+			}
+		}
+	}
+
+	suppressed := 0
+	if e, ok := d.entries[key]; ok {
+		suppressed = e.suppressed
+	}
+	d.entries[key] = &dedupEntry{last: now}
+	return true, suppressed
+}
+
+// AddReporter 添加错误上报
+//   参数
+//     rep: 错误上报
+//   返回
+//     void
+func (rt *RouterTab) AddReporter(rep ErrorReporter) {
+	rt.reporters = append(rt.reporters, rep)
+}
+
+// SetReportWindow 设置错误上报的去重窗口，相同的错误在窗口内只上报一次，默认取配置文件的[report]段
+//   参数
+//     window: 去重窗口，<=0 不去重
+//   返回
+//     void
+func (rt *RouterTab) SetReportWindow(window time.Duration) {
+	rt.dedup = newReportDedup(window)
+	rt.dedupSet = true
+}
+
+// newReportDedup 实例化错误去重
+//   参数
+//     window: 去重窗口，<=0 不去重
+//   返回
+//     reportDedup对象地址
+func newReportDedup(window time.Duration) *reportDedup {
+	return &reportDedup{window: window, entries: make(map[string]*dedupEntry)}
+}
+
+// report 上报错误，没有添加错误上报时不处理
+//   参数
+//     r:      Request对象
+//     ri:     匹配到的路由，没有匹配到路由时为nil
+//     kind:   错误种类
+//     status: http状态码
+//     perr:   panic的值，不是panic时为nil
+//     stack:  panic的堆栈
+//   返回
+//     void
+func (rt *RouterTab) report(r *http.Request, ri *RouterInfo, kind string, status int, perr interface{}, stack string) {
+	if len(rt.reporters) == 0 {
+		return
+	}
+
+	rep := &ErrorReport{
+		Time:       time.Now(),
+		Kind:       kind,
+		Status:     status,
+		Message:    http.StatusText(status),
+		Stack:      stack,
+		Method:     r.Method,
+		Url:        rt.uri(r),
+		Host:       r.Host,
+		RemoteAddr: r.RemoteAddr,
+		Header:     redactHeader(r.Header),
+		RequestId:  RequestId(r),
+	}
+	if perr != nil {
+		rep.Message = fmt.Sprint(perr)
+	}
+	if ri != nil {
+		rep.Route = ri.pattern
+		rep.Controller = ri.controllerName()
+		rep.Action = ri.method
+	}
+
+	ok, suppressed := rt.dedup.allow(reportKey(rep), rep.Time)
+	if !ok {
+		return
+	}
+	rep.Suppressed = suppressed
+
+	for _, reporter := range rt.reporters {
+		go func(reporter ErrorReporter) {
+			defer func() {
+				if err := recover(); err != nil {
+					Flogger.Errorf("report: path[%s] err[%v]", rep.Url, err)
+				}
+			}()
+			if err := reporter.Report(rep); err != nil {
+				Flogger.Errorf("report: path[%s] err[%s]", rep.Url, err.Error())
+			}
+		}(reporter)
+	}
+}
+
+// reportKey 返回错误的摘要，种类、状态码、路由和堆栈都相同的错误认为是同一个错误
+//   参数
+//     rep: 错误信息
+//   返回
+//     摘要
+func reportKey(rep *ErrorReport) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s|%d|%s|%s|%s", rep.Kind, rep.Status, rep.Host, rep.Route, rep.Stack)
+	if rep.Route == "" {
+		// 没有匹配到路由时按请求路径区分
+		h.Write([]byte(rep.Url))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// redactHeader 复制请求头，隐藏敏感的请求头
+//   参数
+//     header: 请求头
+//   返回
+//     请求头，多个值用逗号拼接
+func redactHeader(header http.Header) map[string]string {
+	m := make(map[string]string, len(header))
+	for k, v := range header {
+		m[k] = strings.Join(v, ", ")
+	}
+	for _, k := range RedactHeaders {
+		k = http.CanonicalHeaderKey(k)
+		if _, ok := m[k]; ok {
+			m[k] = "[REDACTED]"
+		}
+	}
+	return m
+}
+
+// fileReporter 错误写到本地文件，每个错误一行JSON
+type fileReporter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileReporter 实例化本地文件的错误上报，文件不存在时创建
+//   参数
+//     file: 文件路径，如：/data/log/error.json
+//   返回
+//     错误上报、错误信息
+func NewFileReporter(file string) (ErrorReporter, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0660)
+	if err != nil {
+		return nil, err
+	}
+	return &fileReporter{f: f}, nil
+}
+
+func (fr *fileReporter) Report(rep *ErrorReport) error {
+	b, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	_, err = fr.f.Write(append(b, '\n'))
+	return err
+}
+
+// webhookReporter 错误以JSON格式POST到指定的地址
+type webhookReporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookReporter 实例化HTTP webhook的错误上报
+//   参数
+//     url:     webhook地址
+//     timeout: 请求超时时间，<=0 时使用3秒
+//   返回
+//     错误上报
+func NewWebhookReporter(url string, timeout time.Duration) ErrorReporter {
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	return &webhookReporter{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (wr *webhookReporter) Report(rep *ErrorReport) error {
+	b, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	rsp, err := wr.client.Post(wr.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return fmt.Errorf("webhook [%s] status [%d]", wr.url, rsp.StatusCode)
+	}
+	return nil
+}
+
+// ReportConfig 错误上报配置
+type ReportConfig struct {
+	File           string        // 本地文件路径，为空时不写文件，相对路径是相对AppRoot
+	Webhook        string        // webhook地址，为空时不调用
+	WebhookTimeout time.Duration // webhook的请求超时时间，单位毫秒，默认3000
+	Window         time.Duration // 去重窗口，单位秒，默认60，0为不去重
+}
+
+// initReporters 按配置设置去重窗口、添加错误上报，代码里已设置去重窗口时不覆盖
+//   参数
+//     cfg: 错误上报配置
+//   返回
+//     成功返回nil，失败返回错误信息
+func (rt *RouterTab) initReporters(cfg ReportConfig) error {
+	if !rt.dedupSet {
+		rt.dedup = newReportDedup(cfg.Window * time.Second)
+	}
+	if cfg.File != "" {
+		file := cfg.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(AppRoot, file)
+		}
+		rep, err := NewFileReporter(file)
+		if err != nil {
+			return fmt.Errorf("report: file [%s] err [%s]", file, err.Error())
+		}
+		rt.AddReporter(rep)
+	}
+	if cfg.Webhook != "" {
+		rt.AddReporter(NewWebhookReporter(cfg.Webhook, cfg.WebhookTimeout*time.Millisecond))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package bingo
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestReport 测试panic、5xx和超时的上报
+func TestReport(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	reps := make(chan *ErrorReport, 10)
+	rt.AddReporter(ReportFunc(func(rep *ErrorReport) error {
+		reps <- rep
+		return nil
+	}))
+	rt.HandleFunc("/report/panic/:id", func(w http.ResponseWriter, r *http.Request) {
+		panic("report panic")
+	})
+	rt.HandleFunc("/report/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	rt.HandleFunc("/report/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	rt.HandleFunc("/report/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}, WithTimeout(20*time.Millisecond))
+
+	serve := func(url string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", url, nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		r.Header.Set("X-Request-Id", "req-1")
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, r)
+		return w
+	}
+	next := func() *ErrorReport {
+		select {
+		case rep := <-reps:
+			return rep
+		case <-time.After(time.Second):
+			t.Fatalf("report timeout")
+		}
+		return nil
+	}
+
+	// panic，相同的堆栈在去重窗口内只上报一次
+	for i := 0; i < 3; i++ {
+		if w := serve("/report/panic/1"); w.Code != http.StatusInternalServerError {
+			t.Errorf("panic failed. Got status %d, expected 500.", w.Code)
+		}
+	}
+	rep := next()
+	if rep.Kind != ReportPanic || rep.Status != 500 || rep.Message != "report panic" || rep.Stack == "" {
+		t.Errorf("panic report failed. Got %+v.", rep)
+	}
+	if rep.Route != "/report/panic/:id" || rep.Controller == "" || rep.RequestId != "req-1" {
+		t.Errorf("panic report failed. Got route %s, controller %s, request id %s.", rep.Route, rep.Controller, rep.RequestId)
+	}
+	if rep.Header["Authorization"] != "[REDACTED]" {
+		t.Errorf("redact failed. Got %s.", rep.Header["Authorization"])
+	}
+
+	// Action输出的5xx
+	serve("/report/fail")
+	if rep = next(); rep.Kind != ReportError || rep.Status != http.StatusServiceUnavailable || rep.Stack != "" {
+		t.Errorf("error report failed. Got %+v.", rep)
+	}
+
+	// 超时
+	serve("/report/slow")
+	if rep = next(); rep.Kind != ReportTimeout || rep.Status != http.StatusBadGateway {
+		t.Errorf("timeout report failed. Got %+v.", rep)
+	}
+
+	// 正常的请求不上报
+	serve("/report/ok")
+	select {
+	case rep = <-reps:
+		t.Errorf("report failed. Got %+v, expected nothing.", rep)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestReportDedup 测试去重窗口
+func TestReportDedup(t *testing.T) {
+	d := &reportDedup{window: time.Minute, entries: make(map[string]*dedupEntry)}
+	now := time.Now()
+	if ok, _ := d.allow("a", now); !ok {
+		t.Errorf("allow failed. Got false, expected true.")
+	}
+	for i := 0; i < 2; i++ {
+		if ok, _ := d.allow("a", now.Add(time.Second)); ok {
+			t.Errorf("allow failed. Got true, expected false in window.")
+		}
+	}
+	if ok, _ := d.allow("b", now); !ok {
+		t.Errorf("allow failed. Got false, expected true for other key.")
+	}
+	if ok, n := d.allow("a", now.Add(time.Minute)); !ok || n != 2 {
+		t.Errorf("allow failed. Got %v %d, expected true 2.", ok, n)
+	}
+
+	d.window = 0
+	if ok, _ := d.allow("a", now.Add(time.Minute)); !ok {
+		t.Errorf("allow failed. Got false, expected true without window.")
+	}
+}
+
+// TestReportWindow 测试代码里设置的去重窗口不被配置文件覆盖
+func TestReportWindow(t *testing.T) {
+	rt := NewRouterTab()
+	if err := rt.initReporters(ReportConfig{Window: 30}); err != nil || rt.dedup.window != 30*time.Second {
+		t.Errorf("initReporters failed. Got %v %s, expected 30s.", err, rt.dedup.window)
+	}
+
+	rt = NewRouterTab()
+	rt.SetReportWindow(0)
+	if err := rt.initReporters(ReportConfig{Window: 30}); err != nil || rt.dedup.window != 0 {
+		t.Errorf("initReporters after SetReportWindow failed. Got %v %s, expected 0s.", err, rt.dedup.window)
+	}
+}
+
+// TestFileReporter 测试写JSON行文件
+func TestFileReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bingo-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rep, err := NewFileReporter(filepath.Join(dir, "log", "error.json"))
+	if err != nil {
+		t.Fatalf("NewFileReporter failed. err: %s", err.Error())
+	}
+	rep.Report(&ErrorReport{Kind: ReportPanic, Status: 500, Route: "/a"})
+	rep.Report(&ErrorReport{Kind: ReportTimeout, Status: 502, Route: "/b"})
+
+	f, err := os.Open(filepath.Join(dir, "log", "error.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var routes []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e ErrorReport
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Unmarshal failed. err: %s", err.Error())
+		}
+		routes = append(routes, e.Route)
+	}
+	if len(routes) != 2 || routes[0] != "/a" || routes[1] != "/b" {
+		t.Errorf("FileReporter failed. Got %v.", routes)
+	}
+}
+
+// TestWebhookReporter 测试POST到webhook
+func TestWebhookReporter(t *testing.T) {
+	got := make(chan ErrorReport, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e ErrorReport
+		json.NewDecoder(r.Body).Decode(&e)
+		got <- e
+		if e.Status == 502 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	rep := NewWebhookReporter(srv.URL, time.Second)
+	if err := rep.Report(&ErrorReport{Kind: ReportPanic, Status: 500, Route: "/a"}); err != nil {
+		t.Errorf("Report failed. err: %s", err.Error())
+	}
+	if e := <-got; e.Route != "/a" || e.Kind != ReportPanic {
+		t.Errorf("Report failed. Got %+v.", e)
+	}
+	if err := rep.Report(&ErrorReport{Kind: ReportTimeout, Status: 502}); err == nil {
+		t.Errorf("Report failed. Got nil, expected error for status 500.")
+	}
+	<-got
+}
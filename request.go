@@ -4,6 +4,7 @@
 package bingo
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 	"strings"
@@ -30,6 +31,24 @@ func (req *Request) GetRequest() *http.Request {
 	return req.r
 }
 
+// Context 返回请求的context，请求超时或客户端断开连接时会被取消
+//   参数
+//     void
+//   返回
+//     请求的context
+func (req *Request) Context() context.Context {
+	return req.r.Context()
+}
+
+// RequestId 返回请求ID
+//   参数
+//     void
+//   返回
+//     请求ID
+func (req *Request) RequestId() string {
+	return RequestId(req.r)
+}
+
 // Uri 返回带参数的url
 //   参数
 //     void
@@ -279,6 +298,15 @@ func (req *Request) Referer() string {
 	return req.Header("Referer")
 }
 
+// AcceptJson 返回请求是否优先接受JSON，Accept里json在html之前或者没有html时为true
+//   参数
+//     void
+//   返回
+//     是返回true，否则返回false
+func (req *Request) AcceptJson() bool {
+	return acceptJson(req.r)
+}
+
 // ClientIp 返回客户端IP
 // 为空时返回127.0.0.1
 //   参数
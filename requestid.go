@@ -0,0 +1,296 @@
+// 请求ID
+// 请求带有合法的请求ID时沿用，否则生成一个，应答里回传同名的Header
+// 访问日志、错误上报和请求相关的日志都带上请求ID，调用其它服务时可以用RequestIdTransport转发
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lixy529/gotools/logs"
+)
+
+// defaultRequestIdHeader 默认的请求ID的Header名称
+const defaultRequestIdHeader = "X-Request-Id"
+
+// requestIdMax 请求里带的请求ID的最大长度，超过时重新生成
+const requestIdMax = 128
+
+// requestIdSeq 生成随机数失败时使用的序号
+var requestIdSeq uint64
+
+// requestIdKey 请求ID在请求context里的key
+type requestIdKey struct{}
+
+// SetRequestIdHeader 设置请求ID的Header名称，默认取配置文件[server]段的request_id_header
+//   参数
+//     name: Header名称，为空时使用X-Request-Id
+//   返回
+//     void
+func (rt *RouterTab) SetRequestIdHeader(name string) {
+	rt.reqIdHeader = http.CanonicalHeaderKey(strings.TrimSpace(name))
+}
+
+// requestIdHeader 返回请求ID的Header名称
+//   参数
+//     void
+//   返回
+//     Header名称
+func (rt *RouterTab) requestIdHeader() string {
+	if rt.reqIdHeader == "" {
+		return defaultRequestIdHeader
+	}
+	return rt.reqIdHeader
+}
+
+// withRequestId 取请求里的请求ID或生成一个，写到应答Header和请求context里
+//   参数
+//     w: ResponseWriter对象
+//     r: Request对象
+//   返回
+//     带请求ID的Request对象
+func (rt *RouterTab) withRequestId(w http.ResponseWriter, r *http.Request) *http.Request {
+	name := rt.requestIdHeader()
+	id := r.Header.Get(name)
+	if !validRequestId(id) {
+		id = newRequestId()
+	}
+	w.Header().Set(name, id)
+	return r.WithContext(context.WithValue(r.Context(), requestIdKey{}, id))
+}
+
+// RequestId 返回请求ID
+//   参数
+//     r: Request对象
+//   返回
+//     请求ID，没有经过路由时返回空
+func RequestId(r *http.Request) string {
+	return ContextRequestId(r.Context())
+}
+
+// ContextRequestId 返回context里的请求ID，用于Model等只有context的地方
+//   参数
+//     ctx: 请求的context
+//   返回
+//     请求ID，没有时返回空
+func ContextRequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// validRequestId 判断请求里带的请求ID是否可以沿用，只允许字母、数字和-_.:，避免写日志时被注入
+//   参数
+//     id: 请求ID
+//   返回
+//     可以沿用返回true，否则返回false
+func validRequestId(id string) bool {
+	if id == "" || len(id) > requestIdMax {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '-' || c == '_' || c == '.' || c == ':' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// newRequestId 生成请求ID，32位十六进制字符串
+//   参数
+//     void
+//   返回
+//     请求ID
+func newRequestId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%016x%016x", time.Now().UnixNano(), atomic.AddUint64(&requestIdSeq, 1))
+	}
+	return hex.EncodeToString(b)
+}
+
+// flogger 返回请求相关的框架日志
+//   参数
+//     r: Request对象
+//   返回
+//     日志对象
+func flogger(r *http.Request) logs.Logger {
+	return NewReqLogger(Flogger, RequestId(r))
+}
+
+// reqLogger 每条日志前加上请求ID
+// 各方法直接调用原日志的WriteMsg，showcall记录的调用位置不变
+type reqLogger struct {
+	logs.Logger
+	prefix string       // 如：reqid[9f86d081884c7d65]
+	level  *levelLogger // 原日志是levelLogger时在这里判断级别，再调用里层日志，调用位置不变
+}
+
+// NewReqLogger 返回请求相关的日志，每条日志前加上请求ID，用于把业务日志和访问日志对应起来
+//   参数
+//     logger: 日志对象，如：Glogger
+//     id:     请求ID，为空时直接返回logger
+//   返回
+//     日志对象
+func NewReqLogger(logger logs.Logger, id string) logs.Logger {
+	if logger == nil || id == "" {
+		return logger
+	}
+	l := &reqLogger{Logger: logger, prefix: "reqid[" + id + "]"}
+	if ll, ok := logger.(*levelLogger); ok {
+		l.Logger, l.level = ll.Logger, ll
+	}
+	return l
+}
+
+// enabled 返回该级别的日志是否输出
+//   参数
+//     level: 日志级别
+//   返回
+//     输出返回true，否则返回false
+func (l *reqLogger) enabled(level int) bool {
+	return l.level == nil || l.level.enabled(level)
+}
+
+func (l *reqLogger) WriteMsg(level int, fmtStr string, v ...interface{}) error {
+	if !l.enabled(level) {
+		return nil
+	}
+	return l.Logger.WriteMsg(level, "%s "+fmtStr, l.args(v)...)
+}
+
+func (l *reqLogger) Debug(v ...interface{}) {
+	if l.enabled(logs.LevelDebug) {
+		l.Logger.WriteMsg(logs.LevelDebug, "%s "+msgFormat(len(v)), l.args(v)...)
+	}
+}
+
+func (l *reqLogger) Info(v ...interface{}) {
+	if l.enabled(logs.LevelInfo) {
+		l.Logger.WriteMsg(logs.LevelInfo, "%s "+msgFormat(len(v)), l.args(v)...)
+	}
+}
+
+func (l *reqLogger) Warn(v ...interface{}) {
+	if l.enabled(logs.LevelWarn) {
+		l.Logger.WriteMsg(logs.LevelWarn, "%s "+msgFormat(len(v)), l.args(v)...)
+	}
+}
+
+func (l *reqLogger) Error(v ...interface{}) {
+	if l.enabled(logs.LevelError) {
+		l.Logger.WriteMsg(logs.LevelError, "%s "+msgFormat(len(v)), l.args(v)...)
+	}
+}
+
+func (l *reqLogger) Fatal(v ...interface{}) {
+	if l.enabled(logs.LevelFatal) {
+		l.Logger.WriteMsg(logs.LevelFatal, "%s "+msgFormat(len(v)), l.args(v)...)
+	}
+}
+
+func (l *reqLogger) Debugf(fmtStr string, v ...interface{}) {
+	if l.enabled(logs.LevelDebug) {
+		l.Logger.WriteMsg(logs.LevelDebug, "%s "+fmtStr, l.args(v)...)
+	}
+}
+
+func (l *reqLogger) Infof(fmtStr string, v ...interface{}) {
+	if l.enabled(logs.LevelInfo) {
+		l.Logger.WriteMsg(logs.LevelInfo, "%s "+fmtStr, l.args(v)...)
+	}
+}
+
+func (l *reqLogger) Warnf(fmtStr string, v ...interface{}) {
+	if l.enabled(logs.LevelWarn) {
+		l.Logger.WriteMsg(logs.LevelWarn, "%s "+fmtStr, l.args(v)...)
+	}
+}
+
+func (l *reqLogger) Errorf(fmtStr string, v ...interface{}) {
+	if l.enabled(logs.LevelError) {
+		l.Logger.WriteMsg(logs.LevelError, "%s "+fmtStr, l.args(v)...)
+	}
+}
+
+func (l *reqLogger) Fatalf(fmtStr string, v ...interface{}) {
+	if l.enabled(logs.LevelFatal) {
+		l.Logger.WriteMsg(logs.LevelFatal, "%s "+fmtStr, l.args(v)...)
+	}
+}
+
+// args 在日志参数前加上请求ID
+//   参数
+//     v: 日志参数
+//   返回
+//     加上请求ID后的参数
+func (l *reqLogger) args(v []interface{}) []interface{} {
+	return append([]interface{}{l.prefix}, v...)
+}
+
+// msgFormat 返回n个参数的日志格式，同logs包里Info等方法的格式
+//   参数
+//     n: 参数个数
+//   返回
+//     日志格式，如：%v\t%v
+func msgFormat(n int) string {
+	return strings.TrimRight(strings.Repeat("%v"+logs.MsgSep, n), logs.MsgSep)
+}
+
+// RequestIdTransport 调用其它服务时转发请求ID，请求ID取自外部请求的context
+// 开启链路追踪时同时记录调用的span，并通过traceparent传递链路
+// 如：client := &http.Client{Transport: &bingo.RequestIdTransport{}}
+//     req, _ := http.NewRequestWithContext(c.Context(), "GET", url, nil)
+type RequestIdTransport struct {
+	Base http.RoundTripper // 实际发送请求的RoundTripper，为nil时使用http.DefaultTransport
+}
+
+// RoundTrip 实现http.RoundTripper接口，请求里已经设置了请求ID时不覆盖
+//   参数
+//     req: 发送的请求
+//   返回
+//     应答、错误信息
+func (t *RequestIdTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	name := Router.requestIdHeader()
+	id := ContextRequestId(req.Context())
+	_, span := startSpan(req.Context(), "HTTP "+req.Method, SpanClient)
+	if (id != "" && req.Header.Get(name) == "") || span != nil {
+		// RoundTripper不能修改原请求
+		req = req.Clone(req.Context())
+		if id != "" && req.Header.Get(name) == "" {
+			req.Header.Set(name, id)
+		}
+	}
+	if span == nil {
+		return base.RoundTrip(req)
+	}
+
+	req.Header.Set(traceParentHeader, span.TraceParent())
+	span.SetAttr("http.method", req.Method)
+	span.SetAttr("http.url", req.URL.Redacted())
+	rsp, err := base.RoundTrip(req)
+	if err != nil {
+		span.end(err)
+		return nil, err
+	}
+	span.SetAttr("http.status_code", rsp.StatusCode)
+	if rsp.StatusCode >= 500 {
+		span.SetError(fmt.Errorf("status %d", rsp.StatusCode))
+	}
+	span.Finish()
+	return rsp, nil
+}
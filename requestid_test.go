@@ -0,0 +1,136 @@
+package bingo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lixy529/gotools/logs"
+)
+
+// TestRequestId 测试请求ID的沿用、生成和回传
+func TestRequestId(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.HandleFunc("/reqid", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(RequestId(r)))
+	})
+
+	tests := []struct {
+		header string
+		reuse  bool
+	}{
+		{"abc-123_x.y:z", true},
+		{"", false},
+		{"bad id\n", false},
+		{strings.Repeat("a", requestIdMax+1), false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/reqid", nil)
+		if test.header != "" {
+			r.Header.Set("X-Request-Id", test.header)
+		}
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, r)
+		id := w.Header().Get("X-Request-Id")
+		if id == "" || w.Body.String() != id {
+			t.Errorf("RequestId failed. Got header %s, body %s.", id, w.Body.String())
+		}
+		if (id == test.header) != test.reuse {
+			t.Errorf("RequestId [%q] failed. Got %s, expected reuse %v.", test.header, id, test.reuse)
+		}
+	}
+
+	// 自定义Header名称，404也回传
+	rt.SetRequestIdHeader("x-trace-id")
+	r := httptest.NewRequest("GET", "/none", nil)
+	r.Header.Set("X-Trace-Id", "trace-1")
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound || w.Header().Get("X-Trace-Id") != "trace-1" || w.Header().Get("X-Request-Id") != "" {
+		t.Errorf("SetRequestIdHeader failed. Got %d %v.", w.Code, w.Header())
+	}
+
+	if a, b := newRequestId(), newRequestId(); len(a) != 32 || a == b {
+		t.Errorf("newRequestId failed. Got %s %s.", a, b)
+	}
+}
+
+// msgLogger 记录WriteMsg写入的日志
+type msgLogger struct {
+	logs.Logger
+	levels []int
+	lines  []string
+}
+
+func (l *msgLogger) WriteMsg(level int, fmtStr string, v ...interface{}) error {
+	l.levels = append(l.levels, level)
+	l.lines = append(l.lines, fmt.Sprintf(fmtStr, v...))
+	return nil
+}
+
+// TestReqLogger 测试日志前加上请求ID
+func TestReqLogger(t *testing.T) {
+	if l := NewReqLogger(Flogger, ""); l != Flogger {
+		t.Errorf("NewReqLogger failed. Got wrapped logger without request id.")
+	}
+
+	ml := &msgLogger{}
+	l := NewReqLogger(ml, "req-1")
+	l.Info("a", 1)
+	l.Errorf("user[%d] 100%%", 5)
+	l.Warn()
+	expected := []string{"reqid[req-1] a\t1", "reqid[req-1] user[5] 100%", "reqid[req-1] "}
+	if len(ml.lines) != len(expected) {
+		t.Fatalf("ReqLogger failed. Got %q.", ml.lines)
+	}
+	for i, line := range expected {
+		if ml.lines[i] != line {
+			t.Errorf("ReqLogger failed. Got %q, expected %q.", ml.lines[i], line)
+		}
+	}
+	if ml.levels[0] != logs.LevelInfo || ml.levels[1] != logs.LevelError || ml.levels[2] != logs.LevelWarn {
+		t.Errorf("ReqLogger level failed. Got %v.", ml.levels)
+	}
+}
+
+// TestRequestIdTransport 测试调用其它服务时转发请求ID
+func TestRequestIdTransport(t *testing.T) {
+	got := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get("X-Request-Id")
+	}))
+	defer srv.Close()
+
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	client := &http.Client{Transport: &RequestIdTransport{}}
+	rt.HandleFunc("/call", func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		rsp, err := client.Do(req.WithContext(r.Context()))
+		if err != nil {
+			t.Errorf("Do failed. err: %s", err.Error())
+			return
+		}
+		rsp.Body.Close()
+	})
+
+	r := httptest.NewRequest("GET", "/call", nil)
+	r.Header.Set("X-Request-Id", "out-1")
+	rt.ServeHTTP(httptest.NewRecorder(), r)
+	if id := <-got; id != "out-1" {
+		t.Errorf("RequestIdTransport failed. Got %s, expected out-1.", id)
+	}
+
+	// 不是请求里发出的调用不设置
+	rsp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get failed. err: %s", err.Error())
+	}
+	rsp.Body.Close()
+	if id := <-got; id != "" {
+		t.Errorf("RequestIdTransport failed. Got %s, expected empty.", id)
+	}
+}
@@ -4,36 +4,82 @@
 package bingo
 
 import (
+	"context"
+	"fmt"
 	"github.com/lixy529/gotools/utils"
 	"net/http"
-	"path"
-	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
-	"runtime"
 )
 
 type ShellFunc func()
 
+// StatusClientClosed 客户端在应答前断开连接时记录的状态码，同nginx
+const StatusClientClosed = 499
+
+// httpMethods 支持的请求方法，顺序即为Allow头里的输出顺序
+var httpMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "CONNECT", "TRACE"}
+
 // RouterInfo 路由信息
 type RouterInfo struct {
 	controllerType reflect.Type
 	method         string
+	httpMethods    []string         // 允许的请求方法，为空时不限制
+	middlewares    []MiddlewareFunc // 路由的中间件
+	group          *RouterGroup     // 路由所属的分组，不属于分组时为nil
+	name           string           // 路由名称，用于生成url
+	pattern        string           // 路由请求路径，包含分组前缀
+	host           *hostRule        // 域名限定，不限域名时为nil
+	timeout        time.Duration    // 请求超时时间，为0时使用分组或全局的设置，NoTimeout为不限制
+	handler        http.Handler     // http.Handler路由的处理函数，控制器路由为nil
+	chain          *routeChain      // 分组中间件和路由中间件包装后的处理函数，复制后共用
+}
+
+// routeChain 路由的中间件链，第一次匹配到路由时创建
+type routeChain struct {
+	once    sync.Once
+	handler http.Handler
+}
+
+// regularRouter 正则路由
+type regularRouter struct {
+	pattern  string         // 正则表达式
+	re       *regexp.Regexp // 编译后的正则
+	priority int            // 优先级，值越大越先匹配
+	infos    []RouterInfo
 }
 
 // RouterTab 路由表
 type RouterTab struct {
-	fixedRouters   map[string]RouterInfo // 固定路由列表
-	regularRouters map[string]RouterInfo // 正则路由列表
-	autoRouters    map[string]RouterInfo // 自动路由列表
-	shellRouters   map[string]ShellFunc  // 脚本路由列表
+	tree           *treeNode               // 固定路由和自动路由的前缀树
+	regularRouters []*regularRouter        // 正则路由列表，按优先级和添加顺序排列
+	shellRouters   map[string]ShellFunc    // 脚本路由列表
+	namedRouters   map[string]*namedRouter // 命名路由列表
+
+	middlewares []MiddlewareFunc // 全局中间件
+	handler     http.Handler     // 全局中间件包装后的处理函数
+
+	groups        []*RouterGroup        // 所有的路由分组，用于查找没有匹配到路由时的错误处理
+	errorHandlers map[int]*errorHandler // 全局的错误处理，key为http状态码
 
-	maxPathCnt int           // 路由最大路径个数，比如/aa/bb/cc，则值为3
-	minPathCnt int           // 路由最小路径个数，不能小于2
 	reqTimeout time.Duration // 请求超时时间
+	limiter    *limiter      // 全局的准入控制，不限制时为nil
+	limitSet   bool          // 代码里是否设置过全局的准入控制，设置过时不使用配置文件
+	cors       *cors         // 全局的跨域配置，不处理跨域时为nil
+
+	reporters []ErrorReporter // 错误上报
+	dedup     *reportDedup    // 错误上报的去重
+	dedupSet  bool            // 代码里是否设置过去重窗口，设置过时不使用配置文件
+	access    *accessLogger   // 访问日志的格式和记录规则，为nil时使用bingo格式
+
+	reqIdHeader string   // 请求ID的Header名称，为空时使用X-Request-Id
+	metrics     *metrics // 请求相关的指标，没有开启时为nil
+
+	probes map[string]http.Handler // 健康检查和指标等内部路径，不经过准入控制、全局中间件和请求超时
 }
 
 // NewRouterTab 实例化一个路由表
@@ -43,8 +89,9 @@ type RouterTab struct {
 //     路由表对象
 func NewRouterTab() *RouterTab {
 	rt := &RouterTab{}
-	rt.maxPathCnt = 0
-	rt.minPathCnt = 99999
+	rt.tree = &treeNode{}
+	rt.handler = http.HandlerFunc(rt.dispatch)
+	rt.dedup = newReportDedup(defaultReportWindow)
 	return rt
 }
 
@@ -56,64 +103,371 @@ func NewRouterTab() *RouterTab {
 func (rt *RouterTab) SetReqTimeout(reqTimeout time.Duration) {
 	if reqTimeout <= 0 {
 		rt.reqTimeout = 10
+		return
 	}
 	rt.reqTimeout = reqTimeout
 }
 
-// AddFixed 添加固定路由，路径不区分大小，同一路径设置多次，后面会覆盖前面
+// AddFixed 添加固定路由，路径不区分大小，同一路径同一请求方法设置多次，后面会覆盖前面
+// 控制器方法名前可以加上请求方法限定，多个用逗号分隔，如："post:CreateAction"、"get,post:IndexAction"
+// 路径里可以带命名参数和通配参数，如："/user/:id(int)/orders/:orderId"、"/files/*path"
+// 参数类型支持string(默认)、int、float、alpha、alnum，类型不匹配时返回404
 //   参数
 //     pattern: 路由请求路径
 //     c:       控制器对象地址
 //     method:  控制器方法名
-//     args:    支持路径前面拼接此信息，比如路径上带上版本v3.0
+//     args:    其它信息，支持以下两种类型
+//       1): string型，支持路径前面拼接此信息，比如路径上带上版本v3.0
+//       2): RouteOption型，路由选项，如：WithMiddleware(mw)、WithName(name)、WithHost(host)
 //   返回
 //     void
-func (rt *RouterTab) AddFixed(pattern string, c ControllerInterface, method string, args ...string) {
+func (rt *RouterTab) AddFixed(pattern string, c ControllerInterface, method string, args ...interface{}) {
 	reflectVal := reflect.ValueOf(c)
 	t := reflect.Indirect(reflectVal).Type()
 	routeInfo := RouterInfo{}
 	routeInfo.controllerType = t
-	routeInfo.method = method
-	if rt.fixedRouters == nil {
-		rt.fixedRouters = make(map[string]RouterInfo)
-	}
-	pattern = strings.ToLower(strings.TrimRight(pattern, "/"))
+	routeInfo.httpMethods, routeInfo.method = parseMethod(method)
+	rt.addFixed(pattern, routeInfo, args)
+}
+
+// addFixed 添加固定路由，控制器路由和http.Handler路由共用
+//   参数
+//     pattern:   路由请求路径
+//     routeInfo: 路由信息
+//     args:      其它信息，同AddFixed
+//   返回
+//     void
+func (rt *RouterTab) addFixed(pattern string, routeInfo RouterInfo, args []interface{}) {
+	pattern = strings.TrimRight(pattern, "/")
 	if pattern == "" {
 		pattern = "/"
 	}
-	if len(args) > 0 {
-		ext := strings.Trim(args[0], "/")
-		if ext != "" {
-			pattern = "/" + strings.ToLower(ext) + pattern
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			ext := strings.Trim(v, "/")
+			if ext != "" {
+				pattern = "/" + ext + pattern
+			}
+		case RouteOption:
+			v(&routeInfo)
 		}
 	}
-	rt.fixedRouters[pattern] = routeInfo
-	cnt := rt.getPathCnt(pattern)
-	if rt.maxPathCnt < cnt {
-		rt.maxPathCnt = cnt
+	if routeInfo.group != nil {
+		pattern = routeInfo.group.prefix + pattern
 	}
-	if cnt > 1 && rt.minPathCnt > cnt {
-		rt.minPathCnt = cnt
+
+	routeInfo.pattern = pattern
+	leaf := rt.insert(pattern)
+	if old := overlapRouter(leaf.fixed, routeInfo); old != nil {
+		rt.conflict("fixed route [%s] %s overrides %s", pattern, routeInfo.desc(), old.desc())
 	}
+	if old := overlapRouter(leaf.auto, routeInfo); old != nil {
+		rt.conflict("fixed route [%s] %s shadows auto route %s", pattern, routeInfo.desc(), old.desc())
+	}
+	leaf.fixed = addRouterInfo(leaf.fixed, routeInfo)
+	rt.addName(routeInfo.name, pattern, false)
 }
 
-// AddRegular 添加正则路由，同一路径设置多次，后面会覆盖前面
+// AddRegular 添加正则路由，同一路径同一请求方法设置多次，后面会覆盖前面
+// 控制器方法名前可以加上请求方法限定，规则同AddFixed
+// 正则表达式在添加时编译，匹配时按优先级从高到低、同优先级按添加顺序匹配
+// 正则里的命名分组会做为path参数，如：^/user/(?P<id>\d+)$，可用ParamString("id")获取
 //   参数
 //     pattern: 路由请求路径正则表达式
 //     c:       控制器对象地址
 //     method:  控制器方法名
+//     args:    其它信息，支持以下两种类型
+//       1): int型，优先级，默认为0，值越大越先匹配
+//       2): RouteOption型，路由选项，如：WithMiddleware(mw)、WithName(name)、WithHost(host)
 //   返回
 //     void
-func (rt *RouterTab) AddRegular(pattern string, c ControllerInterface, method string) {
+func (rt *RouterTab) AddRegular(pattern string, c ControllerInterface, method string, args ...interface{}) {
 	reflectVal := reflect.ValueOf(c)
 	t := reflect.Indirect(reflectVal).Type()
 	routeInfo := RouterInfo{}
 	routeInfo.controllerType = t
-	routeInfo.method = method
-	if rt.regularRouters == nil {
-		rt.regularRouters = make(map[string]RouterInfo)
+	routeInfo.httpMethods, routeInfo.method = parseMethod(method)
+
+	priority := 0
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case int:
+			priority = v
+		case RouteOption:
+			v(&routeInfo)
+		}
+	}
+	if routeInfo.group != nil {
+		// 分组里的正则从分组前缀之后开始匹配
+		pattern = "^" + regexp.QuoteMeta(routeInfo.group.prefix) + strings.TrimPrefix(pattern, "^")
+	}
+
+	routeInfo.pattern = pattern
+	rt.addName(routeInfo.name, pattern, true)
+	for _, rr := range rt.regularRouters {
+		if rr.pattern == pattern {
+			if old := overlapRouter(rr.infos, routeInfo); old != nil {
+				rt.conflict("regular route [%s] %s overrides %s", pattern, routeInfo.desc(), old.desc())
+			}
+			rr.infos = addRouterInfo(rr.infos, routeInfo)
+			return
+		}
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("router: regular pattern [%s] is error, %s", pattern, err.Error()))
+	}
+	rt.regularRouters = append(rt.regularRouters, &regularRouter{
+		pattern:  pattern,
+		re:       re,
+		priority: priority,
+		infos:    addRouterInfo(nil, routeInfo),
+	})
+
+	// 稳定排序，同优先级保持添加顺序
+	sort.SliceStable(rt.regularRouters, func(i, j int) bool {
+		return rt.regularRouters[i].priority > rt.regularRouters[j].priority
+	})
+}
+
+// Get 添加只响应GET(HEAD)请求的固定路由
+//   参数
+//     pattern: 路由请求路径
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同AddFixed
+//   返回
+//     void
+func (rt *RouterTab) Get(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	rt.AddFixed(pattern, c, "GET:"+method, args...)
+}
+
+// Post 添加只响应POST请求的固定路由
+//   参数
+//     pattern: 路由请求路径
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同AddFixed
+//   返回
+//     void
+func (rt *RouterTab) Post(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	rt.AddFixed(pattern, c, "POST:"+method, args...)
+}
+
+// Put 添加只响应PUT请求的固定路由
+//   参数
+//     pattern: 路由请求路径
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同AddFixed
+//   返回
+//     void
+func (rt *RouterTab) Put(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	rt.AddFixed(pattern, c, "PUT:"+method, args...)
+}
+
+// Delete 添加只响应DELETE请求的固定路由
+//   参数
+//     pattern: 路由请求路径
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同AddFixed
+//   返回
+//     void
+func (rt *RouterTab) Delete(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	rt.AddFixed(pattern, c, "DELETE:"+method, args...)
+}
+
+// Patch 添加只响应PATCH请求的固定路由
+//   参数
+//     pattern: 路由请求路径
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同AddFixed
+//   返回
+//     void
+func (rt *RouterTab) Patch(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	rt.AddFixed(pattern, c, "PATCH:"+method, args...)
+}
+
+// parseMethod 解析控制器方法名，拆出请求方法限定
+// 比如"get,post:IndexAction"，返回[GET POST]和IndexAction
+//   参数
+//     method: 控制器方法名
+//   返回
+//     请求方法列表(为空不限制)、控制器方法名
+func parseMethod(method string) ([]string, string) {
+	pos := strings.Index(method, ":")
+	if pos < 0 {
+		return nil, method
+	}
+
+	var methods []string
+	for _, m := range strings.Split(method[:pos], ",") {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m == "" {
+			continue
+		}
+		if m == "*" {
+			return nil, method[pos+1:]
+		}
+		if !isHttpMethod(m) {
+			panic(fmt.Sprintf("router: unknown http method [%s] in [%s]", m, method))
+		}
+		methods = append(methods, m)
+	}
+
+	return methods, method[pos+1:]
+}
+
+// isHttpMethod 判断是否是支持的请求方法
+//   参数
+//     m: 请求方法，需要大写
+//   返回
+//     是返回true，否则返回false
+func isHttpMethod(m string) bool {
+	for _, v := range httpMethods {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
+
+// addRouterInfo 添加一条路由信息到同一路径的路由列表
+// 请求方法和域名限定都相同的路由会被覆盖
+//   参数
+//     infos:     同一路径已有的路由列表
+//     routeInfo: 要添加的路由
+//   返回
+//     新的路由列表
+func addRouterInfo(infos []RouterInfo, routeInfo RouterInfo) []RouterInfo {
+	routeInfo.chain = &routeChain{}
+	for i, info := range infos {
+		if sameMethods(info.httpMethods, routeInfo.httpMethods) && info.host.String() == routeInfo.host.String() {
+			infos[i] = routeInfo
+			return infos
+		}
 	}
-	rt.regularRouters[pattern] = routeInfo
+	return append(infos, routeInfo)
+}
+
+// sameMethods 判断两个请求方法列表是否相同
+//   参数
+//     a: 请求方法列表
+//     b: 请求方法列表
+//   返回
+//     相同返回true，否则返回false
+func sameMethods(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, m := range a {
+		found := false
+		for _, n := range b {
+			if m == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// allowMethod 判断路由是否允许指定的请求方法，允许GET的路由同时允许HEAD
+//   参数
+//     method: 请求方法
+//   返回
+//     允许返回true，否则返回false
+func (ri *RouterInfo) allowMethod(method string) bool {
+	if len(ri.httpMethods) == 0 {
+		return true
+	}
+	for _, m := range ri.httpMethods {
+		if m == method || (m == "GET" && method == "HEAD") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMethod 在同一路径的路由列表里查找允许当前请求域名和请求方法的路由
+// 优先匹配域名限定更精确的路由，域名限定相同时优先匹配明确指定了请求方法的路由
+//   参数
+//     infos:  同一路径的路由列表
+//     method: 请求方法
+//     host:   请求域名
+//     allows: 路径匹配但请求方法不匹配时，收集允许的请求方法
+//   返回
+//     匹配到的路由、是否匹配成功
+func matchMethod(infos []RouterInfo, method, host string, allows map[string]bool) (RouterInfo, bool) {
+	var best *RouterInfo
+	bestRank := -1
+	for i := range infos {
+		info := &infos[i]
+		rank := info.host.rank(host)
+		if rank < 0 {
+			continue
+		}
+		rank *= 2
+		if len(info.httpMethods) > 0 {
+			if !info.allowMethod(method) {
+				for _, m := range info.httpMethods {
+					allows[m] = true
+				}
+				continue
+			}
+			rank++
+		}
+		if rank > bestRank {
+			best, bestRank = info, rank
+		}
+	}
+	if best != nil {
+		return *best, true
+	}
+	return RouterInfo{}, false
+}
+
+// allowHeader 生成Allow头
+//   参数
+//     allows: 允许的请求方法
+//   返回
+//     Allow头，如：GET, HEAD, POST, OPTIONS
+func allowHeader(allows map[string]bool) string {
+	if allows["GET"] {
+		allows["HEAD"] = true
+	}
+	allows["OPTIONS"] = true
+
+	list := make([]string, 0, len(allows))
+	for m := range allows {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return methodIndex(list[i]) < methodIndex(list[j])
+	})
+
+	return strings.Join(list, ", ")
+}
+
+// methodIndex 返回请求方法在httpMethods里的下标，用于排序
+//   参数
+//     m: 请求方法
+//   返回
+//     下标
+func methodIndex(m string) int {
+	for i, v := range httpMethods {
+		if v == m {
+			return i
+		}
+	}
+	return len(httpMethods)
 }
 
 // AddAuto 添加自动路由，路径不区分大小
@@ -122,9 +476,19 @@ func (rt *RouterTab) AddRegular(pattern string, c ControllerInterface, method st
 //     args: 其它信息，支持以下两个字段
 //       1): bool型，路径上是否带上包名
 //       2): string型，支持路径前面拼接此信息，比如路径上带上版本v3.0, /v3.0/api/user/index
+//     另外可以在任意位置传入RouteOption型的路由选项，对所有Action生效
+//     WithName(name)设置的名称会拼上Action名，如：WithName("user")，IndexAction的名称为user.index
 //   返回
 //     void
 func (rt *RouterTab) AddAuto(c ControllerInterface, args ...interface{}) {
+	// 路由选项
+	var opts []RouteOption
+	for _, arg := range args {
+		if opt, ok := arg.(RouteOption); ok {
+			opts = append(opts, opt)
+		}
+	}
+
 	// 路径上是否带上包名
 	usePackage := false
 	if len(args) > 0 {
@@ -172,21 +536,30 @@ func (rt *RouterTab) AddAuto(c ControllerInterface, args ...interface{}) {
 			routeInfo := RouterInfo{}
 			routeInfo.controllerType = t
 			routeInfo.method = method
-			if rt.autoRouters == nil {
-				rt.autoRouters = make(map[string]RouterInfo)
+			for _, opt := range opts {
+				opt(&routeInfo)
 			}
 			if ext != "" {
 				pattern = "/" + strings.ToLower(ext) + pattern
 			}
+			if routeInfo.group != nil {
+				pattern = routeInfo.group.prefix + pattern
+			}
+			if routeInfo.name != "" {
+				// 自动路由的名称为：名称.Action名，如：user.index
+				routeInfo.name += "." + strings.ToLower(methodPart)
+			}
 
-			rt.autoRouters[pattern] = routeInfo
-			cnt := rt.getPathCnt(pattern)
-			if rt.maxPathCnt < cnt {
-				rt.maxPathCnt = cnt
+			routeInfo.pattern = pattern
+			leaf := rt.insert(pattern)
+			if old := overlapRouter(leaf.auto, routeInfo); old != nil {
+				rt.conflict("auto route [%s] %s overrides %s", pattern, routeInfo.desc(), old.desc())
 			}
-			if cnt > 1 && rt.minPathCnt > cnt {
-				rt.minPathCnt = cnt
+			if old := overlapRouter(leaf.fixed, routeInfo); old != nil {
+				rt.conflict("auto route [%s] %s is shadowed by fixed route %s", pattern, routeInfo.desc(), old.desc())
 			}
+			leaf.auto = addRouterInfo(leaf.auto, routeInfo)
+			rt.addName(routeInfo.name, pattern, false)
 		}
 	}
 }
@@ -208,287 +581,476 @@ func (rt *RouterTab) uri(r *http.Request) string {
 	return r.URL.Path + "?" + r.URL.RawQuery
 }
 
-// ServeHTTP 实现http.Handler接口，匹配路由顺序：固定路由 => 自动路由 => 正则路由
+// ServeHTTP 实现http.Handler接口，请求先经过全局中间件，再分发到静态文件或路由，结束后写访问日志
 //   参数
 //     w: ResponseWriter对象
 //     r: Request对象
 //   返回
 //     void
 func (rt *RouterTab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 记录实际输出的状态码和字节数，请求结束后写访问日志和记录指标
+	start := time.Now()
+	aw := &accessWriter{ResponseWriter: w, rt: rt}
+	w = aw
+	r = r.WithContext(context.WithValue(r.Context(), accessKey{}, aw))
+	r = rt.withRequestId(w, r)
+	defer rt.accessLog(aw, r, start)
+	defer rt.observe(aw, r, start)
+	r, span := startServerSpan(r)
+	defer finishServerSpan(aw, r, span)
+
 	defer func() {
 		if err := recover(); err != nil {
 			stack := utils.Stack()
-			Flogger.Errorf("path[%s] err[%v] stack[%v]", rt.uri(r), err, stack)
-			rt.accessLog(r, http.StatusInternalServerError)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError) // 500
+			flogger(r).Errorf("path[%s] err[%v] stack[%v]", rt.uri(r), err, stack)
+			rt.report(r, nil, ReportPanic, http.StatusInternalServerError, err, stack)
+			rt.handleError(w, r, http.StatusInternalServerError, nil, err, stack) // 500
 			return
 		}
 	}()
 
-	if AppCfg.ServerCfg.MaxGoCnt > 0 {
-		curGoCnt := runtime.NumGoroutine()
-		if curGoCnt > AppCfg.ServerCfg.MaxGoCnt {
-			Flogger.Errorf("curGoCnt[%d] maxGoCnt[%d]", curGoCnt, AppCfg.ServerCfg.MaxGoCnt)
-			rt.accessLog(r, http.StatusBadGateway)
-			http.Error(w, "Internal Server Error", http.StatusBadGateway) // 502
-			return
-		}
+	// 健康检查和指标在准入控制之前应答，限流时探针也能返回
+	if h := rt.probe(r); h != nil {
+		h.ServeHTTP(w, r)
+		return
 	}
 
-	// 处理url path
-	realPath := utils.DelRepeat(r.URL.Path, '/')
-	if realPath != "/" {
-		realPath = strings.TrimRight(realPath, "/")
+	// 全局准入控制
+	if !rt.admit(w, r, rt.limiter, nil) {
+		return
+	}
+	if rt.limiter != nil {
+		defer rt.limiter.release()
 	}
 
-	// 静态路由
-	if rt.staticRouter(w, r, realPath) {
-		return
+	rt.handler.ServeHTTP(w, r)
+}
+
+// handleProbe 添加内部路径，只响应GET和HEAD请求，路径需要完全匹配
+// 在准入控制、全局中间件和路由匹配之前应答，只能在初始化时调用
+//   参数
+//     path: 请求路径，如：/healthz
+//     h:    处理函数
+//   返回
+//     void
+func (rt *RouterTab) handleProbe(path string, h http.Handler) {
+	if rt.probes == nil {
+		rt.probes = make(map[string]http.Handler)
 	}
+	rt.probes[path] = h
+}
 
-	var runRouter reflect.Type
-	var routeInfo RouterInfo
-	var param map[string]string
-	ok := false
-	var urlPath string
-	urlPath = strings.ToLower(realPath)
-	curPathCnt := rt.getPathCnt(urlPath)
+// probe 返回请求对应的内部路径的处理函数
+//   参数
+//     r: Request对象
+//   返回
+//     处理函数，不是内部路径时返回nil
+func (rt *RouterTab) probe(r *http.Request) http.Handler {
+	if len(rt.probes) == 0 || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return nil
+	}
+	return rt.probes[r.URL.Path]
+}
 
-	// 固定路由
-	routeInfo, ok = rt.fixedRouters[urlPath]
-	if ok {
-		goto RUNNING
+// dispatch 分发请求，匹配路由顺序：固定路由 => 自动路由 => 带参数的路由 => 正则路由
+// 路径匹配但请求方法不匹配时返回405，并设置Allow头，OPTIONS请求自动应答
+//   参数
+//     w: ResponseWriter对象
+//     r: Request对象
+//   返回
+//     void
+func (rt *RouterTab) dispatch(w http.ResponseWriter, r *http.Request) {
+	// 处理url path
+	realPath := cleanPath(r.URL.Path)
+
+	// 查找路由，跨域的预检请求按要使用的请求方法查找
+	host := requestHost(r)
+	method, preflight := r.Method, isPreflight(r)
+	if preflight {
+		method = strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
 	}
+	routeInfo, param, allows, ok := rt.findRouter(method, host, realPath)
 
-	// 自动路由
-	routeInfo, ok = rt.autoRouters[urlPath]
+	// 跨域处理，预检请求在分发到控制器之前应答
+	var corsRoute *RouterInfo
 	if ok {
-		goto RUNNING
+		corsRoute = &routeInfo
+	}
+	if rt.handleCors(w, r, host, realPath, corsRoute) {
+		return
+	}
+	if preflight {
+		// 没有应答的预检请求按OPTIONS请求处理
+		routeInfo, param, allows, ok = rt.findRouter(r.Method, host, realPath)
 	}
 
-	// 正则路由，正则路由是否区分大小要看正则表达如果写
-	routeInfo, ok = rt.regularMatch(realPath)
-	if ok {
-		goto RUNNING
+	// 静态路由
+	if rt.staticRouter(w, r, realPath) {
+		return
 	}
 
-	// 全路径未匹配到
-	for i := rt.maxPathCnt; i >= rt.minPathCnt; i-- {
-		if i > curPathCnt {
-			continue;
-		}
-		urlPath = rt.getPattern(urlPath, i)
-		if urlPath == "" {
-			break
+	if !ok {
+		if len(allows) > 0 {
+			w.Header().Set("Allow", allowHeader(allows))
+			if r.Method == "OPTIONS" {
+				// 未注册OPTIONS的路由自动应答
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			rt.handleError(w, r, http.StatusMethodNotAllowed, rt.matchGroup(host, realPath, (*RouterGroup).hasErrorHandler), nil, "") // 405
+			return
 		}
 
-		// 固定路由
-		routeInfo, ok = rt.fixedRouters[urlPath]
-		if ok {
-			param = rt.getParam(realPath, i)
-			goto RUNNING
+		// SPA回退
+		if rt.spaRouter(w, r, realPath) {
+			return
 		}
 
-		// 自动路由
-		routeInfo, ok = rt.autoRouters[urlPath]
-		if ok {
-			param = rt.getParam(realPath, i)
-			goto RUNNING
-		}
+		rt.handleError(w, r, http.StatusNotFound, rt.matchGroup(host, realPath, (*RouterGroup).hasErrorHandler), nil, "") // 404
+		return
+	}
+
+	// 记录匹配到的路由，中间件可以用RoutePattern获取
+	r = r.WithContext(context.WithValue(r.Context(), routeKey{}, &routeMatch{info: &routeInfo, param: param}))
+	setAccessRoute(r, &routeInfo)
 
-		// 正则路由，正则路由是否区分大小要看正则表达如果写
-		routeInfo, ok = rt.regularMatch(realPath)
-		if ok {
-			param = rt.getParam(realPath, i)
-			goto RUNNING
+	// 分组准入控制
+	for _, l := range routeInfo.group.allLimiters() {
+		if !rt.admit(w, r, l, routeInfo.group) {
+			return
 		}
+		defer l.release()
+	}
+	if done := rt.trackInflight(&routeInfo); done != nil {
+		defer done()
 	}
 
-	rt.accessLog(r, http.StatusNotFound)
-	if AppCfg.ServerCfg.Url404 != "" {
-		http.Redirect(w, r, AppCfg.ServerCfg.Url404, http.StatusFound)
-	} else {
-		http.NotFound(w, r)
+	// 分组中间件和路由中间件
+	rt.routeHandler(&routeInfo).ServeHTTP(w, r)
+}
+
+// routeHandler 返回分组中间件和路由中间件包装后的处理函数，每个路由只创建一次
+// 中间件链在第一次匹配到路由时创建，之后添加的分组中间件不再生效
+//   参数
+//     ri: 路由信息
+//   返回
+//     处理函数
+func (rt *RouterTab) routeHandler(ri *RouterInfo) http.Handler {
+	build := func() http.Handler {
+		h := chainMiddleware(ri.middlewares, http.HandlerFunc(rt.serveRoute))
+		return chainMiddleware(ri.group.allMiddlewares(), h)
+	}
+	if ri.chain == nil {
+		return build()
 	}
-	return
+	ri.chain.once.Do(func() {
+		ri.chain.handler = build()
+	})
+	return ri.chain.handler
+}
 
-RUNNING:
-	runRouter = routeInfo.controllerType
+// serveRoute 执行请求context里记录的路由，在中间件链的最内层
+//   参数
+//     w: ResponseWriter对象
+//     r: Request对象
+//   返回
+//     void
+func (rt *RouterTab) serveRoute(w http.ResponseWriter, r *http.Request) {
+	m := r.Context().Value(routeKey{}).(*routeMatch)
+	rt.runController(w, r, *m.info, m.param)
+}
+
+// cleanPath 处理url path，去掉重复的/和结尾的/
+//   参数
+//     urlPath: 请求路径
+//   返回
+//     处理后的路径
+func cleanPath(urlPath string) string {
+	realPath := utils.DelRepeat(urlPath, '/')
+	if realPath != "/" {
+		realPath = strings.TrimRight(realPath, "/")
+	}
+	return realPath
+}
+
+// routeKey 匹配到的路由在请求context里的key
+type routeKey struct{}
+
+// routeMatch 匹配到的路由和path参数
+type routeMatch struct {
+	info  *RouterInfo
+	param map[string]string
+}
+
+// RoutePattern 返回请求匹配到的路由请求路径，用于中间件按路由统计、限流
+//   参数
+//     r: Request对象
+//   返回
+//     路由请求路径，如：/user/:id，还没有匹配到路由时返回空
+func RoutePattern(r *http.Request) string {
+	if m, ok := r.Context().Value(routeKey{}).(*routeMatch); ok {
+		return m.info.pattern
+	}
+	return ""
+}
+
+// runController 执行控制器的Action
+//   参数
+//     w:         ResponseWriter对象
+//     r:         Request对象
+//     routeInfo: 路由信息
+//     param:     path参数
+//   返回
+//     void
+func (rt *RouterTab) runController(w http.ResponseWriter, r *http.Request, routeInfo RouterInfo, param map[string]string) {
+	if routeInfo.handler != nil {
+		rt.runRoute(w, r, routeInfo, &handlerRunner{h: routeInfo.handler, param: param})
+		return
+	}
+
+	runRouter := routeInfo.controllerType
 	vc := reflect.New(runRouter)
 	objController, ok := vc.Interface().(ControllerInterface)
 	if !ok {
 		// 500
-		Flogger.Errorf("path[%s] err[controller is not ControllerInterface]", rt.uri(r))
-		rt.accessLog(r, http.StatusInternalServerError)
-		if AppCfg.ServerCfg.Url500 != "" {
-			http.Redirect(w, r, AppCfg.ServerCfg.Url500, http.StatusFound)
-		} else {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		}
+		flogger(r).Errorf("path[%s] err[controller is not ControllerInterface]", rt.uri(r))
+		rt.report(r, &routeInfo, ReportError, http.StatusInternalServerError, nil, "")
+		rt.handleError(w, r, http.StatusInternalServerError, routeInfo.group, nil, "")
+		return
 	}
 
-	runMethod := routeInfo.method
-	objController.Init(w, r, runRouter.Name(), runMethod, param)
-	chanRes := make(chan bool)
-	httpStatus := 0
-	var mu sync.Mutex
+	rt.runRoute(w, r, routeInfo, &controllerRunner{c: objController, vc: vc, method: routeInfo.method, param: param})
+}
 
-	// 超时设置
-	f := func() {
-		mu.Lock()
-		defer mu.Unlock()
-		if httpStatus == 0 {
-			httpStatus = http.StatusBadGateway
-			close(chanRes)
-		}
+// routeRunner 路由的执行过程，控制器和http.Handler都按此过程执行
+type routeRunner interface {
+	init(w http.ResponseWriter, r *http.Request) // 初始化，在请求协程里执行
+	action()                                     // 处理请求，在单独的协程里执行，超时后可能还在运行
+	show()                                       // action正常结束后输出结果
+	unInit()                                     // 反初始化，action结束后执行
+}
+
+// controllerRunner 控制器的执行过程
+// 开启链路追踪时每一步记录一个span
+type controllerRunner struct {
+	c      ControllerInterface
+	vc     reflect.Value
+	method string
+	param  map[string]string
+	ctx    context.Context // 请求的context，带有服务端span
+}
+
+func (cr *controllerRunner) init(w http.ResponseWriter, r *http.Request) {
+	cr.ctx = r.Context()
+	cr.c.Init(w, r, cr.vc.Elem().Type().Name(), cr.method, cr.param)
+}
+
+func (cr *controllerRunner) action() {
+	traceStep(cr.ctx, "Prepare", cr.c.Prepare)
+	pass := false
+	traceStep(cr.ctx, "Filter", func() { pass = cr.c.Filter() })
+	if pass {
+		traceStep(cr.ctx, cr.method, func() { cr.vc.MethodByName(cr.method).Call(nil) })
+	}
+	traceStep(cr.ctx, "Finish", cr.c.Finish)
+}
+
+func (cr *controllerRunner) show() {
+	traceStep(cr.ctx, "Show", cr.c.Show)
+}
+
+func (cr *controllerRunner) unInit() {
+	cr.c.UnInit()
+}
+
+// runRoute 执行路由，超时或客户端断开时取消context，Action发生panic时输出500
+//   参数
+//     w:         ResponseWriter对象
+//     r:         Request对象
+//     routeInfo: 路由信息
+//     runner:    路由的执行过程
+//   返回
+//     void
+func (rt *RouterTab) runRoute(w http.ResponseWriter, r *http.Request, routeInfo RouterInfo, runner routeRunner) {
+	// 超时或客户端断开时取消context，Action和Model可以通过Context()感知
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout := rt.routeTimeout(routeInfo); timeout == NoTimeout {
+		ctx, cancel = context.WithCancel(r.Context())
+	} else {
+		ctx, cancel = context.WithTimeout(r.Context(), timeout)
 	}
-	t := time.AfterFunc(rt.reqTimeout*time.Second, f)
+	defer cancel()
+	r = r.WithContext(ctx)
+	tw := newTimeoutWriter(w, ctx)
+
+	runner.init(tw, r)
+	chanRes := make(chan int, 1)
+	var panicErr interface{}
+	var panicStack string
 
 	// 处理Action
 	go func() {
+		httpStatus := http.StatusOK
 		defer func() {
 			if err := recover(); err != nil {
-				stack := utils.Stack()
-				Flogger.Errorf("path[%s] err[%v] stack[%v]", rt.uri(r), err, stack)
-
-				mu.Lock()
-				defer mu.Unlock()
-				if httpStatus == 0 {
-					t.Stop()
-					httpStatus = http.StatusInternalServerError
-					close(chanRes)
-				}
+				panicErr, panicStack = err, utils.Stack()
+				flogger(r).Errorf("path[%s] err[%v] stack[%v]", rt.uri(r), err, panicStack)
+				httpStatus = http.StatusInternalServerError
 			}
+			chanRes <- httpStatus
 		}()
 
-		objController.Prepare()
-		res := objController.Filter()
-		if res {
-			var in []reflect.Value
-			method := vc.MethodByName(runMethod)
-			method.Call(in)
-		}
-		objController.Finish()
-
-		mu.Lock()
-		defer mu.Unlock()
-		if httpStatus == 0 {
-			httpStatus = http.StatusOK
-			close(chanRes)
-		}
+		runner.action()
 	}()
 
 	select {
-	case <-chanRes:
-		if httpStatus == http.StatusBadGateway {
-			// 502
-			rt.accessLog(r, httpStatus)
-			if AppCfg.ServerCfg.Url502 != "" {
-				http.Redirect(w, r, AppCfg.ServerCfg.Url502, http.StatusFound)
-			} else {
-				http.Error(w, "Bad Gateway", httpStatus)
-			}
-		} else if httpStatus == http.StatusInternalServerError {
+	case httpStatus := <-chanRes:
+		tw.detach()
+		if httpStatus == http.StatusInternalServerError {
 			// 500
-			rt.accessLog(r, httpStatus)
-			if AppCfg.ServerCfg.Url500 != "" {
-				http.Redirect(w, r, AppCfg.ServerCfg.Url500, http.StatusFound)
-			} else {
-				http.Error(w, "Internal Server Error", httpStatus)
+			rt.report(r, &routeInfo, ReportPanic, httpStatus, panicErr, panicStack)
+			rt.handleError(tw, r, httpStatus, routeInfo.group, panicErr, panicStack)
+		} else {
+			runner.show()
+			if status := tw.statusCode(); status >= 500 {
+				// Action自己输出的5xx
+				rt.report(r, &routeInfo, ReportError, status, nil, "")
+			}
+		}
+		runner.unInit()
+	case <-ctx.Done():
+		// 超时后丢弃Action的输出，Action已经有输出时不再输出超时应答
+		// 客户端断开连接时不再输出，访问日志记为499
+		canWrite := tw.timeout()
+		if ctx.Err() == context.DeadlineExceeded {
+			// 502
+			flogger(r).Errorf("path[%s] err[request timeout]", rt.uri(r))
+			rt.report(r, &routeInfo, ReportTimeout, http.StatusBadGateway, nil, "")
+			if canWrite {
+				rt.handleError(w, r, http.StatusBadGateway, routeInfo.group, nil, "")
 			}
 		}
-	}
-	if httpStatus == http.StatusOK {
-		objController.Show()
-	}
 
-	objController.UnInit()
-	return
+		// Action结束后再反初始化，避免与Action并发访问控制器
+		go func() {
+			<-chanRes
+			runner.unInit()
+		}()
+	}
 }
 
-// regularMatch 正则路由匹配
+// insert 添加路由路径到路由树
 //   参数
-//     urlPath: 访问路径
+//     pattern: 路由请求路径
 //   返回
-//     匹配成功返回路由信息，否则返回匹配失败
-func (rt *RouterTab) regularMatch(urlPath string) (RouterInfo, bool) {
-	var (
-		pattern    string
-		routerInfo RouterInfo
-	)
-	for pattern, routerInfo = range rt.regularRouters {
-		m, _ := regexp.MatchString(pattern, urlPath)
-		if m {
-			return routerInfo, true
-		}
+//     路由路径对应的叶子
+func (rt *RouterTab) insert(pattern string) *treeLeaf {
+	segs, err := parseSegs(pattern)
+	if err != nil {
+		panic(err.Error())
 	}
-	return routerInfo, false
+
+	leaf, err := rt.tree.insert(segs)
+	if err != nil {
+		rt.conflict("route [%s] %s", pattern, err.Error())
+	}
+	return leaf
 }
 
-// staticRouter 静态路由
+// findRouter 查找路由，匹配顺序：固定路由 => 自动路由 => 带参数的路由 => 正则路由
+// 全路径未匹配到时，从长到短查找是请求路径前缀的固定路由和自动路由，剩余的部分做为参数
+// 前缀至少要有两段，比如/user/index/id/10，匹配/user/index，参数为id=10
+// 同一路径有多个路由时，按域名限定和请求方法选择，见matchMethod
 //   参数
-//     w:       ResponseWriter对象
-//     w:       Request对象
-//     urlPath: 请求路径
+//     method:   请求方法
+//     host:     请求域名，已去掉端口并转成小写
+//     realPath: 请求路径
 //   返回
-//     匹配成功返回true，否则返回false
-func (rt *RouterTab) staticRouter(w http.ResponseWriter, r *http.Request, urlPath string) bool {
-	if r.Method != "GET" && r.Method != "HEAD" {
-		return false
-	}
-	filePath, isMatch := rt.checkStaticFile(urlPath)
-	if !isMatch {
-		return false
+//     路由信息、path参数、路径匹配但请求方法不匹配时允许的请求方法、是否匹配成功
+func (rt *RouterTab) findRouter(method, host, realPath string) (RouterInfo, map[string]string, map[string]bool, bool) {
+	allows := make(map[string]bool)
+	urlPath := lowerASCII(realPath)
+
+	// 固定路由、自动路由、带参数的路由
+	var routeInfo RouterInfo
+	var param map[string]string
+	ok := rt.tree.find(realPath, urlPath, nil, func(leaf *treeLeaf, ps []treeParam) bool {
+		var found bool
+		if routeInfo, found = matchMethod(leaf.fixed, method, host, allows); !found {
+			routeInfo, found = matchMethod(leaf.auto, method, host, allows)
+		}
+		if found && len(ps) > 0 {
+			param = make(map[string]string, len(ps))
+			for _, p := range ps {
+				param[p.name] = p.value
+			}
+		}
+		return found
+	})
+	if ok {
+		return routeInfo, hostParam(routeInfo, host, param), nil, true
 	}
 
-	if filePath == "" {
-		return false
+	// 正则路由，正则路由是否区分大小要看正则表达如果写
+	if routeInfo, param, ok := rt.regularMatch(realPath, method, host, allows); ok {
+		return routeInfo, hostParam(routeInfo, host, param), nil, true
 	}
 
-	http.ServeFile(w, r, filePath)
+	// 全路径未匹配到
+	leaves, ends := rt.tree.prefixLeaves(urlPath)
+	for i := len(leaves) - 1; i >= 0; i-- {
+		cnt := rt.getPathCnt(urlPath[:ends[i]])
+		if cnt < 2 {
+			break
+		}
 
-	return true
+		// 固定路由
+		if routeInfo, ok := matchMethod(leaves[i].fixed, method, host, allows); ok {
+			return routeInfo, hostParam(routeInfo, host, rt.getParam(realPath, cnt)), nil, true
+		}
+
+		// 自动路由
+		if routeInfo, ok := matchMethod(leaves[i].auto, method, host, allows); ok {
+			return routeInfo, hostParam(routeInfo, host, rt.getParam(realPath, cnt)), nil, true
+		}
+	}
+
+	return RouterInfo{}, nil, allows, false
 }
 
-// checkStaticFile 验证静态文件
+// regularMatch 正则路由匹配
 //   参数
-//     urlPath: 请求路径
+//     urlPath: 访问路径
+//     method:  请求方法
+//     host:    请求域名
+//     allows:  路径匹配但请求方法不匹配时，收集允许的请求方法
 //   返回
-//     文件路径、是否匹配路由
-func (rt *RouterTab) checkStaticFile(urlPath string) (string, bool) {
-	var file string
-
-	requestPath := filepath.ToSlash(filepath.Clean(urlPath))
-
-	// favicon.ico、robots.txt文件单独处理
-	if requestPath == "/favicon.ico" || requestPath == "/robots.txt" {
-		file = path.Join(AppRoot, requestPath)
-		r, err := utils.IsFile(file)
-		if !r || err != nil {
-			return file, false
+//     匹配成功返回路由信息和命名分组参数，否则返回匹配失败
+func (rt *RouterTab) regularMatch(urlPath, method, host string, allows map[string]bool) (RouterInfo, map[string]string, bool) {
+	for _, rr := range rt.regularRouters {
+		matches := rr.re.FindStringSubmatch(urlPath)
+		if matches == nil {
+			continue
 		}
-		return file, true
-	}
-
-	// 静态文件
-	for _, staticDir := range AppCfg.WebCfg.StaticDir {
-		staticDir = strings.TrimRight(staticDir, "/")
-		if requestPath != staticDir && !strings.HasPrefix(requestPath, staticDir+"/") {
+		routerInfo, ok := matchMethod(rr.infos, method, host, allows)
+		if !ok {
 			continue
 		}
 
-		file := path.Join(AppRoot, requestPath)
-		// 如果是文件夹，拼上index.html
-		isDir, err := utils.IsDir(file)
-		if err != nil {
-			return "", false
-		} else if isDir {
-			file = filepath.Join(file, "index.html")
+		var param map[string]string
+		for i, name := range rr.re.SubexpNames() {
+			if i == 0 || name == "" {
+				continue
+			}
+			if param == nil {
+				param = make(map[string]string)
+			}
+			param[name] = matches[i]
 		}
-		return file, true
+		return routerInfo, param, true
 	}
-
-	return "", false
+	return RouterInfo{}, nil, false
 }
 
 // getUrlAndParam 根据path获取用于匹配的pattern和参数
@@ -525,27 +1087,6 @@ func (rt *RouterTab) getPatternAndParam(path string) (string, map[string]string)
 	return pattern, param
 }
 
-// getPattern 根据path获取用于匹配的pattern
-// 比如url=/user/index/ver/3.0/id/10, cnt=2，则参数为ver=3.0 id=10
-//   参数：
-//     path: 原始的url
-//     cnt:  前面cnt段为路径
-//   返回：
-//     参数信息
-func (rt *RouterTab) getPattern(path string, cnt int) string {
-	if path == "" {
-		return ""
-	}
-
-	urlList := strings.Split(strings.TrimLeft(path, "/"), "/")
-	n := len(urlList)
-	if n < cnt {
-		return ""
-	}
-
-	return "/" + strings.Join(urlList[:cnt], "/")
-}
-
 // getParam 根据path获取参数
 // 比如url=/user/index/ver/3.0/id/10, cnt=2，则pattern为/user/index
 //   参数：
@@ -570,27 +1111,6 @@ func (rt *RouterTab) getParam(path string, cnt int) map[string]string {
 	return param
 }
 
-// accessLog 写访问日志
-//   参数
-//     r:          http.Request
-//     httpStatus: http状态码
-//   返回
-//     void
-func (rt *RouterTab) accessLog(r *http.Request, httpStatus int) {
-	userAgent := r.Header.Get("User-Agent")
-	proxy1 := r.Header.Get("X-Forwarded-For")
-	proxy2 := ""
-	if AppCfg.ServerCfg.ForwardName != "" {
-		proxy2 = r.Header.Get(AppCfg.ServerCfg.ForwardName)
-	}
-
-	if httpStatus >= 400 {
-		Flogger.Errorf("%s|%s|%s|%d|%s|%s|%s|%s", r.RemoteAddr, r.Method, rt.uri(r), httpStatus, userAgent, r.Host, proxy1, proxy2)
-	} else {
-		Flogger.Infof("%s|%s|%s|%d|%s|%s|%s|%s", r.RemoteAddr, r.Method, rt.uri(r), httpStatus, userAgent, r.Host, proxy1, proxy2)
-	}
-}
-
 // AddShell 添加Shell脚本路由
 //   参数
 //     pattern: 路由请求路径
@@ -603,6 +1123,9 @@ func (rt *RouterTab) AddShell(pattern string, handler ShellFunc) {
 	}
 
 	pattern = strings.ToLower(pattern)
+	if _, ok := rt.shellRouters[pattern]; ok {
+		rt.conflict("shell route [%s] is added repeatedly", pattern)
+	}
 	rt.shellRouters[pattern] = handler
 }
 
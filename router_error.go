@@ -0,0 +1,409 @@
+// 错误处理
+// 404、405、500、502等错误可以交给控制器方法或模板处理，按状态码和路由分组设置，直接输出不跳转
+// 查找顺序：分组 => 上级分组 => 全局 => 模板errors/状态码.html => url_xxx跳转 => 默认输出
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lixy529/gotools/utils"
+)
+
+// HttpError 错误信息，传给错误处理的控制器和模板
+type HttpError struct {
+	Status  int    `json:"code"`            // http状态码
+	Message string `json:"msg"`             // 状态描述，如：Not Found
+	Panic   string `json:"panic,omitempty"` // panic的值，只在dev模式下设置
+	Stack   string `json:"stack,omitempty"` // panic的堆栈，只在dev模式下设置
+}
+
+// errorHandler 错误处理，控制器方法和模板二选一
+type errorHandler struct {
+	controllerType reflect.Type
+	method         string
+	tplName        string
+}
+
+// httpErrorKey 错误信息在请求context里的key
+type httpErrorKey struct{}
+
+// SetErrorHandler 设置错误处理的控制器方法
+// 控制器里可以用GetError()获取错误信息，输出时默认使用错误的状态码
+//   参数
+//     status: http状态码，如：404，为0时对所有状态码生效
+//     c:      控制器对象地址
+//     method: 控制器方法名
+//   返回
+//     void
+func (rt *RouterTab) SetErrorHandler(status int, c ControllerInterface, method string) {
+	rt.errorHandlers = setErrorHandler(rt.errorHandlers, status, newErrorHandler(c, method))
+}
+
+// SetErrorTemplate 设置错误处理的模板，模板数据为HttpError
+// 请求的Accept优先JSON时不使用模板，输出JSON
+//   参数
+//     status:  http状态码，如：404，为0时对所有状态码生效
+//     tplName: 模板名，如：errors/404.html
+//   返回
+//     void
+func (rt *RouterTab) SetErrorTemplate(status int, tplName string) {
+	rt.errorHandlers = setErrorHandler(rt.errorHandlers, status, &errorHandler{tplName: tplName})
+}
+
+// SetErrorHandler 设置分组的错误处理的控制器方法，对分组和下级分组生效，参数同RouterTab.SetErrorHandler
+//   参数
+//     status: http状态码，为0时对所有状态码生效
+//     c:      控制器对象地址
+//     method: 控制器方法名
+//   返回
+//     void
+func (g *RouterGroup) SetErrorHandler(status int, c ControllerInterface, method string) {
+	g.errorHandlers = setErrorHandler(g.errorHandlers, status, newErrorHandler(c, method))
+}
+
+// SetErrorTemplate 设置分组的错误处理的模板，对分组和下级分组生效，参数同RouterTab.SetErrorTemplate
+//   参数
+//     status:  http状态码，为0时对所有状态码生效
+//     tplName: 模板名
+//   返回
+//     void
+func (g *RouterGroup) SetErrorTemplate(status int, tplName string) {
+	g.errorHandlers = setErrorHandler(g.errorHandlers, status, &errorHandler{tplName: tplName})
+}
+
+// newErrorHandler 实例化控制器方法的错误处理
+//   参数
+//     c:      控制器对象地址
+//     method: 控制器方法名
+//   返回
+//     错误处理
+func newErrorHandler(c ControllerInterface, method string) *errorHandler {
+	reflectVal := reflect.ValueOf(c)
+	if !reflectVal.MethodByName(method).IsValid() {
+		panic(fmt.Sprintf("router: error handler [%s] method [%s] is not exist", reflectVal.Type().String(), method))
+	}
+	return &errorHandler{
+		controllerType: reflect.Indirect(reflectVal).Type(),
+		method:         method,
+	}
+}
+
+// setErrorHandler 添加错误处理到列表
+//   参数
+//     handlers: 已有的错误处理列表
+//     status:   http状态码
+//     h:        错误处理
+//   返回
+//     新的错误处理列表
+func setErrorHandler(handlers map[int]*errorHandler, status int, h *errorHandler) map[int]*errorHandler {
+	if handlers == nil {
+		handlers = make(map[int]*errorHandler)
+	}
+	handlers[status] = h
+	return handlers
+}
+
+// findErrorHandler 查找错误处理，先找分组及上级分组，再找全局，同一级里状态码精确匹配优先
+//   参数
+//     status: http状态码
+//     g:      路由分组，可以为nil
+//   返回
+//     错误处理，没有时返回nil
+func (rt *RouterTab) findErrorHandler(status int, g *RouterGroup) *errorHandler {
+	for ; g != nil; g = g.parent {
+		if h := matchErrorHandler(g.errorHandlers, status); h != nil {
+			return h
+		}
+	}
+	return matchErrorHandler(rt.errorHandlers, status)
+}
+
+// matchErrorHandler 按状态码查找错误处理
+//   参数
+//     handlers: 错误处理列表
+//     status:   http状态码
+//   返回
+//     错误处理，没有时返回nil
+func matchErrorHandler(handlers map[int]*errorHandler, status int) *errorHandler {
+	if h, ok := handlers[status]; ok {
+		return h
+	}
+	return handlers[0]
+}
+
+// requestErrorTarget 返回处理请求的路由表和分组，用于中间件输出错误时按所属的路由表和分组处理
+// 已匹配到路由时取路由所在的分组，否则同404按请求路径查找分组
+//   参数
+//     r: Request对象
+//   返回
+//     路由表、路由分组，不是路由表处理的请求返回Router和nil
+func requestErrorTarget(r *http.Request) (*RouterTab, *RouterGroup) {
+	aw, ok := r.Context().Value(accessKey{}).(*accessWriter)
+	if !ok || aw.rt == nil {
+		return Router, nil
+	}
+	if aw.route != nil {
+		return aw.rt, aw.route.group
+	}
+	return aw.rt, aw.rt.matchGroup(requestHost(r), cleanPath(r.URL.Path), (*RouterGroup).hasErrorHandler)
+}
+
+// matchGroup 查找请求路径所在的分组，用于没有匹配到路由时的错误处理、跨域处理
+// 取前缀最长的、满足条件的分组
+//   参数
+//     host:     请求域名
+//     realPath: 请求路径
+//     has:      分组需要满足的条件，如：(*RouterGroup).hasErrorHandler
+//   返回
+//     路由分组，没有时返回nil
+func (rt *RouterTab) matchGroup(host, realPath string, has func(*RouterGroup) bool) *RouterGroup {
+	urlPath := strings.ToLower(realPath)
+	var best *RouterGroup
+	for _, g := range rt.groups {
+		if g.host.rank(host) < 0 || !has(g) {
+			continue
+		}
+		prefix := strings.ToLower(g.prefix)
+		if urlPath != prefix && !strings.HasPrefix(urlPath, prefix+"/") {
+			continue
+		}
+		if best == nil || len(g.prefix) > len(best.prefix) || (len(g.prefix) == len(best.prefix) && g.host != nil) {
+			best = g
+		}
+	}
+	return best
+}
+
+// hasErrorHandler 分组或上级分组是否设置了错误处理
+//   参数
+//     void
+//   返回
+//     有返回true，否则返回false
+func (g *RouterGroup) hasErrorHandler() bool {
+	for ; g != nil; g = g.parent {
+		if len(g.errorHandlers) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// handleError 输出错误
+//   参数
+//     w:      ResponseWriter对象
+//     r:      Request对象
+//     status: http状态码
+//     g:      路由分组，可以为nil
+//     perr:   panic的值，不是panic时为nil
+//     stack:  panic的堆栈
+//   返回
+//     void
+func (rt *RouterTab) handleError(w http.ResponseWriter, r *http.Request, status int, g *RouterGroup, perr interface{}, stack string) {
+	herr := &HttpError{
+		Status:  status,
+		Message: http.StatusText(status),
+	}
+	if perr != nil && AppCfg.RunMode == DEV {
+		herr.Panic = fmt.Sprint(perr)
+		herr.Stack = stack
+	}
+
+	h := rt.findErrorHandler(status, g)
+	if h == nil {
+		// 默认的错误模板
+		tplName := "errors/" + strconv.Itoa(status) + AppCfg.WebCfg.ViewsExt
+		if lookupTemplate(tplName) {
+			h = &errorHandler{tplName: tplName}
+		}
+	}
+
+	if h != nil {
+		if h.controllerType != nil {
+			if rt.runErrorController(w, r, h, herr) {
+				return
+			}
+		} else if !acceptJson(r) && rt.renderErrorTemplate(w, r, h.tplName, herr) {
+			return
+		}
+	} else if url := errorUrl(status); url != "" && !acceptJson(r) {
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	}
+
+	writeError(w, r, herr)
+}
+
+// runErrorController 执行错误处理的控制器方法，不设置超时
+//   参数
+//     w:    ResponseWriter对象
+//     r:    Request对象
+//     h:    错误处理
+//     herr: 错误信息
+//   返回
+//     成功返回true，控制器方法panic时返回false
+func (rt *RouterTab) runErrorController(w http.ResponseWriter, r *http.Request, h *errorHandler, herr *HttpError) (ok bool) {
+	vc := reflect.New(h.controllerType)
+	objController, isController := vc.Interface().(ControllerInterface)
+	if !isController {
+		flogger(r).Errorf("path[%s] err[error handler is not ControllerInterface]", rt.uri(r))
+		return false
+	}
+
+	ew := &errorWriter{ResponseWriter: w, status: herr.Status}
+	defer func() {
+		if err := recover(); err != nil {
+			flogger(r).Errorf("path[%s] err[error handler: %v] stack[%v]", rt.uri(r), err, utils.Stack())
+			ok = ew.wroteHeader
+		}
+	}()
+
+	r = r.WithContext(context.WithValue(r.Context(), httpErrorKey{}, herr))
+	objController.Init(ew, r, h.controllerType.Name(), h.method, nil)
+	objController.Prepare()
+	if objController.Filter() {
+		vc.MethodByName(h.method).Call(nil)
+	}
+	objController.Finish()
+	objController.Show()
+	objController.UnInit()
+
+	if !ew.wroteHeader {
+		ew.WriteHeader(herr.Status)
+	}
+	return true
+}
+
+// renderErrorTemplate 用模板输出错误
+//   参数
+//     w:       ResponseWriter对象
+//     r:       Request对象
+//     tplName: 模板名
+//     herr:    错误信息
+//   返回
+//     成功返回true，失败返回false
+func (rt *RouterTab) renderErrorTemplate(w http.ResponseWriter, r *http.Request, tplName string, herr *HttpError) bool {
+	if !lookupTemplate(tplName) {
+		flogger(r).Errorf("path[%s] err[error template %s is not exist]", rt.uri(r), tplName)
+		return false
+	}
+
+	buf := &bytes.Buffer{}
+	if err := GTemplate.ViewTemp.ExecuteTemplate(buf, tplName, herr); err != nil {
+		flogger(r).Errorf("path[%s] err[error template %s: %s]", rt.uri(r), tplName, err.Error())
+		return false
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(herr.Status)
+	w.Write(buf.Bytes())
+	return true
+}
+
+// writeError 默认的错误输出，Accept优先JSON时输出JSON，否则输出文本
+//   参数
+//     w:    ResponseWriter对象
+//     r:    Request对象
+//     herr: 错误信息
+//   返回
+//     void
+func writeError(w http.ResponseWriter, r *http.Request, herr *HttpError) {
+	if acceptJson(r) {
+		b, _ := json.Marshal(herr)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(herr.Status)
+		w.Write(b)
+		return
+	}
+
+	msg := herr.Message
+	if herr.Panic != "" {
+		msg += "\n\npanic: " + herr.Panic + "\n\n" + herr.Stack
+	}
+	http.Error(w, msg, herr.Status)
+}
+
+// errorWriter 错误处理控制器使用的ResponseWriter，没有指定状态码时使用错误的状态码
+type errorWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader 输出状态码，200替换成错误的状态码
+//   参数
+//     code: 状态码
+//   返回
+//     void
+func (ew *errorWriter) WriteHeader(code int) {
+	if ew.wroteHeader {
+		return
+	}
+	if code == http.StatusOK {
+		code = ew.status
+	}
+	ew.wroteHeader = true
+	ew.ResponseWriter.WriteHeader(code)
+}
+
+// Write 输出数据
+//   参数
+//     b: 数据
+//   返回
+//     输出的字节数、错误信息
+func (ew *errorWriter) Write(b []byte) (int, error) {
+	if !ew.wroteHeader {
+		ew.WriteHeader(ew.status)
+	}
+	return ew.ResponseWriter.Write(b)
+}
+
+// acceptJson 判断请求是否优先接受JSON
+//   参数
+//     r: Request对象
+//   返回
+//     Accept里json在html之前或者没有html时返回true
+func acceptJson(r *http.Request) bool {
+	accept := strings.ToLower(r.Header.Get("Accept"))
+	j := strings.Index(accept, "json")
+	if j < 0 {
+		return false
+	}
+	h := strings.Index(accept, "html")
+	return h < 0 || j < h
+}
+
+// lookupTemplate 判断模板是否存在
+//   参数
+//     tplName: 模板名
+//   返回
+//     存在返回true，否则返回false
+func lookupTemplate(tplName string) bool {
+	return GTemplate != nil && GTemplate.ViewTemp != nil && GTemplate.ViewTemp.Lookup(tplName) != nil
+}
+
+// errorUrl 返回配置的错误跳转地址，兼容url_404、url_500、url_502配置
+//   参数
+//     status: http状态码
+//   返回
+//     跳转地址，没有配置时返回空
+func errorUrl(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return AppCfg.ServerCfg.Url404
+	case http.StatusInternalServerError:
+		return AppCfg.ServerCfg.Url500
+	case http.StatusBadGateway:
+		return AppCfg.ServerCfg.Url502
+	}
+	return ""
+}
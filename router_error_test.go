@@ -0,0 +1,103 @@
+package bingo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// errController 测试错误处理的控制器
+type errController struct {
+	Controller
+}
+
+func (c *errController) NotFoundAction() {
+	c.WriteString("nf:" + c.GetError().Message)
+}
+
+func (c *errController) ApiAction() {
+	herr := c.GetError()
+	c.WriteString(herr.Message + "|" + herr.Panic)
+}
+
+func (c *errController) PanicAction() {
+	panic("boom")
+}
+
+// TestErrorHandler 测试错误处理
+func TestErrorHandler(t *testing.T) {
+	runMode := AppCfg.RunMode
+	tpl := GTemplate
+	defer func() {
+		AppCfg.RunMode = runMode
+		GTemplate = tpl
+	}()
+	AppCfg.RunMode = DEV
+
+	dir, err := ioutil.TempDir("", "views")
+	if err != nil {
+		t.Fatalf("TempDir failed, err: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "errors"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "errors", "502.html"), []byte(`<h1>{{.Status}} {{.Message}}</h1>`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "errors", "oops.html"), []byte(`<h1>oops {{.Panic}}</h1>`), 0644)
+	GTemplate = NewTemplate(dir, ".html")
+	if err = GTemplate.buildViews(); err != nil {
+		t.Fatalf("buildViews failed, err: %s", err.Error())
+	}
+
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.SetErrorHandler(http.StatusNotFound, &errController{}, "NotFoundAction")
+	rt.SetErrorTemplate(http.StatusInternalServerError, "errors/oops.html")
+	rt.AddFixed("/panic", &errController{}, "PanicAction")
+	rt.Get("/get", &testController{}, "IndexAction")
+	api := rt.Group("/api")
+	api.SetErrorHandler(0, &errController{}, "ApiAction")
+	api.AddFixed("/panic", &errController{}, "PanicAction")
+
+	cases := []struct {
+		method string
+		url    string
+		accept string
+		status int
+		body   string
+	}{
+		{"GET", "/none", "", 404, "nf:Not Found"},
+		{"GET", "/panic", "text/html", 500, "<h1>oops boom</h1>"},
+		{"GET", "/panic", "application/json", 500, `{"code":500,"msg":"Internal Server Error","panic":"boom","stack":`},
+		{"POST", "/get", "application/json, text/html", 405, `{"code":405,"msg":"Method Not Allowed"}`},
+		{"POST", "/get", "", 405, "Method Not Allowed\n"},
+		{"GET", "/api/none", "", 404, "Not Found|"},
+		{"GET", "/API/panic", "", 500, "Internal Server Error|boom"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(c.method, c.url, nil)
+		r.Header.Set("Accept", c.accept)
+		rt.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s %s status failed. Got %d, expected %d.", c.method, c.url, w.Code, c.status)
+		} else if !strings.HasPrefix(w.Body.String(), c.body) {
+			t.Errorf("%s %s body failed. Got %s, expected %s.", c.method, c.url, w.Body.String(), c.body)
+		}
+	}
+
+	// 默认的错误模板，生产模式不输出panic信息
+	AppCfg.RunMode = PROD
+	w := httptest.NewRecorder()
+	rt.handleError(w, httptest.NewRequest("GET", "/", nil), http.StatusBadGateway, nil, "boom", "stack")
+	if w.Code != http.StatusBadGateway || w.Body.String() != "<h1>502 Bad Gateway</h1>" {
+		t.Errorf("default template failed. Got %d %s.", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	rt.handleError(w, httptest.NewRequest("GET", "/", nil), http.StatusInternalServerError, nil, "boom", "stack")
+	if strings.Contains(w.Body.String(), "boom") {
+		t.Errorf("prod panic info failed. Got %s.", w.Body.String())
+	}
+}
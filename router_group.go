@@ -0,0 +1,334 @@
+// 路由分组
+// 分组内的路由共享路径前缀、域名限定和中间件，分组可以嵌套
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RouterGroup 路由分组
+type RouterGroup struct {
+	rt          *RouterTab
+	parent      *RouterGroup     // 上级分组，顶级分组为nil
+	prefix      string           // 路径前缀，包含上级分组的前缀
+	host        *hostRule        // 域名限定，没有设置时继承上级分组
+	timeout     time.Duration    // 请求超时时间，为0时继承上级分组
+	middlewares []MiddlewareFunc // 分组的中间件
+	allMws      []MiddlewareFunc // 分组及所有上级分组的中间件，上级分组的在前，添加中间件时更新
+	limiter     *limiter         // 分组的准入控制，不限制时为nil
+	cors        *cors            // 分组的跨域配置，为nil时继承上级分组
+
+	errorHandlers map[int]*errorHandler // 分组的错误处理，key为http状态码
+}
+
+// Group 添加一个路由分组
+//   参数
+//     prefix: 路径前缀，如：/v2.1
+//     mws:    分组的中间件
+//   返回
+//     路由分组
+func (rt *RouterTab) Group(prefix string, mws ...MiddlewareFunc) *RouterGroup {
+	return newRouterGroup(rt, nil, prefix, mws)
+}
+
+// Host 添加一个限定域名的路由分组，不带路径前缀
+// 多个站点可以共用一个App和一个监听端口，如：
+//   site := Router.Host("api.example.com")
+//   site.AddAuto(&api.UserController{})
+//   Router.Host(":tenant.example.com").AddFixed("/", &controllers.TenantController{}, "IndexAction")
+//   参数
+//     host: 域名规则，如：api.example.com、:tenant.example.com、*.example.com，通配子域名用:name时做为path参数
+//     mws:  分组的中间件
+//   返回
+//     路由分组
+func (rt *RouterTab) Host(host string, mws ...MiddlewareFunc) *RouterGroup {
+	g := newRouterGroup(rt, nil, "", mws)
+	g.setHost(host)
+	return g
+}
+
+// Group 添加一个嵌套的路由分组，继承上级分组的前缀、域名限定和中间件
+//   参数
+//     prefix: 路径前缀，拼接在上级分组的前缀后面
+//     mws:    分组的中间件
+//   返回
+//     路由分组
+func (g *RouterGroup) Group(prefix string, mws ...MiddlewareFunc) *RouterGroup {
+	return newRouterGroup(g.rt, g, prefix, mws)
+}
+
+// Host 添加一个嵌套的限定域名的路由分组，继承上级分组的前缀和中间件，域名限定以此为准
+//   参数
+//     host: 域名规则，同RouterTab.Host
+//     mws:  分组的中间件
+//   返回
+//     路由分组
+func (g *RouterGroup) Host(host string, mws ...MiddlewareFunc) *RouterGroup {
+	sub := newRouterGroup(g.rt, g, "", mws)
+	sub.setHost(host)
+	return sub
+}
+
+// newRouterGroup 实例化路由分组
+//   参数
+//     rt:     路由表
+//     parent: 上级分组
+//     prefix: 路径前缀
+//     mws:    分组的中间件
+//   返回
+//     路由分组
+func newRouterGroup(rt *RouterTab, parent *RouterGroup, prefix string, mws []MiddlewareFunc) *RouterGroup {
+	g := &RouterGroup{
+		rt:          rt,
+		parent:      parent,
+		middlewares: mws,
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if parent != nil {
+		g.prefix = parent.prefix
+		g.host = parent.host
+	}
+	if prefix != "" {
+		g.prefix += "/" + prefix
+	}
+	g.buildMiddlewares()
+	rt.groups = append(rt.groups, g)
+
+	return g
+}
+
+// Use 添加分组的中间件，对分组和下级分组里的路由生效，需要在Run之前调用
+//   参数
+//     mws: 中间件
+//   返回
+//     void
+func (g *RouterGroup) Use(mws ...MiddlewareFunc) {
+	g.middlewares = append(g.middlewares, mws...)
+
+	// 更新分组和下级分组的中间件，上级分组先于下级分组创建，按创建顺序更新
+	for _, sub := range g.rt.groups {
+		for p := sub; p != nil; p = p.parent {
+			if p == g {
+				sub.buildMiddlewares()
+				break
+			}
+		}
+	}
+}
+
+// setHost 设置分组的域名限定，格式错误时panic
+//   参数
+//     host: 域名规则
+//   返回
+//     void
+func (g *RouterGroup) setHost(host string) {
+	rule, err := parseHost(host)
+	if err != nil {
+		panic(err.Error())
+	}
+	g.host = rule
+}
+
+// SetTimeout 设置分组的请求超时时间，对分组和下级分组里没有单独设置的路由生效
+//   参数
+//     timeout: 请求超时时间，如：30 * time.Second，NoTimeout为不限制
+//   返回
+//     void
+func (g *RouterGroup) SetTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = NoTimeout
+	}
+	g.timeout = timeout
+}
+
+// getTimeout 返回分组的请求超时时间，没有设置时取上级分组的
+//   参数
+//     void
+//   返回
+//     请求超时时间，都没有设置时返回0
+func (g *RouterGroup) getTimeout() time.Duration {
+	for ; g != nil; g = g.parent {
+		if g.timeout != 0 {
+			return g.timeout
+		}
+	}
+	return 0
+}
+
+// Prefix 返回分组的路径前缀
+//   参数
+//     void
+//   返回
+//     路径前缀
+func (g *RouterGroup) Prefix() string {
+	return g.prefix
+}
+
+// AddFixed 在分组里添加固定路由，参数同RouterTab.AddFixed
+//   参数
+//     pattern: 路由请求路径，拼接在分组前缀后面
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同RouterTab.AddFixed
+//   返回
+//     void
+func (g *RouterGroup) AddFixed(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	g.rt.AddFixed(pattern, c, method, append(args, g.option())...)
+}
+
+// AddRegular 在分组里添加正则路由，正则从分组前缀之后开始匹配，如：^/user/(?P<id>\d+)$
+//   参数
+//     pattern: 路由请求路径正则表达式
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同RouterTab.AddRegular
+//   返回
+//     void
+func (g *RouterGroup) AddRegular(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	g.rt.AddRegular(pattern, c, method, append(args, g.option())...)
+}
+
+// AddAuto 在分组里添加自动路由，参数同RouterTab.AddAuto
+//   参数
+//     c:    控制器对象地址
+//     args: 其它信息，同RouterTab.AddAuto
+//   返回
+//     void
+func (g *RouterGroup) AddAuto(c ControllerInterface, args ...interface{}) {
+	g.rt.AddAuto(c, append(args, g.option())...)
+}
+
+// Get 在分组里添加只响应GET(HEAD)请求的固定路由
+//   参数
+//     pattern: 路由请求路径
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同RouterTab.AddFixed
+//   返回
+//     void
+func (g *RouterGroup) Get(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	g.AddFixed(pattern, c, "GET:"+method, args...)
+}
+
+// Post 在分组里添加只响应POST请求的固定路由
+//   参数
+//     pattern: 路由请求路径
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同RouterTab.AddFixed
+//   返回
+//     void
+func (g *RouterGroup) Post(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	g.AddFixed(pattern, c, "POST:"+method, args...)
+}
+
+// Put 在分组里添加只响应PUT请求的固定路由
+//   参数
+//     pattern: 路由请求路径
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同RouterTab.AddFixed
+//   返回
+//     void
+func (g *RouterGroup) Put(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	g.AddFixed(pattern, c, "PUT:"+method, args...)
+}
+
+// Delete 在分组里添加只响应DELETE请求的固定路由
+//   参数
+//     pattern: 路由请求路径
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同RouterTab.AddFixed
+//   返回
+//     void
+func (g *RouterGroup) Delete(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	g.AddFixed(pattern, c, "DELETE:"+method, args...)
+}
+
+// Patch 在分组里添加只响应PATCH请求的固定路由
+//   参数
+//     pattern: 路由请求路径
+//     c:       控制器对象地址
+//     method:  控制器方法名
+//     args:    其它信息，同RouterTab.AddFixed
+//   返回
+//     void
+func (g *RouterGroup) Patch(pattern string, c ControllerInterface, method string, args ...interface{}) {
+	g.AddFixed(pattern, c, "PATCH:"+method, args...)
+}
+
+// option 返回把路由归到此分组的路由选项，路由没有用WithHost时使用分组的域名限定
+//   参数
+//     void
+//   返回
+//     路由选项
+func (g *RouterGroup) option() RouteOption {
+	return func(ri *RouterInfo) {
+		ri.group = g
+		if ri.host == nil {
+			ri.host = g.host
+		}
+	}
+}
+
+// allMiddlewares 返回分组及所有上级分组的中间件，上级分组的在前，返回的列表不能修改
+//   参数
+//     void
+//   返回
+//     中间件列表
+func (g *RouterGroup) allMiddlewares() []MiddlewareFunc {
+	if g == nil {
+		return nil
+	}
+	return g.allMws
+}
+
+// buildMiddlewares 合并上级分组和分组的中间件，创建分组和添加中间件时调用
+//   参数
+//     void
+//   返回
+//     void
+func (g *RouterGroup) buildMiddlewares() {
+	parent := g.parent.allMiddlewares()
+	mws := make([]MiddlewareFunc, 0, len(parent)+len(g.middlewares))
+	g.allMws = append(append(mws, parent...), g.middlewares...)
+}
+
+// Handle 在分组里添加http.Handler路由，参数同RouterTab.Handle
+//   参数
+//     pattern: 路由请求路径，拼接在分组前缀后面
+//     h:       处理函数
+//     args:    其它信息，同RouterTab.AddFixed
+//   返回
+//     void
+func (g *RouterGroup) Handle(pattern string, h http.Handler, args ...interface{}) {
+	g.rt.Handle(pattern, h, append(args, g.option())...)
+}
+
+// HandleFunc 在分组里添加http.HandlerFunc路由，参数同RouterTab.HandleFunc
+//   参数
+//     pattern: 路由请求路径，拼接在分组前缀后面
+//     f:       处理函数
+//     args:    其它信息，同RouterTab.AddFixed
+//   返回
+//     void
+func (g *RouterGroup) HandleFunc(pattern string, f func(http.ResponseWriter, *http.Request), args ...interface{}) {
+	g.rt.HandleFunc(pattern, f, append(args, g.option())...)
+}
+
+// Mount 在分组里挂载http.Handler，参数同RouterTab.Mount
+//   参数
+//     prefix: 路径前缀，拼接在分组前缀后面
+//     h:      处理函数
+//     args:   其它信息，同RouterTab.AddFixed
+//   返回
+//     void
+func (g *RouterGroup) Mount(prefix string, h http.Handler, args ...interface{}) {
+	g.rt.Mount(prefix, h, append(args, g.option())...)
+}
@@ -0,0 +1,87 @@
+// 路由分组测试
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestRouterGroup 测试路由分组
+func TestRouterGroup(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.Use(headerMiddleware("g"))
+
+	v2 := rt.Group("/v2.1", headerMiddleware("v2"))
+	v2.AddFixed("/user", &testController{}, "IndexAction", WithMiddleware(headerMiddleware("r")))
+	v2.Post("/user", &testController{}, "CreateAction")
+	v2.AddAuto(&testController{}, true)
+	v2.AddRegular(`^/order/(?P<id>\d+)$`, &testController{}, "ParamAction")
+
+	admin := v2.Group("admin")
+	admin.Use(headerMiddleware("admin"))
+	admin.AddFixed("/user/:id(int)", &testController{}, "ParamAction", "ext")
+
+	cases := []struct {
+		method string
+		url    string
+		status int
+		body   string
+		trace  string
+	}{
+		{"GET", "/v2.1/user", http.StatusOK, "index", "g,v2,r"},
+		{"POST", "/v2.1/user", http.StatusOK, "create", "g,v2"},
+		{"GET", "/user", http.StatusNotFound, "", "g"},
+		{"GET", "/v2.1/bingo/test/update", http.StatusOK, "update", "g,v2"},
+		{"GET", "/v2.1/order/12", http.StatusOK, "12||", "g,v2"},
+		{"GET", "/order/12", http.StatusNotFound, "", "g"},
+		{"GET", "/v2.1/admin/ext/user/7", http.StatusOK, "7||", "g,v2,admin"},
+	}
+	for _, c := range cases {
+		w := doRequest(rt, c.method, c.url)
+		if w.Code != c.status {
+			t.Errorf("%s %s status failed. Got %d, expected %d.", c.method, c.url, w.Code, c.status)
+			continue
+		}
+		if c.body != "" && w.Body.String() != c.body {
+			t.Errorf("%s %s body failed. Got %s, expected %s.", c.method, c.url, w.Body.String(), c.body)
+		}
+		if trace := strings.Join(w.Header()["X-Trace"], ","); trace != c.trace {
+			t.Errorf("%s %s trace failed. Got %s, expected %s.", c.method, c.url, trace, c.trace)
+		}
+	}
+}
+
+// TestRouterGroupUse 测试创建下级分组后在上级分组添加的中间件对下级分组生效
+func TestRouterGroupUse(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	v2 := rt.Group("/v2", headerMiddleware("v2"))
+	admin := v2.Group("/admin", headerMiddleware("admin"))
+	sub := admin.Group("/sub")
+	other := rt.Group("/other")
+	for _, g := range []*RouterGroup{v2, admin, sub, other} {
+		g.HandleFunc("/x", func(w http.ResponseWriter, r *http.Request) {})
+	}
+	v2.Use(headerMiddleware("v2b"))
+	admin.Use(headerMiddleware("adminb"))
+
+	cases := []struct {
+		url   string
+		trace string
+	}{
+		{"/v2/x", "v2,v2b"},
+		{"/v2/admin/x", "v2,v2b,admin,adminb"},
+		{"/v2/admin/sub/x", "v2,v2b,admin,adminb"},
+		{"/other/x", ""},
+	}
+	for _, c := range cases {
+		w := doRequest(rt, "GET", c.url)
+		if trace := strings.Join(w.Header()["X-Trace"], ","); w.Code != http.StatusOK || trace != c.trace {
+			t.Errorf("GET %s failed. Got %d %s, expected %s.", c.url, w.Code, trace, c.trace)
+		}
+	}
+}
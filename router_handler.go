@@ -0,0 +1,126 @@
+// http.Handler路由
+// 可以把net/http的处理函数挂到路由表上，如：pprof、Prometheus、第三方的回调地址
+// 与控制器路由一样参与路由匹配、中间件、超时、访问日志和panic处理
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// mountParam Mount时通配参数的名称，值为前缀后面的路径
+const mountParam = "mountpath"
+
+// paramKey path参数在请求context里的key
+type paramKey struct{}
+
+// Handle 添加http.Handler路由，规则同AddFixed，路径里可以带命名参数和通配参数
+//   参数
+//     pattern: 路由请求路径
+//     h:       处理函数
+//     args:    其它信息，同AddFixed，可以用WithMethods限定请求方法
+//   返回
+//     void
+func (rt *RouterTab) Handle(pattern string, h http.Handler, args ...interface{}) {
+	if h == nil {
+		panic(fmt.Sprintf("router: handle [%s] handler is nil", pattern))
+	}
+	rt.addFixed(pattern, RouterInfo{handler: h}, args)
+}
+
+// HandleFunc 添加http.HandlerFunc路由，同Handle
+//   参数
+//     pattern: 路由请求路径
+//     f:       处理函数
+//     args:    其它信息，同AddFixed
+//   返回
+//     void
+func (rt *RouterTab) HandleFunc(pattern string, f func(http.ResponseWriter, *http.Request), args ...interface{}) {
+	if f == nil {
+		panic(fmt.Sprintf("router: handle [%s] handler is nil", pattern))
+	}
+	rt.Handle(pattern, http.HandlerFunc(f), args...)
+}
+
+// Mount 把前缀及前缀下的所有路径交给http.Handler处理，如：Mount("/debug/pprof", h)
+// 处理函数收到的是完整的请求路径，需要去掉前缀时可以用http.StripPrefix包装
+// 前缀后面的路径可以用PathParam(r, "mountpath")获取，路由名称只对前缀生效
+//   参数
+//     prefix: 路径前缀
+//     h:      处理函数
+//     args:   其它信息，同AddFixed
+//   返回
+//     void
+func (rt *RouterTab) Mount(prefix string, h http.Handler, args ...interface{}) {
+	prefix = strings.TrimRight(prefix, "/")
+	rt.Handle(prefix, h, args...)
+
+	// 路由名称只给前缀的路由
+	noName := RouteOption(func(ri *RouterInfo) {
+		ri.name = ""
+	})
+	rt.Handle(prefix+"/*"+mountParam, h, append(args[:len(args):len(args)], noName)...)
+}
+
+// WithMethods 路由选项，限定请求方法，用于http.Handler路由
+//   参数
+//     methods: 请求方法，如："GET"、"POST"，"*"为不限制
+//   返回
+//     路由选项
+func WithMethods(methods ...string) RouteOption {
+	ms, _ := parseMethod(strings.Join(methods, ",") + ":")
+	return func(ri *RouterInfo) {
+		ri.httpMethods = ms
+	}
+}
+
+// PathParam 返回http.Handler路由的path参数
+//   参数
+//     r:    Request对象
+//     name: 参数名
+//   返回
+//     参数值，不存在时返回空
+func PathParam(r *http.Request, name string) string {
+	param, _ := r.Context().Value(paramKey{}).(map[string]string)
+	return param[name]
+}
+
+// handlerName 返回http.Handler的名称，用于查看路由表和冲突提示
+//   参数
+//     h: 处理函数
+//   返回
+//     名称，如：net/http/pprof.Index、*http.ServeMux
+func handlerName(h http.Handler) string {
+	if f, ok := h.(http.HandlerFunc); ok {
+		return funcName(f)
+	}
+	return fmt.Sprintf("%T", h)
+}
+
+// handlerRunner http.Handler的执行过程
+type handlerRunner struct {
+	h     http.Handler
+	param map[string]string
+	w     http.ResponseWriter
+	r     *http.Request
+}
+
+func (hr *handlerRunner) init(w http.ResponseWriter, r *http.Request) {
+	hr.w = w
+	hr.r = r
+	if len(hr.param) > 0 {
+		hr.r = r.WithContext(context.WithValue(r.Context(), paramKey{}, hr.param))
+	}
+}
+
+func (hr *handlerRunner) action() {
+	hr.h.ServeHTTP(hr.w, hr.r)
+}
+
+func (hr *handlerRunner) show() {}
+
+func (hr *handlerRunner) unInit() {}
@@ -0,0 +1,86 @@
+package bingo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHandle 测试http.Handler路由
+func TestHandle(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.HandleFunc("/hook/:provider/callback", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hook:" + PathParam(r, "provider")))
+	}, WithMethods("GET"), WithName("hook"))
+	rt.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	rt.Mount("/debug", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + "|" + PathParam(r, mountParam)))
+	}), WithName("debug"))
+	g := rt.Group("/api", headerMiddleware("api"))
+	g.Handle("/mux", http.NotFoundHandler(), WithMiddleware(headerMiddleware("mux")))
+
+	cases := []struct {
+		method string
+		url    string
+		status int
+		body   string
+		trace  string
+	}{
+		{"GET", "/hook/github/callback", 200, "hook:github", ""},
+		{"POST", "/hook/github/callback", 405, "", ""},
+		{"GET", "/boom", 500, "", ""},
+		{"GET", "/debug", 200, "/debug|", ""},
+		{"GET", "/debug/pprof/heap", 200, "/debug/pprof/heap|pprof/heap", ""},
+		{"GET", "/api/mux", 404, "404 page not found\n", "api,mux"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(c.method, c.url, nil))
+		if w.Code != c.status {
+			t.Errorf("%s %s failed. Got status %d, expected %d.", c.method, c.url, w.Code, c.status)
+		}
+		if c.body != "" && w.Body.String() != c.body {
+			t.Errorf("%s %s failed. Got body %q, expected %q.", c.method, c.url, w.Body.String(), c.body)
+		}
+		if trace := strings.Join(w.Header()["X-Trace"], ","); trace != c.trace {
+			t.Errorf("%s %s failed. Got trace %s, expected %s.", c.method, c.url, trace, c.trace)
+		}
+	}
+
+	if url, err := rt.URLFor("debug"); err != nil || url != "/debug" {
+		t.Errorf("URLFor failed. Got %s, expected /debug, err: %v.", url, err)
+	}
+
+	kinds := 0
+	for _, d := range rt.Routes() {
+		if d.Kind == RouteHandler {
+			kinds++
+		}
+	}
+	if kinds != 5 {
+		t.Errorf("Routes failed. Got %d handler routes, expected 5.", kinds)
+	}
+}
+
+// TestHandleConflict 测试http.Handler路由冲突
+func TestHandleConflict(t *testing.T) {
+	runMode := AppCfg.RunMode
+	defer func() { AppCfg.RunMode = runMode }()
+	AppCfg.RunMode = DEV
+
+	rt := NewRouterTab()
+	h := http.NotFoundHandler()
+	rt.Handle("/a", h)
+	mustPanic(t, "handle twice", func() {
+		rt.Handle("/a", h)
+	})
+	rt.Handle("/b", h, WithMethods("GET"))
+	rt.Handle("/b", h, WithMethods("POST"))
+	mustPanic(t, "unknown method", func() {
+		WithMethods("FETCH")
+	})
+}
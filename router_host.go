@@ -0,0 +1,171 @@
+// 路由域名限定
+// 支持精确域名和通配子域名，如：api.example.com、:tenant.example.com、*.example.com
+// 匹配优先级：精确域名 => 通配子域名 => 不限域名
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// hostRule 域名规则
+type hostRule struct {
+	pattern string // 域名规则，已转成小写，如：:tenant.example.com
+	name    string // 子域名参数名，不取参数时为空
+	suffix  string // 通配子域名之后的部分，如：.example.com，精确域名为空
+}
+
+// parseHost 解析域名规则
+// 通配只支持第一级子域名，:name会把子域名做为path参数，*只匹配不取参数
+//   参数
+//     pattern: 域名规则，如：api.example.com、:tenant.example.com、*.example.com
+//   返回
+//     域名规则，格式错误时返回错误信息
+func parseHost(pattern string) (*hostRule, error) {
+	raw := strings.TrimSpace(pattern)
+	pattern = stripPort(strings.ToLower(raw))
+	if pattern == "" {
+		return nil, fmt.Errorf("router: host is empty")
+	}
+
+	rule := &hostRule{pattern: pattern}
+	if !strings.HasPrefix(pattern, ":") && !strings.HasPrefix(pattern, "*") {
+		if strings.ContainsAny(pattern, ":*") {
+			return nil, fmt.Errorf("router: host [%s] wildcard must be the first label", pattern)
+		}
+		return rule, nil
+	}
+
+	pos := strings.Index(pattern, ".")
+	if pos < 0 || pos == len(pattern)-1 {
+		return nil, fmt.Errorf("router: host [%s] wildcard needs a domain", pattern)
+	}
+	rule.suffix = pattern[pos:]
+	if strings.ContainsAny(rule.suffix, ":*") {
+		return nil, fmt.Errorf("router: host [%s] wildcard must be the first label", pattern)
+	}
+	if pattern[0] == ':' {
+		// 参数名保留原样
+		rule.name = raw[1:pos]
+		if rule.name == "" {
+			return nil, fmt.Errorf("router: host [%s] param name is empty", pattern)
+		}
+	} else if pos != 1 {
+		return nil, fmt.Errorf("router: host [%s] wildcard is error", pattern)
+	}
+
+	return rule, nil
+}
+
+// WithHost 路由选项，限定路由只响应指定域名的请求
+//   参数
+//     host: 域名规则，如：api.example.com、:tenant.example.com、*.example.com
+//   返回
+//     路由选项
+func WithHost(host string) RouteOption {
+	rule, err := parseHost(host)
+	if err != nil {
+		panic(err.Error())
+	}
+	return func(ri *RouterInfo) {
+		ri.host = rule
+	}
+}
+
+// rank 匹配请求域名，返回匹配级别
+//   参数
+//     host: 请求域名，已去掉端口并转成小写
+//   返回
+//     精确域名匹配返回2，通配子域名匹配返回1，不限域名返回0，不匹配返回-1
+func (h *hostRule) rank(host string) int {
+	if h == nil {
+		return 0
+	}
+	if h.suffix == "" {
+		if h.pattern == host {
+			return 2
+		}
+		return -1
+	}
+
+	if h.subdomain(host) != "" {
+		return 1
+	}
+	return -1
+}
+
+// subdomain 返回通配匹配到的子域名
+//   参数
+//     host: 请求域名，已去掉端口并转成小写
+//   返回
+//     子域名，不匹配时返回空
+func (h *hostRule) subdomain(host string) string {
+	if !strings.HasSuffix(host, h.suffix) {
+		return ""
+	}
+	sub := host[:len(host)-len(h.suffix)]
+	if strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
+
+// String 返回域名规则，不限域名时返回空
+//   参数
+//     void
+//   返回
+//     域名规则
+func (h *hostRule) String() string {
+	if h == nil {
+		return ""
+	}
+	return h.pattern
+}
+
+// hostParam 把通配子域名做为path参数
+//   参数
+//     ri:    匹配到的路由
+//     host:  请求域名
+//     param: 已有的path参数，可以为nil
+//   返回
+//     path参数
+func hostParam(ri RouterInfo, host string, param map[string]string) map[string]string {
+	if ri.host == nil || ri.host.name == "" {
+		return param
+	}
+	if param == nil {
+		param = make(map[string]string)
+	}
+	param[ri.host.name] = ri.host.subdomain(host)
+	return param
+}
+
+// requestHost 返回请求的域名，已去掉端口并转成小写
+//   参数
+//     r: Request对象
+//   返回
+//     请求域名，如：api.example.com
+func requestHost(r *http.Request) string {
+	return stripPort(strings.ToLower(r.Host))
+}
+
+// stripPort 去掉域名里的端口
+//   参数
+//     host: 域名，如：api.example.com:8080
+//   返回
+//     不带端口的域名
+func stripPort(host string) string {
+	h, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return host
+	}
+	if _, err = strconv.Atoi(port); err != nil {
+		return host
+	}
+	return h
+}
@@ -0,0 +1,88 @@
+package bingo
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseHost 测试域名规则解析
+func TestParseHost(t *testing.T) {
+	cases := []struct {
+		pattern string
+		host    string
+		rank    int
+	}{
+		{"API.example.com", "api.example.com", 2},
+		{"api.example.com:8080", "api.example.com", 2},
+		{"api.example.com", "www.example.com", -1},
+		{":tenant.example.com", "foo.example.com", 1},
+		{":tenant.example.com", "a.b.example.com", -1},
+		{":tenant.example.com", "example.com", -1},
+		{"*.example.com", "foo.example.com", 1},
+	}
+	for _, c := range cases {
+		rule, err := parseHost(c.pattern)
+		if err != nil {
+			t.Errorf("parseHost %s failed. err: %s.", c.pattern, err.Error())
+			continue
+		}
+		if rank := rule.rank(c.host); rank != c.rank {
+			t.Errorf("rank %s %s failed. Got %d, expected %d.", c.pattern, c.host, rank, c.rank)
+		}
+	}
+
+	for _, pattern := range []string{"", ":.example.com", "*", "a*.example.com", "api.*.com", "www.:sub.com"} {
+		if _, err := parseHost(pattern); err == nil {
+			t.Errorf("parseHost %s failed. Got nil, expected error.", pattern)
+		}
+	}
+}
+
+// TestHostRouter 测试按域名路由
+func TestHostRouter(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.AddFixed("/user/index", &testController{}, "IndexAction")
+	rt.Host("api.example.com").AddFixed("/user/index", &testController{}, "CreateAction")
+	tenant := rt.Host(":orderId.example.com").Group("/v2")
+	tenant.AddFixed("/user/:id(int)", &testController{}, "ParamAction")
+	tenant.Get("/user/index", &testController{}, "UpdateAction", WithHost("www.example.com"))
+	rt.AddRegular("^/reg/(?P<id>\\d+)$", &testController{}, "ParamAction", WithHost(":orderId.example.com"))
+
+	cases := []struct {
+		method string
+		host   string
+		url    string
+		status int
+		body   string
+	}{
+		{"GET", "www.test.com", "/user/index", 200, "index"},
+		{"GET", "api.example.com", "/user/index", 200, "create"},
+		{"GET", "API.example.com:8080", "/user/index", 200, "create"},
+		{"GET", "shop.example.com", "/user/index", 200, "index"},
+		{"GET", "shop.example.com", "/v2/user/10", 200, "10|shop|"},
+		{"GET", "shop.example.com", "/v2/user/10/path/a", 404, ""},
+		{"GET", "www.test.com", "/v2/user/10", 404, ""},
+		{"GET", "www.example.com", "/v2/user/index", 200, "update"},
+		{"POST", "www.example.com", "/v2/user/index", 405, ""},
+		{"GET", "shop.example.com", "/v2/user/index", 404, ""},
+		{"GET", "shop.example.com", "/reg/7", 200, "7|shop|"},
+		{"GET", "www.test.com", "/reg/7", 404, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(c.method, c.url, nil)
+		r.Host = c.host
+		rt.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s %s%s status failed. Got %d, expected %d.", c.method, c.host, c.url, w.Code, c.status)
+		} else if c.body != "" && w.Body.String() != c.body {
+			t.Errorf("%s %s%s body failed. Got %s, expected %s.", c.method, c.host, c.url, w.Body.String(), c.body)
+		}
+	}
+
+	routes := rt.Routes()
+	if len(routes) != 5 || routes[1].Host != "api.example.com" {
+		t.Errorf("Routes host failed. Got %+v.", routes)
+	}
+}
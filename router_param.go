@@ -0,0 +1,122 @@
+// 路由路径解析
+// 支持命名参数和通配参数，如：/user/:id(int)/orders/:orderId、/files/*path
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 参数类型
+const (
+	ParamTypeString = "string" // 任意非空字符串，默认类型
+	ParamTypeInt    = "int"    // 整数
+	ParamTypeFloat  = "float"  // 浮点数
+	ParamTypeAlpha  = "alpha"  // 字母
+	ParamTypeAlnum  = "alnum"  // 字母和数字
+)
+
+// routeSeg 路由中的一段
+type routeSeg struct {
+	value string // 静态段的值，已转成小写
+	name  string // 参数名，静态段为空
+	typ   string // 参数类型
+	wild  bool   // 是否是通配段，匹配剩余的全部路径
+}
+
+// parseSegs 解析路由路径
+// 静态段不区分大小写，参数名保留原样
+//   参数
+//     pattern: 路由请求路径，如：/user/:id(int)/orders/:orderId、/files/*path
+//   返回
+//     路由段列表，格式错误时返回错误信息
+func parseSegs(pattern string) ([]routeSeg, error) {
+	parts := strings.Split(strings.Trim(pattern, "/"), "/")
+	segs := make([]routeSeg, 0, len(parts))
+	names := make(map[string]bool)
+	for i, part := range parts {
+		seg := routeSeg{}
+		switch {
+		case strings.HasPrefix(part, ":"):
+			seg.name, seg.typ = part[1:], ParamTypeString
+			if pos := strings.Index(seg.name, "("); pos > 0 {
+				if !strings.HasSuffix(seg.name, ")") {
+					return nil, fmt.Errorf("router: pattern [%s] param [%s] is error", pattern, part)
+				}
+				seg.name, seg.typ = seg.name[:pos], seg.name[pos+1:len(seg.name)-1]
+			}
+			if !isParamType(seg.typ) {
+				return nil, fmt.Errorf("router: pattern [%s] param type [%s] is unknown", pattern, seg.typ)
+			}
+		case strings.HasPrefix(part, "*"):
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("router: pattern [%s] wildcard must be the last", pattern)
+			}
+			seg.name, seg.wild = part[1:], true
+		default:
+			seg.value = lowerASCII(part)
+			segs = append(segs, seg)
+			continue
+		}
+
+		if seg.name == "" {
+			return nil, fmt.Errorf("router: pattern [%s] param name is empty", pattern)
+		}
+		if names[seg.name] {
+			return nil, fmt.Errorf("router: pattern [%s] param [%s] is repeated", pattern, seg.name)
+		}
+		names[seg.name] = true
+		segs = append(segs, seg)
+	}
+
+	return segs, nil
+}
+
+// isParamType 判断是否是支持的参数类型
+//   参数
+//     typ: 参数类型
+//   返回
+//     是返回true，否则返回false
+func isParamType(typ string) bool {
+	switch typ {
+	case ParamTypeString, ParamTypeInt, ParamTypeFloat, ParamTypeAlpha, ParamTypeAlnum:
+		return true
+	}
+	return false
+}
+
+// checkParamType 检查参数值是否符合参数类型
+//   参数
+//     typ: 参数类型
+//     val: 参数值
+//   返回
+//     符合返回true，否则返回false
+func checkParamType(typ, val string) bool {
+	if val == "" {
+		return false
+	}
+
+	switch typ {
+	case ParamTypeInt:
+		_, err := strconv.ParseInt(val, 10, 64)
+		return err == nil
+	case ParamTypeFloat:
+		_, err := strconv.ParseFloat(val, 64)
+		return err == nil
+	case ParamTypeAlpha, ParamTypeAlnum:
+		for _, c := range val {
+			if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+				continue
+			}
+			if typ == ParamTypeAlnum && c >= '0' && c <= '9' {
+				continue
+			}
+			return false
+		}
+	}
+
+	return true
+}
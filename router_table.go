@@ -0,0 +1,271 @@
+// 路由表查看及冲突检测
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// 路由种类
+const (
+	RouteFixed   = "fixed"
+	RouteAuto    = "auto"
+	RouteRegular = "regular"
+	RouteShell   = "shell"
+	RouteHandler = "handler" // http.Handler路由，见RouterTab.Handle、RouterTab.Mount
+)
+
+// RouteDesc 路由描述，用于查看路由表
+type RouteDesc struct {
+	Pattern     string        // 路由请求路径
+	Host        string        // 域名限定，为空时不限制
+	Kind        string        // 路由种类：fixed | auto | regular | shell | handler
+	Controller  string        // 控制器类型，如：controllers.DemoController
+	Action      string        // 控制器方法名
+	Methods     []string      // 允许的请求方法，为空时不限制
+	Middlewares []string      // 分组和路由的中间件，不包括全局中间件
+	Name        string        // 路由名称
+	Timeout     time.Duration // 生效的请求超时时间，NoTimeout为不限制，脚本路由为0
+}
+
+// Routes 返回路由表
+// 固定路由和自动路由按路径排序，正则路由按匹配顺序排列，最后是脚本路由
+//   参数
+//     void
+//   返回
+//     路由列表
+func (rt *RouterTab) Routes() []RouteDesc {
+	var list []RouteDesc
+
+	// 固定路由、自动路由
+	rt.tree.walk(func(leaf *treeLeaf) {
+		for _, ri := range leaf.fixed {
+			kind := RouteFixed
+			if ri.handler != nil {
+				kind = RouteHandler
+			}
+			list = append(list, rt.routeDesc(ri, kind))
+		}
+		for _, ri := range leaf.auto {
+			list = append(list, rt.routeDesc(ri, RouteAuto))
+		}
+	})
+	sort.SliceStable(list, func(i, j int) bool {
+		if pi, pj := strings.ToLower(list[i].Pattern), strings.ToLower(list[j].Pattern); pi != pj {
+			return pi < pj
+		}
+		return list[i].Host < list[j].Host
+	})
+
+	// 正则路由
+	for _, rr := range rt.regularRouters {
+		for _, ri := range rr.infos {
+			list = append(list, rt.routeDesc(ri, RouteRegular))
+		}
+	}
+
+	// 脚本路由
+	shells := make([]string, 0, len(rt.shellRouters))
+	for pattern := range rt.shellRouters {
+		shells = append(shells, pattern)
+	}
+	sort.Strings(shells)
+	for _, pattern := range shells {
+		list = append(list, RouteDesc{
+			Pattern:    pattern,
+			Kind:       RouteShell,
+			Controller: "-",
+			Action:     funcName(rt.shellRouters[pattern]),
+		})
+	}
+
+	return list
+}
+
+// PrintRoutes 以表格形式输出路由表
+//   参数
+//     w: 输出位置，如：os.Stdout
+//   返回
+//     void
+func (rt *RouterTab) PrintRoutes(w io.Writer) {
+	if len(rt.middlewares) > 0 {
+		mws := make([]string, 0, len(rt.middlewares))
+		for _, mw := range rt.middlewares {
+			mws = append(mws, funcName(mw))
+		}
+		fmt.Fprintf(w, "Global middlewares: %s\n\n", strings.Join(mws, ", "))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATTERN\tHOST\tKIND\tCONTROLLER\tACTION\tMETHODS\tTIMEOUT\tMIDDLEWARES\tNAME")
+	for _, r := range rt.Routes() {
+		methods := "*"
+		if len(r.Methods) > 0 {
+			methods = strings.Join(r.Methods, ",")
+		}
+		timeout := "-"
+		if r.Timeout == NoTimeout {
+			timeout = "none"
+		} else if r.Timeout > 0 {
+			timeout = r.Timeout.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Pattern, strOrDash(r.Host), r.Kind, r.Controller, r.Action,
+			methods, timeout, strOrDash(strings.Join(r.Middlewares, ",")), strOrDash(r.Name))
+	}
+	tw.Flush()
+}
+
+// conflict 处理路由冲突，开发模式直接panic，其它模式输出日志，后添加的路由生效
+//   参数
+//     format: 格式串
+//     v:      参数
+//   返回
+//     void
+func (rt *RouterTab) conflict(format string, v ...interface{}) {
+	msg := fmt.Sprintf("router: conflict, "+format, v...)
+	if AppCfg != nil && AppCfg.RunMode == DEV {
+		panic(msg)
+	}
+	log.Println(msg)
+}
+
+// overlapRouter 查找请求方法有重叠的路由
+// 域名限定相同，并且都不限制请求方法或者有相同的请求方法时认为重叠，指向同一个控制器方法的不算重叠
+//   参数
+//     infos:     已有的路由列表
+//     routeInfo: 要添加的路由
+//   返回
+//     重叠的路由，没有时返回nil
+func overlapRouter(infos []RouterInfo, routeInfo RouterInfo) *RouterInfo {
+	for i := range infos {
+		if infos[i].handler == nil && infos[i].controllerType == routeInfo.controllerType && infos[i].method == routeInfo.method {
+			continue
+		}
+		if infos[i].host.String() != routeInfo.host.String() {
+			continue
+		}
+		a, b := infos[i].httpMethods, routeInfo.httpMethods
+		if len(a) == 0 && len(b) == 0 {
+			return &infos[i]
+		}
+		for _, m := range a {
+			for _, n := range b {
+				if m == n {
+					return &infos[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// desc 返回路由的简短描述，用于冲突提示
+//   参数
+//     void
+//   返回
+//     描述，如：controllers.DemoController.IndexAction
+func (ri *RouterInfo) desc() string {
+	if ri.handler != nil {
+		return ri.controllerName()
+	}
+	return fmt.Sprintf("%s.%s", ri.controllerName(), ri.method)
+}
+
+// controllerName 返回控制器类型名
+//   参数
+//     void
+//   返回
+//     控制器类型名，如：controllers.DemoController，http.Handler路由返回处理函数名
+func (ri *RouterInfo) controllerName() string {
+	if ri.handler != nil {
+		return handlerName(ri.handler)
+	}
+	if ri.controllerType == nil {
+		return "-"
+	}
+	return ri.controllerType.String()
+}
+
+// routeDesc 生成路由描述
+//   参数
+//     ri:   路由信息
+//     kind: 路由种类
+//   返回
+//     路由描述
+func (rt *RouterTab) routeDesc(ri RouterInfo, kind string) RouteDesc {
+	desc := RouteDesc{
+		Pattern:    ri.pattern,
+		Host:       ri.host.String(),
+		Kind:       kind,
+		Controller: ri.controllerName(),
+		Action:     ri.method,
+		Methods:    ri.httpMethods,
+		Name:       ri.name,
+		Timeout:    rt.routeTimeout(ri),
+	}
+	// 分组的中间件列表是共用的，限定容量后再追加，不写入原列表
+	mws := ri.group.allMiddlewares()
+	mws = append(mws[:len(mws):len(mws)], ri.middlewares...)
+	for _, mw := range mws {
+		desc.Middlewares = append(desc.Middlewares, funcName(mw))
+	}
+
+	return desc
+}
+
+// walk 遍历路由树所有的叶子
+//   参数
+//     f: 处理叶子
+//   返回
+//     void
+func (n *treeNode) walk(f func(*treeLeaf)) {
+	if n.leaf != nil {
+		f(n.leaf)
+	}
+	for _, c := range n.children {
+		c.walk(f)
+	}
+	for _, p := range n.params {
+		p.walk(f)
+	}
+	if n.wild != nil {
+		n.wild.walk(f)
+	}
+}
+
+// funcName 返回函数名
+//   参数
+//     f: 函数
+//   返回
+//     函数名，如：github.com/lixy529/bingo/demo/controllers.IndexAction
+func funcName(f interface{}) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return "-"
+	}
+	if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return "-"
+}
+
+// strOrDash 字符串为空时返回"-"
+//   参数
+//     s: 字符串
+//   返回
+//     处理后的字符串
+func strOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package bingo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// mustPanic 执行f，没有panic时报错
+func mustPanic(t *testing.T, name string, f func()) {
+	defer func() {
+		if err := recover(); err == nil {
+			t.Errorf("%s failed. Got no panic, expected panic.", name)
+		}
+	}()
+	f()
+}
+
+// TestRouterConflict 测试路由冲突检测
+func TestRouterConflict(t *testing.T) {
+	runMode := AppCfg.RunMode
+	defer func() { AppCfg.RunMode = runMode }()
+
+	// 开发模式冲突时panic
+	AppCfg.RunMode = DEV
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.Get("/user/index", &testController{}, "IndexAction")
+	rt.Post("/user/index", &testController{}, "CreateAction")
+	rt.AddAuto(&testController{})
+	rt.AddFixed("/order/:id(int)", &testController{}, "ParamAction")
+	rt.AddRegular("^/regular/(?P<id>\\d+)$", &testController{}, "ParamAction")
+	rt.AddShell("test:shell", func() {})
+
+	mustPanic(t, "fixed override", func() {
+		rt.AddFixed("/User/Index", &testController{}, "get,put:UpdateAction")
+	})
+	mustPanic(t, "fixed shadows auto", func() {
+		rt.AddFixed("/test/index", &testController{}, "UpdateAction")
+	})
+	mustPanic(t, "auto shadowed by fixed", func() {
+		rt2 := NewRouterTab()
+		rt2.AddFixed("/test/create", &testController{}, "IndexAction")
+		rt2.AddAuto(&testController{})
+	})
+	mustPanic(t, "param shadowed", func() {
+		rt.AddFixed("/order/:orderId(int)", &testController{}, "ParamAction")
+	})
+	mustPanic(t, "regular override", func() {
+		rt.AddRegular("^/regular/(?P<id>\\d+)$", &testController{}, "IndexAction")
+	})
+	mustPanic(t, "shell repeated", func() {
+		rt.AddShell("test:shell", func() {})
+	})
+
+	// 方法不重叠或指向同一个控制器方法时不冲突
+	rt.AddFixed("/test/update", &testController{}, "UpdateAction")
+	rt.Put("/user/index", &testController{}, "UpdateAction")
+
+	// 其它模式只输出日志，后添加的路由生效
+	AppCfg.RunMode = PROD
+	rt.AddFixed("/user/index", &testController{}, "get:UpdateAction")
+	if w := doRequest(rt, "GET", "/user/index"); w.Body.String() != "update" {
+		t.Errorf("prod override failed. Got %s, expected update.", w.Body.String())
+	}
+}
+
+// TestRoutes 测试路由表查看
+func TestRoutes(t *testing.T) {
+	rt := NewRouterTab()
+	rt.Use(headerMiddleware("g"))
+	rt.Group("/v2", headerMiddleware("v2")).Get("/user/index", &testController{}, "IndexAction", WithName("v2.user"))
+	rt.AddAuto(&testController{})
+	rt.AddRegular("^/regular/(?P<id>\\d+)$", &testController{}, "ParamAction")
+	rt.AddShell("test:shell", func() {})
+
+	routes := rt.Routes()
+	kinds := map[string]int{}
+	for _, r := range routes {
+		kinds[r.Kind]++
+	}
+	if kinds[RouteFixed] != 1 || kinds[RouteAuto] != 4 || kinds[RouteRegular] != 1 || kinds[RouteShell] != 1 {
+		t.Fatalf("Routes kinds failed. Got %v.", kinds)
+	}
+
+	r := routes[len(routes)-3]
+	if r.Kind != RouteFixed || r.Pattern != "/v2/user/index" || r.Action != "IndexAction" || r.Name != "v2.user" ||
+		r.Controller != "bingo.testController" || len(r.Methods) != 1 || r.Methods[0] != "GET" || len(r.Middlewares) != 1 {
+		t.Errorf("Routes fixed failed. Got %+v.", r)
+	}
+	if r = routes[len(routes)-1]; r.Pattern != "test:shell" {
+		t.Errorf("Routes shell failed. Got %+v.", r)
+	}
+
+	buf := &bytes.Buffer{}
+	rt.PrintRoutes(buf)
+	if out := buf.String(); !strings.Contains(out, "Global middlewares:") || !strings.Contains(out, "/v2/user/index") {
+		t.Errorf("PrintRoutes failed. Got %s.", out)
+	}
+}
@@ -5,9 +5,50 @@ package bingo
 
 import (
 	"fmt"
+	"github.com/lixy529/gotools/logs"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
+func init() {
+	// 测试时框架日志输出到控制台，只输出FATAL级别
+	if Flogger == nil {
+		Flogger = logs.Log("console")
+		Flogger.Init(`{"level":5}`)
+	}
+}
+
+// testController 测试用的控制器
+type testController struct {
+	Controller
+}
+
+func (c *testController) IndexAction() {
+	c.WriteString("index")
+}
+
+func (c *testController) CreateAction() {
+	c.WriteString("create")
+}
+
+func (c *testController) UpdateAction() {
+	c.WriteString("update")
+}
+
+func (c *testController) ParamAction() {
+	id, _ := c.ParamInt("id")
+	c.WriteString(fmt.Sprintf("%d|%s|%s", id, c.ParamString("orderId"), c.ParamString("path")))
+}
+
+// doRequest 发送一个测试请求
+func doRequest(rt *RouterTab, method, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, url, nil)
+	rt.ServeHTTP(w, r)
+	return w
+}
+
 // TestGetPatternAndParam
 func TestGetPatternAndParam(t *testing.T) {
 	r := &RouterTab{}
@@ -37,3 +78,258 @@ func TestGetPatternAndParam(t *testing.T) {
 	fmt.Println(pattern)
 	fmt.Println(param)
 }
+
+// TestMethodRouter 测试按请求方法路由
+func TestMethodRouter(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.Get("/user", &testController{}, "IndexAction")
+	rt.AddFixed("/user", &testController{}, "post:CreateAction")
+	rt.AddFixed("/user/update", &testController{}, "put,patch:UpdateAction")
+	rt.AddFixed("/user/all", &testController{}, "IndexAction")
+
+	cases := []struct {
+		method string
+		url    string
+		status int
+		body   string
+		allow  string
+	}{
+		{"GET", "/user", http.StatusOK, "index", ""},
+		{"HEAD", "/user", http.StatusOK, "", ""},
+		{"POST", "/user", http.StatusOK, "create", ""},
+		{"DELETE", "/user", http.StatusMethodNotAllowed, "", "GET, HEAD, POST, OPTIONS"},
+		{"OPTIONS", "/user", http.StatusNoContent, "", "GET, HEAD, POST, OPTIONS"},
+		{"PATCH", "/user/update", http.StatusOK, "update", ""},
+		{"GET", "/user/update", http.StatusMethodNotAllowed, "", "PUT, PATCH, OPTIONS"},
+		{"DELETE", "/user/all", http.StatusOK, "index", ""},
+		{"GET", "/none", http.StatusNotFound, "", ""},
+	}
+
+	for _, c := range cases {
+		w := doRequest(rt, c.method, c.url)
+		if w.Code != c.status {
+			t.Errorf("%s %s status failed. Got %d, expected %d.", c.method, c.url, w.Code, c.status)
+			continue
+		}
+		if c.body != "" && w.Body.String() != c.body {
+			t.Errorf("%s %s body failed. Got %s, expected %s.", c.method, c.url, w.Body.String(), c.body)
+		}
+		if allow := w.Header().Get("Allow"); allow != c.allow {
+			t.Errorf("%s %s Allow failed. Got %s, expected %s.", c.method, c.url, allow, c.allow)
+		}
+	}
+}
+
+// TestParamRouter 测试带参数的路由
+func TestParamRouter(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.AddFixed("/user/:id(int)/orders/:orderId", &testController{}, "ParamAction")
+	rt.AddFixed("/files/*path", &testController{}, "ParamAction")
+	rt.AddFixed("/user/index", &testController{}, "IndexAction")
+
+	cases := []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{"/user/10/orders/A01", http.StatusOK, "10|A01|"},
+		{"/User/10/Orders/a01/", http.StatusOK, "10|a01|"},
+		{"/user/abc/orders/A01", http.StatusNotFound, ""},
+		{"/user/10/orders", http.StatusNotFound, ""},
+		{"/user/index", http.StatusOK, "index"},
+		{"/files/img/logo.png", http.StatusOK, "0||img/logo.png"},
+		{"/files", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		w := doRequest(rt, "GET", c.url)
+		if w.Code != c.status {
+			t.Errorf("GET %s status failed. Got %d, expected %d.", c.url, w.Code, c.status)
+			continue
+		}
+		if c.body != "" && w.Body.String() != c.body {
+			t.Errorf("GET %s body failed. Got %s, expected %s.", c.url, w.Body.String(), c.body)
+		}
+	}
+
+	_, err := parseSegs("/user/:id(uuid)")
+	if err == nil {
+		t.Errorf("parseSegs failed. Got nil, expected error.")
+	}
+	_, err = parseSegs("/files/*path/info")
+	if err == nil {
+		t.Errorf("parseSegs failed. Got nil, expected error.")
+	}
+}
+
+// TestTreeRouter 测试路由树的匹配优先级和尾部参数
+func TestTreeRouter(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.AddFixed("/user/index", &testController{}, "IndexAction")
+	rt.AddFixed("/user/:id/orders/:orderId", &testController{}, "ParamAction")
+	rt.AddFixed("/files/*path", &testController{}, "ParamAction")
+	rt.AddFixed("/users", &testController{}, "CreateAction")
+	rt.AddAuto(&testController{})
+
+	cases := []struct {
+		url  string
+		body string
+	}{
+		{"/user/index", "index"},
+		{"/users", "create"},
+		{"/user/10/orders/20", "10|20|"},
+		{"/files/10/orders", "0||10/orders"},
+		{"/test/update", "update"},
+		{"/TEST/Create", "create"},
+	}
+	for _, c := range cases {
+		w := doRequest(rt, "GET", c.url)
+		if w.Body.String() != c.body {
+			t.Errorf("GET %s failed. Got %s, expected %s.", c.url, w.Body.String(), c.body)
+		}
+	}
+
+	// 尾部参数
+	routeInfo, param, _, ok := rt.findRouter("GET", "", "/user/index/ver/3.0/id/10/isbool")
+	if !ok || routeInfo.method != "IndexAction" {
+		t.Errorf("findRouter failed. Got %s, expected IndexAction.", routeInfo.method)
+	} else if param["ver"] != "3.0" || param["id"] != "10" || len(param) != 3 {
+		t.Errorf("findRouter param failed. Got %v.", param)
+	}
+
+	// 路径段边界
+	if _, _, _, ok = rt.findRouter("GET", "", "/user/indexes/id/10"); ok {
+		t.Errorf("findRouter failed. /user/indexes/id/10 should not match.")
+	}
+	if _, _, _, ok = rt.findRouter("GET", "", "/users/id/10"); ok {
+		t.Errorf("findRouter failed. /users/id/10 should not match.")
+	}
+
+	// 非ASCII路径，ToLower会改变KELVIN SIGN的字节长度
+	rt.AddFixed("/u/:name", &testController{}, "ParamAction")
+	for _, url := range []string{"/u/%E2%84%AA%E2%84%AA", "/U/%E2%84%AA/x", "/%E2%84%AA/%E2%84%AA"} {
+		if w := doRequest(rt, "GET", url); w.Code == http.StatusInternalServerError {
+			t.Errorf("GET %s failed. Got %d.", url, w.Code)
+		}
+	}
+	if _, param, _, ok := rt.findRouter("GET", "", "/U/\u212a\u212a"); !ok || param["name"] != "\u212a\u212a" {
+		t.Errorf("findRouter non-ASCII failed. Got %v %v.", ok, param)
+	}
+}
+
+// benchRouter 生成n组路由的路由表
+func benchRouter(n int) *RouterTab {
+	rt := NewRouterTab()
+	for i := 0; i < n; i++ {
+		rt.AddFixed(fmt.Sprintf("/api%d/user/index", i), &testController{}, "IndexAction")
+		rt.AddFixed(fmt.Sprintf("/api%d/user/:id(int)/orders", i), &testController{}, "ParamAction")
+		rt.AddFixed(fmt.Sprintf("/api%d/static/*path", i), &testController{}, "ParamAction")
+	}
+	return rt
+}
+
+// BenchmarkFindRouter 路由查找的耗时不随路由个数增长
+func BenchmarkFindRouter(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		rt := benchRouter(n)
+		paths := []string{
+			fmt.Sprintf("/api%d/user/index", n/2),
+			fmt.Sprintf("/api%d/user/1001/orders", n/2),
+			fmt.Sprintf("/api%d/user/index/id/10/ver/3.0", n/2),
+			fmt.Sprintf("/api%d/static/js/app.js", n/2),
+		}
+		for _, p := range paths {
+			b.Run(fmt.Sprintf("routes=%d%s", n*3, p[len(fmt.Sprintf("/api%d", n/2)):]), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					rt.findRouter("GET", "", p)
+				}
+			})
+		}
+	}
+}
+
+// TestRegularRouter 测试正则路由
+func TestRegularRouter(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.AddRegular(`^/order/(?P<id>\d+)$`, &testController{}, "ParamAction")
+	rt.AddRegular(`^/order/`, &testController{}, "IndexAction")
+	rt.AddRegular(`^/order/new$`, &testController{}, "CreateAction", 10)
+	rt.AddRegular(`^/files/(?P<path>.+)$`, &testController{}, "post:ParamAction")
+
+	cases := []struct {
+		method string
+		url    string
+		status int
+		body   string
+	}{
+		{"GET", "/order/1001", http.StatusOK, "1001||"},
+		{"GET", "/order/abc", http.StatusOK, "index"},
+		{"GET", "/order/new", http.StatusOK, "create"},
+		{"POST", "/files/a/b.txt", http.StatusOK, "0||a/b.txt"},
+		{"GET", "/files/a/b.txt", http.StatusMethodNotAllowed, ""},
+	}
+
+	// 多次执行，验证匹配顺序是确定的
+	for i := 0; i < 20; i++ {
+		for _, c := range cases {
+			w := doRequest(rt, c.method, c.url)
+			if w.Code != c.status {
+				t.Fatalf("%s %s status failed. Got %d, expected %d.", c.method, c.url, w.Code, c.status)
+			}
+			if c.body != "" && w.Body.String() != c.body {
+				t.Fatalf("%s %s body failed. Got %s, expected %s.", c.method, c.url, w.Body.String(), c.body)
+			}
+		}
+	}
+}
+
+// TestURLFor 测试根据路由名称生成url
+func TestURLFor(t *testing.T) {
+	rt := NewRouterTab()
+	rt.AddFixed("/user/index", &testController{}, "IndexAction", WithName("user.index"))
+	rt.AddFixed("/user/:id(int)/orders/:orderId", &testController{}, "ParamAction", WithName("user.order"))
+	rt.Group("/v2").AddFixed("/files/*path", &testController{}, "ParamAction", WithName("files"))
+	rt.AddAuto(&testController{}, WithName("test"))
+	rt.AddRegular("^/order$", &testController{}, "IndexAction", WithName("order"))
+
+	cases := []struct {
+		name   string
+		params []interface{}
+		url    string
+	}{
+		{"user.index", nil, "/user/index"},
+		{"user.index", []interface{}{"id", 10, "?tab", "a b"}, "/user/index/id/10?tab=a+b"},
+		{"user.order", []interface{}{"id", 10, "orderId", "A01", "page", 2}, "/user/10/orders/A01?page=2"},
+		{"files", []interface{}{"path", "img/logo 1.png"}, "/v2/files/img/logo%201.png"},
+		{"test.update", nil, "/test/update"},
+	}
+	for _, c := range cases {
+		url, err := rt.URLFor(c.name, c.params...)
+		if err != nil {
+			t.Errorf("URLFor %s failed. err: %s.", c.name, err.Error())
+		} else if url != c.url {
+			t.Errorf("URLFor %s failed. Got %s, expected %s.", c.name, url, c.url)
+		}
+	}
+
+	errCases := []struct {
+		name   string
+		params []interface{}
+	}{
+		{"none", nil},
+		{"order", nil},
+		{"user.order", []interface{}{"id", 10}},
+		{"user.order", []interface{}{"id", "abc", "orderId", 1}},
+		{"user.index", []interface{}{"id"}},
+	}
+	for _, c := range errCases {
+		if _, err := rt.URLFor(c.name, c.params...); err == nil {
+			t.Errorf("URLFor %s %v failed. Got nil, expected error.", c.name, c.params)
+		}
+	}
+}
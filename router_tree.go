@@ -0,0 +1,253 @@
+// 路由前缀树
+// 静态路径按压缩前缀树存储，参数段和通配段做为单独的子节点
+// 匹配优先级：静态段 => 参数段 => 通配段
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// treeLeaf 路由树叶子上挂的路由
+type treeLeaf struct {
+	fixed []RouterInfo // 固定路由
+	auto  []RouterInfo // 自动路由
+}
+
+// treeNode 路由树节点
+type treeNode struct {
+	path     string      // 静态路径，已转成小写
+	indices  string      // 静态子节点的首字符，与children一一对应
+	children []*treeNode // 静态子节点
+	params   []*treeNode // 参数子节点
+	wild     *treeNode   // 通配子节点
+	name     string      // 参数名，参数节点和通配节点使用
+	typ      string      // 参数类型，参数节点使用
+	leaf     *treeLeaf   // 挂在此节点上的路由，没有时为nil
+}
+
+// treeParam 匹配时得到的参数
+type treeParam struct {
+	name  string
+	value string
+}
+
+// insert 添加一个路由路径
+//   参数
+//     segs: 路由段列表
+//   返回
+//     路由路径对应的节点的叶子，参数段被同位置同类型的其它参数遮挡时返回错误信息
+func (n *treeNode) insert(segs []routeSeg) (*treeLeaf, error) {
+	var shadow error
+	static := ""
+	for _, seg := range segs {
+		if seg.name == "" {
+			static += "/" + seg.value
+			continue
+		}
+
+		n = n.insertStatic(static + "/")
+		static = ""
+		if seg.wild {
+			if n.wild == nil {
+				n.wild = &treeNode{name: seg.name}
+			} else if n.wild.name != seg.name {
+				panic("router: wildcard [*" + seg.name + "] conflicts with [*" + n.wild.name + "]")
+			}
+			n = n.wild
+			continue
+		}
+		for _, p := range n.params {
+			if p.typ == seg.typ && p.name != seg.name && shadow == nil {
+				shadow = fmt.Errorf("param [:%s] is shadowed by [:%s]", seg.name, p.name)
+			}
+		}
+		n = n.paramChild(seg)
+	}
+	n = n.insertStatic(static)
+
+	if n.leaf == nil {
+		n.leaf = &treeLeaf{}
+	}
+	return n.leaf, shadow
+}
+
+// insertStatic 添加一段静态路径，需要时拆分已有节点
+//   参数
+//     path: 静态路径
+//   返回
+//     静态路径最后对应的节点
+func (n *treeNode) insertStatic(path string) *treeNode {
+	for len(path) > 0 {
+		child := n.staticChild(path[0])
+		if child == nil {
+			child = &treeNode{path: path}
+			n.indices += string(path[0])
+			n.children = append(n.children, child)
+			return child
+		}
+
+		l := commonPrefix(child.path, path)
+		if l < len(child.path) {
+			// 拆分节点，原节点的子节点和路由都挂到拆出来的节点上
+			split := &treeNode{
+				path:     child.path[l:],
+				indices:  child.indices,
+				children: child.children,
+				params:   child.params,
+				wild:     child.wild,
+				leaf:     child.leaf,
+			}
+			*child = treeNode{
+				path:     child.path[:l],
+				indices:  string(split.path[0]),
+				children: []*treeNode{split},
+			}
+		}
+
+		path = path[l:]
+		n = child
+	}
+
+	return n
+}
+
+// paramChild 返回参数名和类型都相同的参数子节点，没有时新建
+//   参数
+//     seg: 参数段
+//   返回
+//     参数子节点
+func (n *treeNode) paramChild(seg routeSeg) *treeNode {
+	for _, p := range n.params {
+		if p.name == seg.name && p.typ == seg.typ {
+			return p
+		}
+	}
+
+	p := &treeNode{name: seg.name, typ: seg.typ}
+	n.params = append(n.params, p)
+	return p
+}
+
+// staticChild 根据首字符查找静态子节点
+//   参数
+//     c: 首字符
+//   返回
+//     静态子节点，没有时返回nil
+func (n *treeNode) staticChild(c byte) *treeNode {
+	if i := strings.IndexByte(n.indices, c); i >= 0 {
+		return n.children[i]
+	}
+	return nil
+}
+
+// find 全路径匹配，按优先级依次把匹配到的叶子交给visit，visit返回true时结束匹配
+//   参数
+//     path:  剩余的请求路径，保留大小写，用于取参数值
+//     lower: 剩余的请求路径，已用lowerASCII转成小写，用于匹配静态路径
+//     ps:    已匹配到的参数
+//     visit: 处理匹配到的叶子
+//   返回
+//     visit返回true时返回true，否则返回false
+func (n *treeNode) find(path, lower string, ps []treeParam, visit func(*treeLeaf, []treeParam) bool) bool {
+	if len(path) == 0 {
+		return n.leaf != nil && visit(n.leaf, ps)
+	}
+
+	// 静态段
+	if child := n.staticChild(lower[0]); child != nil && strings.HasPrefix(lower, child.path) {
+		l := len(child.path)
+		if child.find(path[l:], lower[l:], ps, visit) {
+			return true
+		}
+	}
+
+	if len(n.params) == 0 && n.wild == nil {
+		return false
+	}
+
+	// 参数段
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	for _, p := range n.params {
+		if checkParamType(p.typ, path[:end]) {
+			if p.find(path[end:], lower[end:], append(ps, treeParam{p.name, path[:end]}), visit) {
+				return true
+			}
+		}
+	}
+
+	// 通配段
+	if n.wild != nil && n.wild.leaf != nil {
+		return visit(n.wild.leaf, append(ps, treeParam{n.wild.name, path}))
+	}
+
+	return false
+}
+
+// prefixLeaves 沿静态路径查找所有是请求路径前缀的叶子，前缀必须在路径段的边界上
+//   参数
+//     lower: 请求路径，已用lowerASCII转成小写
+//   返回
+//     叶子列表和前缀长度，由短到长排列
+func (n *treeNode) prefixLeaves(lower string) ([]*treeLeaf, []int) {
+	var leaves []*treeLeaf
+	var ends []int
+	pos := 0
+	for pos < len(lower) {
+		child := n.staticChild(lower[pos])
+		if child == nil || !strings.HasPrefix(lower[pos:], child.path) {
+			break
+		}
+		pos += len(child.path)
+		n = child
+		if n.leaf != nil && pos < len(lower) && lower[pos] == '/' {
+			leaves = append(leaves, n.leaf)
+			ends = append(ends, pos)
+		}
+	}
+
+	return leaves, ends
+}
+
+// lowerASCII 只把A-Z转成小写，转换前后长度相同，path和lower可以使用同一个下标
+// strings.ToLower会改变部分非ASCII字符的字节长度，如：U+212A(KELVIN SIGN)转成k
+//   参数
+//     s: 字符串
+//   返回
+//     转换后的字符串
+func lowerASCII(s string) string {
+	i := 0
+	for i < len(s) && (s[i] < 'A' || s[i] > 'Z') {
+		i++
+	}
+	if i == len(s) {
+		return s
+	}
+
+	b := []byte(s)
+	for ; i < len(b); i++ {
+		if c := b[i]; c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// commonPrefix 返回两个字符串公共前缀的长度
+//   参数
+//     a: 字符串
+//     b: 字符串
+//   返回
+//     公共前缀的长度
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,148 @@
+// 命名路由及url生成
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// namedRouter 命名路由
+type namedRouter struct {
+	pattern string // 路由请求路径，包含分组前缀
+	regular bool   // 是否是正则路由
+}
+
+// WithName 路由选项，设置路由名称，用于URLFor生成url，名称不能重复
+//   参数
+//     name: 路由名称，如：user.show
+//   返回
+//     路由选项
+func WithName(name string) RouteOption {
+	return func(ri *RouterInfo) {
+		ri.name = name
+	}
+}
+
+// addName 添加命名路由
+//   参数
+//     name:    路由名称，为空时不添加
+//     pattern: 路由请求路径
+//     regular: 是否是正则路由
+//   返回
+//     void
+func (rt *RouterTab) addName(name, pattern string, regular bool) {
+	if name == "" {
+		return
+	}
+	if rt.namedRouters == nil {
+		rt.namedRouters = make(map[string]*namedRouter)
+	}
+	if nr, ok := rt.namedRouters[name]; ok && nr.pattern != pattern {
+		panic(fmt.Sprintf("router: route name [%s] is used by [%s]", name, nr.pattern))
+	}
+
+	rt.namedRouters[name] = &namedRouter{pattern: pattern, regular: regular}
+}
+
+// HasRoute 判断命名路由是否存在
+//   参数
+//     name: 路由名称
+//   返回
+//     存在返回true，否则返回false
+func (rt *RouterTab) HasRoute(name string) bool {
+	_, ok := rt.namedRouters[name]
+	return ok
+}
+
+// URLFor 根据路由名称生成url
+// params为key、value成对的参数，先填充路径里的参数，剩余的参数：
+//   key以"?"开头的拼到查询串里
+//   其它的，路由支持尾部参数(两段及以上的固定路由或自动路由)时按/key/value拼到路径后面，否则拼到查询串里
+// 如：路由/user/:id(int)的名称为user.show，URLFor("user.show", "id", 10, "?tab", "info")返回/user/10?tab=info
+//   参数
+//     name:   路由名称
+//     params: 参数
+//   返回
+//     成功返回url，失败返回错误信息
+func (rt *RouterTab) URLFor(name string, params ...interface{}) (string, error) {
+	nr, ok := rt.namedRouters[name]
+	if !ok {
+		return "", fmt.Errorf("router: route name [%s] is unknown", name)
+	}
+	if nr.regular {
+		return "", fmt.Errorf("router: route [%s] is regular, can not build url", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("router: route [%s] params must be key value pairs", name)
+	}
+
+	// 参数
+	keys := make([]string, 0, len(params)/2)
+	vals := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key := fmt.Sprint(params[i])
+		if _, ok := vals[key]; !ok {
+			keys = append(keys, key)
+		}
+		vals[key] = fmt.Sprint(params[i+1])
+	}
+
+	// 路径
+	segs, err := parseSegs(nr.pattern)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(strings.Trim(nr.pattern, "/"), "/")
+	hasParam := false
+	for i, seg := range segs {
+		if seg.name == "" {
+			parts[i] = url.PathEscape(parts[i])
+			continue
+		}
+
+		hasParam = true
+		val, ok := vals[seg.name]
+		if !ok {
+			return "", fmt.Errorf("router: route [%s] param [%s] is missing", name, seg.name)
+		}
+		delete(vals, seg.name)
+		if seg.wild {
+			list := strings.Split(strings.Trim(val, "/"), "/")
+			for j := range list {
+				list[j] = url.PathEscape(list[j])
+			}
+			parts[i] = strings.Join(list, "/")
+			continue
+		}
+		if !checkParamType(seg.typ, val) {
+			return "", fmt.Errorf("router: route [%s] param [%s] value [%s] is not %s", name, seg.name, val, seg.typ)
+		}
+		parts[i] = url.PathEscape(val)
+	}
+	urlStr := "/" + strings.Join(parts, "/")
+
+	// 尾部参数和查询串
+	useTail := !hasParam && len(segs) >= 2
+	query := url.Values{}
+	for _, key := range keys {
+		val, ok := vals[key]
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(key, "?") {
+			query.Add(key[1:], val)
+		} else if useTail {
+			urlStr += "/" + url.PathEscape(key) + "/" + url.PathEscape(val)
+		} else {
+			query.Add(key, val)
+		}
+	}
+	if len(query) > 0 {
+		urlStr += "?" + query.Encode()
+	}
+
+	return urlStr, nil
+}
@@ -94,6 +94,8 @@ func (srv *WebHttp) ListenAndServe() error {
 		srv.endRunning <- true
 	}() // 启动服务
 	<-srv.endRunning
+	onShutdown()
+	time.Sleep(healthDrainDelay(AppCfg.HealthCfg))
 
 	// 关闭老程序
 	pid := os.Getpid()
@@ -120,6 +122,8 @@ func (srv *WebHttp) ListenAndServeTLS(certFile, keyFile string) error {
 		srv.endRunning <- true
 	}() // 启动服务
 	<-srv.endRunning
+	onShutdown()
+	time.Sleep(healthDrainDelay(AppCfg.HealthCfg))
 
 	// 关闭老程序
 	pid := os.Getpid()
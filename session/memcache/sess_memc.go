@@ -198,6 +198,28 @@ func (p *MemcProvider) GetSessData(id string) (session.SessData, error) {
 	return sessData, nil
 }
 
+// Get return the SessData of an existing ID from memcache, nil if it doesn't exist.
+func (p *MemcProvider) Get(id string) (session.SessData, error) {
+	if cliMemc == nil {
+		return nil, errors.New("session: memcache client is nil")
+	}
+
+	item, err := cliMemc.Get(id)
+	if err == memcache.ErrCacheMiss {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	if len(item.Value) > 0 {
+		if err = utils.GobDecode(item.Value, &values); err != nil {
+			return nil, err
+		}
+	}
+	return &MemcData{id: id, lifeTime: p.lifeTime, values: values}, nil
+}
+
 // Destroy destroy SessData by Id.
 func (p *MemcProvider) Destroy(id string) error {
 	p.lock.Lock()
@@ -215,6 +237,14 @@ func (p *MemcProvider) Gc() {
 	return
 }
 
+// Ping check the memcache servers are reachable.
+func (p *MemcProvider) Ping() error {
+	if cliMemc == nil {
+		return fmt.Errorf("session: Memcache isn't initialized")
+	}
+	return cliMemc.Ping()
+}
+
 var memcProvider = &MemcProvider{curId: "", sessDatas: make(map[string]MemcData)}
 
 // init register a memcache session provider.
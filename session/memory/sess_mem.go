@@ -126,6 +126,16 @@ func (p *MemProvider) GetSessData(id string) (session.SessData, error) {
 	return sessData, nil
 }
 
+// Get return the SessData of an existing ID, nil if it doesn't exist.
+func (p *MemProvider) Get(id string) (session.SessData, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if sessData, ok := p.sessDatas[id]; ok {
+		return &sessData, nil
+	}
+	return nil, nil
+}
+
 // Destroy destroy SessData by Id.
 func (p *MemProvider) Destroy(id string) error {
 	p.lock.Lock()
@@ -138,6 +148,13 @@ func (p *MemProvider) Destroy(id string) error {
 	return nil
 }
 
+// Count return the number of live sessions.
+func (p *MemProvider) Count() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return len(p.sessDatas)
+}
+
 // Gc clear expired sessions.
 func (p *MemProvider) Gc() {
 	p.lock.RLock()
@@ -2,8 +2,12 @@
 package memory
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/lixy529/bingo/session"
 )
 
 // TestMemData test MemData.
@@ -148,3 +152,50 @@ func TestMemProvider(t *testing.T) {
 		return
 	}
 }
+
+// TestMemProviderGet test MemProvider.Get doesn't create sessions.
+func TestMemProviderGet(t *testing.T) {
+	memProvider.Init(7200, "")
+	count := memProvider.Count()
+	data, err := memProvider.Get("not-exist")
+	if err != nil || data != nil {
+		t.Errorf("memProvider.Get() failed. Got %v %v, expected nil.", data, err)
+		return
+	}
+	if memProvider.Count() != count {
+		t.Errorf("memProvider.Get() created a session. Got %d, expected %d.", memProvider.Count(), count)
+		return
+	}
+
+	data, _ = memProvider.GetSessData("get-exist")
+	data.Set("k1", "v1")
+	data, err = memProvider.Get("get-exist")
+	if err != nil || data == nil || data.Get("k1") != "v1" {
+		t.Errorf("memProvider.Get() failed. Got %v %v, expected v1.", data, err)
+	}
+	memProvider.Destroy("get-exist")
+}
+
+// TestManagerStarted test Stats.Started only counts new Session Ids.
+func TestManagerStarted(t *testing.T) {
+	mgr, err := session.NewManager("memory", "", "", 3600)
+	if err != nil {
+		t.Fatalf("session.NewManager() failed. err: %s", err.Error())
+	}
+
+	w := httptest.NewRecorder()
+	data, _ := mgr.SessStart(w, httptest.NewRequest("GET", "/", nil))
+	defer memProvider.Destroy(data.Id())
+	if n := mgr.Stats().Started; n != 1 {
+		t.Errorf("mgr.Stats().Started failed. Got %d, expected 1.", n)
+	}
+
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: "GOSESSIONID", Value: data.Id()})
+		mgr.SessStart(httptest.NewRecorder(), r)
+	}
+	if n := mgr.Stats().Started; n != 1 {
+		t.Errorf("mgr.Stats().Started with cookie failed. Got %d, expected 1.", n)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/lixy529/gotools/utils"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
@@ -27,6 +28,28 @@ type Provider interface {
 	Gc()                                              // Clear expired sessions.
 }
 
+// Counter is implemented by providers that can count their live sessions, eg: memory.
+type Counter interface {
+	Count() int
+}
+
+// Getter is implemented by providers that can look up a session without creating it, eg: memory, memcache.
+type Getter interface {
+	Get(id string) (SessData, error) // Return the SessData of an existing session, nil if it doesn't exist.
+}
+
+// Pinger is implemented by providers that can check their backend is reachable, eg: memcache.
+type Pinger interface {
+	Ping() error
+}
+
+// Stats session statistics of a manager.
+type Stats struct {
+	Started   int64 // Number of new Session Ids created by SessStart.
+	Destroyed int64 // Number of SessDestroy calls.
+	Active    int   // Number of live sessions, -1 if the provider can't count them.
+}
+
 var providers = make(map[string]Provider)
 
 // Register register a provider.
@@ -42,6 +65,8 @@ func Register(name string, provide Provider) {
 
 // Manager session manager.
 type Manager struct {
+	started        int64 // Number of new Session Ids created by SessStart, updated atomically.
+	destroyed      int64 // Number of SessDestroy calls, updated atomically.
 	provider       Provider
 	lifeTime       int64
 	providerConfig string
@@ -80,13 +105,13 @@ func NewManager(providerName, providerConfig, cookieName string, lifeTime int64)
 	}, nil
 }
 
-// createSessId return a Session Id.
+// createSessId return a Session Id and whether it is newly created.
 // Get Session Id from cookie.
 // If the cookie hasn't Session Id, create a Session Id and saved in the cookie.
-func (m *Manager) createSessId(w http.ResponseWriter, r *http.Request) string {
+func (m *Manager) createSessId(w http.ResponseWriter, r *http.Request) (string, bool) {
 	cookie, err := r.Cookie(m.cookieName)
 	if err == nil && cookie.Value != "" {
-		return cookie.Value
+		return cookie.Value, false
 	}
 
 	// Create Session Id.
@@ -107,7 +132,7 @@ func (m *Manager) createSessId(w http.ResponseWriter, r *http.Request) string {
 	}
 	http.SetCookie(w, cookie)
 
-	return sessId
+	return sessId, true
 
 }
 
@@ -115,7 +140,7 @@ func (m *Manager) createSessId(w http.ResponseWriter, r *http.Request) string {
 // 1) create sessionId.
 // 2) return provider.
 func (m *Manager) SessStart(w http.ResponseWriter, r *http.Request) (SessData, error) {
-	sessId := m.createSessId(w, r)
+	sessId, isNew := m.createSessId(w, r)
 	if len(sessId) == 0 {
 		return nil, errors.New("session: create session id error")
 	}
@@ -124,6 +149,9 @@ func (m *Manager) SessStart(w http.ResponseWriter, r *http.Request) (SessData, e
 	if err != nil {
 		return nil, err
 	}
+	if isNew {
+		atomic.AddInt64(&m.started, 1)
+	}
 
 	return sessData, nil
 }
@@ -134,6 +162,15 @@ func (m *Manager) GetSessData(id string) (sessions SessData, err error) {
 	return sessData, err
 }
 
+// Get return the Session of an existing ID, never creates one.
+// Return nil if the session doesn't exist or the provider can't look it up without creating it.
+func (m *Manager) Get(id string) (SessData, error) {
+	if g, ok := m.provider.(Getter); ok && id != "" {
+		return g.Get(id)
+	}
+	return nil, nil
+}
+
 // SessDestroy destroy Session
 func (m *Manager) SessDestroy(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie(m.cookieName)
@@ -146,6 +183,7 @@ func (m *Manager) SessDestroy(w http.ResponseWriter, r *http.Request) {
 	if m.provider != nil {
 		m.provider.Destroy(id)
 	}
+	atomic.AddInt64(&m.destroyed, 1)
 
 	// clear cookie.
 	doMain := utils.GetTopDomain(r.Host)
@@ -169,3 +207,24 @@ func (m *Manager) SessGc() {
 	m.provider.Gc()
 	time.AfterFunc(time.Duration(m.lifeTime)*time.Second, func() { m.SessGc() })
 }
+
+// Stats return session statistics.
+func (m *Manager) Stats() Stats {
+	stats := Stats{
+		Started:   atomic.LoadInt64(&m.started),
+		Destroyed: atomic.LoadInt64(&m.destroyed),
+		Active:    -1,
+	}
+	if c, ok := m.provider.(Counter); ok {
+		stats.Active = c.Count()
+	}
+	return stats
+}
+
+// Ping check the session store is reachable, nil if the provider can't check it.
+func (m *Manager) Ping() error {
+	if p, ok := m.provider.(Pinger); ok {
+		return p.Ping()
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+// 静态文件
+// 支持按目录设置Cache-Control、强ETag、预压缩文件(.br/.gz)、动态压缩、SPA回退，禁止通过软链接访问AppRoot以外的文件
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// staticGzipMax 动态压缩的静态文件的最大长度，更大的文件建议使用预压缩文件
+const staticGzipMax = 1 << 20 // 1M
+
+// staticEncodings 预压缩文件，按优先级排列
+var staticEncodings = []struct {
+	name string // Content-Encoding
+	ext  string // 文件扩展名
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// staticEtags 静态文件的ETag缓存，key为文件路径
+var staticEtags sync.Map
+
+// staticEtag 静态文件的ETag，文件修改时间或大小变化时重新计算
+type staticEtag struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+// staticRouter 静态路由
+//   参数
+//     w:       ResponseWriter对象
+//     r:       Request对象
+//     urlPath: 请求路径
+//   返回
+//     匹配成功返回true，否则返回false
+func (rt *RouterTab) staticRouter(w http.ResponseWriter, r *http.Request, urlPath string) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	fsys := appFS()
+	name, isMatch := rt.checkStaticFile(fsys, urlPath)
+	if !isMatch {
+		return false
+	}
+
+	serveStatic(w, r, fsys, name, matchPrefix(AppCfg.WebCfg.StaticCache, urlPath))
+	return true
+}
+
+// checkStaticFile 验证静态文件
+// 目录在static_index打开时使用目录下的index.html，文件不存在或不在AppRoot下时不匹配
+//   参数
+//     fsys:    应用文件系统
+//     urlPath: 请求路径
+//   返回
+//     文件在应用文件系统里的路径、是否匹配路由
+func (rt *RouterTab) checkStaticFile(fsys fs.FS, urlPath string) (string, bool) {
+	requestPath := path.Clean("/" + urlPath)
+	name := requestPath[1:]
+
+	// favicon.ico、robots.txt文件单独处理
+	if requestPath == "/favicon.ico" || requestPath == "/robots.txt" {
+		return staticFile(fsys, name)
+	}
+
+	// 静态文件
+	for _, staticDir := range AppCfg.WebCfg.StaticDir {
+		staticDir = strings.TrimRight(strings.TrimSpace(staticDir), "/")
+		if staticDir == "" || (requestPath != staticDir && !strings.HasPrefix(requestPath, staticDir+"/")) {
+			continue
+		}
+
+		if fi, err := fs.Stat(fsys, name); err == nil && fi.IsDir() {
+			// 如果是文件夹，拼上index.html
+			if !AppCfg.WebCfg.StaticIndex {
+				return "", false
+			}
+			name = path.Join(name, "index.html")
+		}
+		return staticFile(fsys, name)
+	}
+
+	return "", false
+}
+
+// spaRouter SPA回退，请求路径在static_spa设置的前缀下并且没有匹配到路由时，输出前缀对应的入口文件
+// 最后一段带扩展名的请求路径不回退，缺失的资源文件仍然返回404
+//   参数
+//     w:       ResponseWriter对象
+//     r:       Request对象
+//     urlPath: 请求路径
+//   返回
+//     已输出入口文件返回true，否则返回false
+func (rt *RouterTab) spaRouter(w http.ResponseWriter, r *http.Request, urlPath string) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	if path.Ext(urlPath) != "" {
+		return false
+	}
+
+	index := matchPrefix(AppCfg.WebCfg.StaticSpa, urlPath)
+	if index == "" {
+		return false
+	}
+	fsys := appFS()
+	name, ok := staticFile(fsys, path.Clean("/" + index)[1:])
+	if !ok {
+		return false
+	}
+
+	// 入口文件不缓存，发布后立即生效
+	serveStatic(w, r, fsys, name, "no-cache")
+	return true
+}
+
+// serveStatic 输出静态文件
+// 优先输出客户端支持的预压缩文件，没有时按gzip配置动态压缩，支持If-None-Match、If-Modified-Since、Range
+//   参数
+//     w:            ResponseWriter对象
+//     r:            Request对象
+//     fsys:         应用文件系统
+//     name:         文件路径
+//     cacheControl: Cache-Control头，为空时不设置
+//   返回
+//     void
+func serveStatic(w http.ResponseWriter, r *http.Request, fsys fs.FS, name, cacheControl string) {
+	header := w.Header()
+	if cacheControl != "" {
+		header.Set("Cache-Control", cacheControl)
+	}
+	ctype := mime.TypeByExtension(path.Ext(name))
+	acceptEncoding := r.Header.Get("Accept-Encoding")
+
+	// 预压缩文件
+	served, encoding, vary := name, "", false
+	for _, enc := range staticEncodings {
+		if _, ok := staticFile(fsys, name+enc.ext); !ok {
+			continue
+		}
+		vary = true
+		if acceptsEncoding(acceptEncoding, enc.name) {
+			served, encoding = name+enc.ext, enc.name
+			break
+		}
+	}
+
+	f, err := fsys.Open(served)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		// 文件系统不支持Seek时读到内存
+		b, err := ioutil.ReadAll(f)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+	etag, err := fileEtag(served, content, fi)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if encoding == "" && canGzipStatic(r, ctype, fi.Size()) {
+		vary = true
+		if b, enc, ok := gzipStatic(content, acceptEncoding); ok {
+			content, encoding = bytes.NewReader(b), enc
+			etag = etag[:len(etag)-1] + "-" + enc + `"`
+		}
+	}
+
+	if vary {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	if encoding != "" {
+		// 压缩后的内容无法探测类型
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		header.Set("Content-Encoding", encoding)
+	}
+	if ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
+	header.Set("ETag", etag)
+	http.ServeContent(w, r, path.Base(name), fi.ModTime(), content)
+}
+
+// staticFile 检查文件是否是可以访问的普通文件
+// AppRoot下的文件去掉软链接后必须还在AppRoot下，见rootFS
+//   参数
+//     fsys: 应用文件系统
+//     name: 文件路径
+//   返回
+//     文件路径、是否可以访问
+func staticFile(fsys fs.FS, name string) (string, bool) {
+	fi, err := fs.Stat(fsys, name)
+	if err != nil || !fi.Mode().IsRegular() {
+		return "", false
+	}
+	return name, true
+}
+
+// fileEtag 返回文件的强ETag，按内容计算，文件没有变化时使用缓存
+//   参数
+//     name: 文件路径
+//     f:    打开的文件，计算后会重新定位到开头
+//     fi:   文件信息
+//   返回
+//     ETag，如："9f86d081884c7d65"，失败返回错误信息
+func fileEtag(name string, f io.ReadSeeker, fi fs.FileInfo) (string, error) {
+	if v, ok := staticEtags.Load(name); ok {
+		e := v.(*staticEtag)
+		if e.modTime.Equal(fi.ModTime()) && e.size == fi.Size() {
+			return e.etag, nil
+		}
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	staticEtags.Store(name, &staticEtag{modTime: fi.ModTime(), size: fi.Size(), etag: etag})
+	return etag, nil
+}
+
+// canGzipStatic 判断静态文件是否可以动态压缩
+// 需要打开gzip配置，文本类型，长度在gzip_min和staticGzipMax之间，并且不是Range请求
+//   参数
+//     r:     Request对象
+//     ctype: 文件类型
+//     size:  文件长度
+//   返回
+//     可以返回true，否则返回false
+func canGzipStatic(r *http.Request, ctype string, size int64) bool {
+	if !AppCfg.ServerCfg.GzipStatus || r.Header.Get("Range") != "" {
+		return false
+	}
+	if size < int64(gzipMinLen) || size > staticGzipMax {
+		return false
+	}
+
+	ctype = strings.ToLower(ctype)
+	return strings.HasPrefix(ctype, "text/") || strings.Contains(ctype, "javascript") ||
+		strings.Contains(ctype, "json") || strings.Contains(ctype, "xml") || strings.Contains(ctype, "svg")
+}
+
+// gzipStatic 用compress.go动态压缩静态文件
+//   参数
+//     f:              文件
+//     acceptEncoding: 请求的Accept-Encoding
+//   返回
+//     压缩后的内容、压缩格式、是否压缩
+func gzipStatic(f io.Reader, acceptEncoding string) ([]byte, string, bool) {
+	encoding := RspEncoding(acceptEncoding)
+	if encoding == "" {
+		return nil, "", false
+	}
+
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, "", false
+	}
+	buf := &bytes.Buffer{}
+	ok, encoding, err := Compress(encoding, buf, content)
+	if err != nil || !ok {
+		return nil, "", false
+	}
+	return buf.Bytes(), encoding, true
+}
+
+// acceptsEncoding 判断Accept-Encoding是否接受指定的压缩格式
+//   参数
+//     acceptEncoding: 请求的Accept-Encoding，如：gzip, deflate, br;q=0.8
+//     name:           压缩格式，如：br
+//   返回
+//     接受返回true，否则返回false
+func acceptsEncoding(acceptEncoding, name string) bool {
+	for _, v := range strings.Split(acceptEncoding, ",") {
+		vs := strings.Split(strings.TrimSpace(v), ";")
+		if token := strings.ToLower(strings.TrimSpace(vs[0])); token != name && token != "*" {
+			continue
+		}
+		if len(vs) > 1 {
+			q := strings.TrimSpace(vs[1])
+			if strings.HasPrefix(q, "q=") {
+				if f, err := strconv.ParseFloat(q[2:], 64); err == nil && f == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// matchPrefix 按最长路径前缀查找配置
+//   参数
+//     m:       配置，key为路径前缀，如：/static
+//     urlPath: 请求路径
+//   返回
+//     前缀对应的值，没有时返回空
+func matchPrefix(m map[string]string, urlPath string) string {
+	best, val := -1, ""
+	for prefix, v := range m {
+		p := strings.TrimRight(prefix, "/")
+		if urlPath != p && !strings.HasPrefix(urlPath, p+"/") {
+			continue
+		}
+		if len(p) > best {
+			best, val = len(p), v
+		}
+	}
+	return val
+}
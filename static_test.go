@@ -0,0 +1,144 @@
+package bingo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStaticRouter 测试静态文件
+func TestStaticRouter(t *testing.T) {
+	appRoot := AppRoot
+	webCfg := AppCfg.WebCfg
+	gzipStatus := AppCfg.ServerCfg.GzipStatus
+	defer func() {
+		AppRoot = appRoot
+		AppCfg.WebCfg = webCfg
+		AppCfg.ServerCfg.GzipStatus = gzipStatus
+	}()
+
+	root, err := ioutil.TempDir("", "approot")
+	if err != nil {
+		t.Fatalf("TempDir failed, err: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+	outside, err := ioutil.TempDir("", "outside")
+	if err != nil {
+		t.Fatalf("TempDir failed, err: %s", err.Error())
+	}
+	defer os.RemoveAll(outside)
+
+	os.MkdirAll(filepath.Join(root, "static", "css"), 0755)
+	os.MkdirAll(filepath.Join(root, "static", "app"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "static", "index.html"), []byte("index"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "static", "css", "a.css"), []byte("body{}"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "static", "css", "a.css.gz"), gzipBytes(t, "body{}"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "static", "app", "index.html"), []byte("spa"), 0644)
+	ioutil.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+	if err = os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "static", "secret.txt")); err != nil {
+		t.Fatalf("Symlink failed, err: %s", err.Error())
+	}
+
+	AppRoot = root
+	AppCfg.ServerCfg.GzipStatus = false
+	AppCfg.WebCfg.StaticDir = []string{"/static"}
+	AppCfg.WebCfg.StaticIndex = true
+	AppCfg.WebCfg.StaticCache = map[string]string{"/static": "public, max-age=60", "/static/css": "max-age=3600"}
+	AppCfg.WebCfg.StaticSpa = map[string]string{"/static/app": "static/app/index.html"}
+
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+
+	serve := func(url string, header map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", url, nil)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		rt.ServeHTTP(w, r)
+		return w
+	}
+
+	// 目录和Cache-Control
+	w := serve("/static/", nil)
+	if w.Code != 200 || w.Body.String() != "index" || w.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Errorf("index failed, code: %d, body: %s, header: %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	// 预压缩文件
+	w = serve("/static/css/a.css", map[string]string{"Accept-Encoding": "br;q=0, gzip"})
+	if w.Code != 200 || w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" ||
+		!strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") || w.Header().Get("Cache-Control") != "max-age=3600" {
+		t.Errorf("precompressed failed, code: %d, header: %v", w.Code, w.Header())
+	}
+	w = serve("/static/css/a.css", nil)
+	if w.Body.String() != "body{}" || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("identity failed, body: %s, header: %v", w.Body.String(), w.Header())
+	}
+
+	// 强ETag
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) {
+		t.Errorf("etag failed, etag: %s", etag)
+	}
+	w = serve("/static/css/a.css", map[string]string{"If-None-Match": etag})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("if-none-match failed, code: %d", w.Code)
+	}
+
+	// 软链接到AppRoot以外
+	if w = serve("/static/secret.txt", nil); w.Code != 404 {
+		t.Errorf("symlink failed, code: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	// SPA回退
+	if w = serve("/static/app/user/1", nil); w.Code != 200 || w.Body.String() != "spa" || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("spa failed, code: %d, body: %s", w.Code, w.Body.String())
+	}
+	if w = serve("/static/app/none.js", nil); w.Code != 404 {
+		t.Errorf("spa asset failed, code: %d", w.Code)
+	}
+
+	// 关闭index.html
+	AppCfg.WebCfg.StaticIndex = false
+	if w = serve("/static/", nil); w.Code != 404 {
+		t.Errorf("index off failed, code: %d", w.Code)
+	}
+}
+
+// TestAcceptsEncoding 测试Accept-Encoding解析
+func TestAcceptsEncoding(t *testing.T) {
+	cases := []struct {
+		accept string
+		name   string
+		ok     bool
+	}{
+		{"gzip, deflate, br", "br", true},
+		{"gzip;q=0.5", "gzip", true},
+		{"gzip;q=0", "gzip", false},
+		{"*", "br", true},
+		{"deflate", "gzip", false},
+		{"", "gzip", false},
+	}
+	for _, c := range cases {
+		if ok := acceptsEncoding(c.accept, c.name); ok != c.ok {
+			t.Errorf("acceptsEncoding(%q, %q) = %v, want %v", c.accept, c.name, ok, c.ok)
+		}
+	}
+}
+
+func gzipBytes(t *testing.T, s string) []byte {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	zw.Write([]byte(s))
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip failed, err: %s", err.Error())
+	}
+	return buf.Bytes()
+}
@@ -6,12 +6,12 @@ package bingo
 import (
 	"fmt"
 	"html/template"
-	"io/ioutil"
-	"github.com/lixy529/gotools/utils"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"net/url"
+	"text/template/parse"
 )
 
 var (
@@ -20,6 +20,7 @@ var (
 
 // Template
 type Template struct {
+	fsys      fs.FS // 模板文件系统，为nil时使用磁盘上的viewsDir
 	viewsDir  string
 	viewsExt  string
 	viewFiles []string
@@ -39,6 +40,21 @@ func NewTemplate(viewsDir, viewsExt string) *Template {
 	}
 }
 
+// NewTemplateFS 实例化Template，模板文件从文件系统读取，如：embed.FS
+//   参数
+//     fsys:     模板文件系统
+//     viewsDir: 模板文件在文件系统里的目录，如：views
+//     viewsExt: 模板文件的扩展名
+//   返回
+//     Template对象
+func NewTemplateFS(fsys fs.FS, viewsDir, viewsExt string) *Template {
+	return &Template{
+		fsys:     fsys,
+		viewsDir: viewsDir,
+		viewsExt: viewsExt,
+	}
+}
+
 // buildViews 编译viewsDir下的所有模板文件
 //   参数
 //     void
@@ -49,8 +65,13 @@ func (t *Template) buildViews() error {
 		return fmt.Errorf("Template: Views directory or extension is empty")
 	}
 
-	err := filepath.Walk(t.viewsDir, func(path string, info os.FileInfo, err error) error {
-		t.find(path, info, err)
+	fsys, root := t.fsys, t.viewsDir
+	if fsys == nil {
+		fsys, root = os.DirFS(t.viewsDir), "."
+	}
+
+	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		t.find(fsys, path, err)
 		return nil
 	})
 
@@ -58,19 +79,28 @@ func (t *Template) buildViews() error {
 		return err
 	}
 
-	return t.parseFiles()
+	err = t.parseFiles(fsys, root)
+	if err != nil {
+		return err
+	}
+
+	return t.checkUrlFor()
 }
 
 // find 查找模板文件
 //   参数
+//     fsys: 模板文件系统
 //     path: 模板文件路径
-//     f:    模板文件信息
 //     err:  错误信息
 //   返回
 //     成功返回nil，失败返回错误信息
-func (t *Template) find(path string, f os.FileInfo, err error) error {
-	isFile, err := utils.IsFile(path)
-	if err != nil || !isFile {
+func (t *Template) find(fsys fs.FS, path string, err error) error {
+	if err != nil {
+		return err
+	}
+
+	info, err := fs.Stat(fsys, path)
+	if err != nil || !info.Mode().IsRegular() {
 		return fmt.Errorf("Template: path [%s] is not file", path)
 	}
 
@@ -85,19 +115,23 @@ func (t *Template) find(path string, f os.FileInfo, err error) error {
 
 // ParseFiles 解析所有模板文件
 //   参数
-//     void
+//     fsys: 模板文件系统
+//     root: 模板文件在文件系统里的目录，模板名称是相对这个目录的路径
 //   返回
 //     成功返回nil，失败返回错误信息
-func (t *Template) parseFiles() error {
+func (t *Template) parseFiles(fsys fs.FS, root string) error {
 	for _, filename := range t.viewFiles {
-		b, err := ioutil.ReadFile(filename)
+		b, err := fs.ReadFile(fsys, filename)
 		if err != nil {
 			return err
 		}
 
 		s := string(b)
 		var tmpl *template.Template
-		name := strings.Replace(filename, t.viewsDir, "", 1)[1:]
+		name := filename
+		if root != "." {
+			name = strings.TrimPrefix(filename, root+"/")
+		}
 		if t.ViewTemp == nil {
 			t.ViewTemp = template.New(name)
 
@@ -119,6 +153,85 @@ func (t *Template) parseFiles() error {
 	return nil
 }
 
+// checkUrlFor 检查模板里urlfor引用的路由名称是否存在
+// 只检查路由名称是字符串常量的调用
+//   参数
+//     void
+//   返回
+//     成功返回nil，有未知的路由名称时返回错误信息
+func (t *Template) checkUrlFor() error {
+	if t.ViewTemp == nil {
+		return nil
+	}
+
+	for _, tmpl := range t.ViewTemp.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+		var err error
+		walkTplNode(tmpl.Tree.Root, func(name string) {
+			if err == nil && !Router.HasRoute(name) {
+				err = fmt.Errorf("Template: [%s] urlfor route name [%s] is unknown", tmpl.Name(), name)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkTplNode 遍历模板语法树，找到urlfor调用时把路由名称交给f
+//   参数
+//     node: 模板语法树节点
+//     f:    处理路由名称
+//   返回
+//     void
+func walkTplNode(node parse.Node, f func(string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkTplNode(c, f)
+		}
+	case *parse.ActionNode:
+		walkTplNode(n.Pipe, f)
+	case *parse.IfNode:
+		walkTplNode(&n.BranchNode, f)
+	case *parse.RangeNode:
+		walkTplNode(&n.BranchNode, f)
+	case *parse.WithNode:
+		walkTplNode(&n.BranchNode, f)
+	case *parse.BranchNode:
+		walkTplNode(n.Pipe, f)
+		walkTplNode(n.List, f)
+		walkTplNode(n.ElseList, f)
+	case *parse.TemplateNode:
+		walkTplNode(n.Pipe, f)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			walkTplNode(c, f)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) > 1 {
+			if id, ok := n.Args[0].(*parse.IdentifierNode); ok && id.Ident == "urlfor" {
+				if s, ok := n.Args[1].(*parse.StringNode); ok {
+					f(s.Text)
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			walkTplNode(arg, f)
+		}
+	}
+}
+
 // init 初始化函数
 //   参数
 //     void
@@ -138,4 +251,5 @@ func init() {
 	tplFuncMap["substr"] = Substr
 	tplFuncMap["sum"] = Sum
 	tplFuncMap["lang"] = Lang
+	tplFuncMap["urlfor"] = UrlFor
 }
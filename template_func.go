@@ -94,3 +94,14 @@ func Lang(lang, key string) string {
 
 	return GLang.String(lang, key)
 }
+
+// UrlFor 根据路由名称生成url，参数同RouterTab.URLFor
+// 模板里使用：{{urlfor "user.show" "id" 10}}
+//   参数
+//     name:   路由名称
+//     params: 参数
+//   返回
+//     成功返回url，失败返回错误信息
+func UrlFor(name string, params ...interface{}) (string, error) {
+	return Router.URLFor(name, params...)
+}
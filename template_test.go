@@ -5,7 +5,9 @@ package bingo
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -49,3 +51,29 @@ func TestTemplate(t *testing.T) {
 	tp.ViewTemp.ExecuteTemplate(os.Stdout, "demo/index.html", data)
 	fmt.Println()
 }
+
+// TestTemplateUrlFor 测试模板里urlfor引用未知的路由名称
+func TestTemplateUrlFor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "views")
+	if err != nil {
+		t.Fatalf("TempDir failed, err: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	if !Router.HasRoute("tpl.urlfor") {
+		Router.AddFixed("/tpl/urlfor", &testController{}, "IndexAction", WithName("tpl.urlfor"))
+	}
+	ioutil.WriteFile(filepath.Join(dir, "ok.html"), []byte(`<a href="{{urlfor "tpl.urlfor" "id" 1}}">ok</a>`), 0644)
+	tp := NewTemplate(dir, ".html")
+	if err = tp.buildViews(); err != nil {
+		t.Fatalf("buildViews failed, err: %s", err.Error())
+	}
+	tp.ViewTemp.ExecuteTemplate(os.Stdout, "ok.html", nil)
+	fmt.Println()
+
+	ioutil.WriteFile(filepath.Join(dir, "bad.html"), []byte(`{{if .}}<a href="{{urlfor "tpl.none"}}">bad</a>{{end}}`), 0644)
+	tp = NewTemplate(dir, ".html")
+	if err = tp.buildViews(); err == nil {
+		t.Errorf("buildViews failed. Got nil, expected error.")
+	}
+}
@@ -0,0 +1,219 @@
+// 请求超时处理
+// 超时后Action所在的协程可能还在运行，通过timeoutWriter丢弃超时后的输出，避免与超时应答并发写
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// NoTimeout 不限制请求超时时间，用于流式输出、大文件导出等路由
+const NoTimeout time.Duration = -1
+
+// WithTimeout 路由选项，设置路由的请求超时时间，覆盖分组和全局的设置
+//   参数
+//     timeout: 请求超时时间，如：50 * time.Millisecond，NoTimeout为不限制
+//   返回
+//     路由选项
+func WithTimeout(timeout time.Duration) RouteOption {
+	if timeout <= 0 {
+		timeout = NoTimeout
+	}
+	return func(ri *RouterInfo) {
+		ri.timeout = timeout
+	}
+}
+
+// routeTimeout 返回路由生效的请求超时时间
+// 优先级：路由 => 分组 => 上级分组 => 全局的req_timeout
+//   参数
+//     ri: 路由信息
+//   返回
+//     请求超时时间，NoTimeout为不限制
+func (rt *RouterTab) routeTimeout(ri RouterInfo) time.Duration {
+	if ri.timeout != 0 {
+		return ri.timeout
+	}
+	if timeout := ri.group.getTimeout(); timeout != 0 {
+		return timeout
+	}
+	return rt.reqTimeout * time.Second
+}
+
+// timeoutWriter 支持超时的ResponseWriter
+// Header在第一次输出时才复制到原ResponseWriter，context取消后的Header修改和输出都会丢弃
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	h           http.Header
+	ctx         context.Context
+	mu          sync.Mutex
+	wroteHeader bool // 是否已输出Header
+	status      int  // 输出的状态码
+	timedOut    bool // 是否已超时
+	hijacked    bool // 连接是否已被接管，如：websocket
+}
+
+// newTimeoutWriter 实例化timeoutWriter
+//   参数
+//     w:   原ResponseWriter
+//     ctx: 请求的context，取消后不再输出
+//   返回
+//     timeoutWriter对象地址
+func newTimeoutWriter(w http.ResponseWriter, ctx context.Context) *timeoutWriter {
+	h := make(http.Header)
+	for k, v := range w.Header() {
+		h[k] = v
+	}
+	return &timeoutWriter{w: w, h: h, ctx: ctx}
+}
+
+// Header 返回Header，实现http.ResponseWriter接口
+//   参数
+//     void
+//   返回
+//     Header
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+// Write 输出数据，实现http.ResponseWriter接口
+//   参数
+//     b: 数据
+//   返回
+//     输出的字节数，超时后返回http.ErrHandlerTimeout
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.isTimedOut() {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+// WriteHeader 输出状态码，实现http.ResponseWriter接口
+//   参数
+//     code: 状态码
+//   返回
+//     void
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.isTimedOut() || tw.wroteHeader {
+		return
+	}
+	tw.writeHeader(code)
+}
+
+// writeHeader 复制Header并输出状态码，调用者需加锁
+//   参数
+//     code: 状态码
+//   返回
+//     void
+func (tw *timeoutWriter) writeHeader(code int) {
+	dst := tw.w.Header()
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	tw.wroteHeader = true
+	tw.status = code
+	tw.w.WriteHeader(code)
+}
+
+// statusCode 返回输出的状态码，还没有输出时返回0
+//   参数
+//     void
+//   返回
+//     状态码
+func (tw *timeoutWriter) statusCode() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.status
+}
+
+// Flush 实现http.Flusher接口，超时后不处理
+//   参数
+//     void
+//   返回
+//     void
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.isTimedOut() {
+		return
+	}
+	if f, ok := tw.w.(http.Flusher); ok {
+		if !tw.wroteHeader {
+			tw.writeHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Hijack 实现http.Hijacker接口，接管后不再输出超时应答
+//   参数
+//     void
+//   返回
+//     连接、读写缓冲、错误信息
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.isTimedOut() {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	hj, ok := tw.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("bingo: ResponseWriter does not support Hijacker")
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		tw.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// isTimedOut 判断是否已超时，context已取消也认为超时，调用者需加锁
+//   参数
+//     void
+//   返回
+//     已超时返回true，否则返回false
+func (tw *timeoutWriter) isTimedOut() bool {
+	if !tw.timedOut && tw.ctx.Err() != nil {
+		tw.timedOut = true
+	}
+	return tw.timedOut
+}
+
+// detach Action已结束，之后的输出不再受context影响，用于Action结束后输出结果
+//   参数
+//     void
+//   返回
+//     void
+func (tw *timeoutWriter) detach() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.timedOut {
+		tw.ctx = context.Background()
+	}
+}
+
+// timeout 标记为已超时，之后Action的输出都会丢弃
+//   参数
+//     void
+//   返回
+//     还没有输出过数据时返回true，这时可以输出超时应答
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	return !tw.wroteHeader && !tw.hijacked
+}
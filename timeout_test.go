@@ -0,0 +1,127 @@
+package bingo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// slowController 测试超时的控制器
+type slowController struct {
+	Controller
+}
+
+// slowDone 记录Action被取消后的情况
+var slowDone = make(chan error, 1)
+
+func (c *slowController) SlowAction() {
+	select {
+	case <-c.Context().Done():
+	case <-time.After(5 * time.Second):
+	}
+	c.SetHeader("X-Late", "1")
+	_, err := c.Rsp.GetResponse().Write([]byte("late"))
+	slowDone <- err
+}
+
+// TestRequestTimeout 测试请求超时后取消context并丢弃Action的输出
+func TestRequestTimeout(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(1)
+	rt.AddFixed("/slow", &slowController{}, "SlowAction")
+
+	w := doRequest(rt, "GET", "/slow")
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("timeout status failed. Got %d, expected %d.", w.Code, http.StatusBadGateway)
+	}
+	select {
+	case err := <-slowDone:
+		if err != http.ErrHandlerTimeout {
+			t.Errorf("late write failed. Got %v, expected %v.", err, http.ErrHandlerTimeout)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("context is not cancelled.")
+	}
+	if w.Header().Get("X-Late") != "" || w.Body.String() != "Bad Gateway\n" {
+		t.Errorf("late output is not discarded. Header %v, body %s.", w.Header(), w.Body.String())
+	}
+
+	// 客户端断开连接
+	ctx, cancel := context.WithCancel(context.Background())
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/slow", nil).WithContext(ctx)
+	time.AfterFunc(100*time.Millisecond, cancel)
+	rt.ServeHTTP(w, r)
+	if err := <-slowDone; err != http.ErrHandlerTimeout {
+		t.Errorf("client closed write failed. Got %v, expected %v.", err, http.ErrHandlerTimeout)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("client closed output failed. Got %s, expected empty.", w.Body.String())
+	}
+}
+
+// TestTimeoutWriter 测试已有输出时不再输出超时应答
+func TestTimeoutWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	tw := newTimeoutWriter(w, context.Background())
+	tw.Header().Set("X-Test", "1")
+	if w.Header().Get("X-Test") != "" {
+		t.Errorf("header is written before output.")
+	}
+	tw.Write([]byte("ok"))
+	if w.Header().Get("X-Test") != "1" {
+		t.Errorf("header is not copied.")
+	}
+	if tw.timeout() {
+		t.Errorf("timeout failed. Got true, expected false.")
+	}
+	if _, err := tw.Write([]byte("late")); err != http.ErrHandlerTimeout || w.Body.String() != "ok" {
+		t.Errorf("late write failed. err %v, body %s.", err, w.Body.String())
+	}
+}
+
+func (c *slowController) NapAction() {
+	time.Sleep(300 * time.Millisecond)
+	c.WriteString("nap")
+}
+
+// TestRouteTimeout 测试路由和分组的请求超时时间
+func TestRouteTimeout(t *testing.T) {
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.AddFixed("/fast", &slowController{}, "SlowAction", WithTimeout(100*time.Millisecond))
+	g := rt.Group("/g")
+	g.SetTimeout(100 * time.Millisecond)
+	sub := g.Group("/sub")
+	sub.AddFixed("/slow", &slowController{}, "SlowAction")
+	sub.AddFixed("/nap", &slowController{}, "NapAction", WithTimeout(NoTimeout))
+	rt.Group("/stream").AddFixed("/nap", &slowController{}, "NapAction")
+
+	for _, url := range []string{"/fast", "/g/sub/slow"} {
+		start := time.Now()
+		if w := doRequest(rt, "GET", url); w.Code != http.StatusBadGateway {
+			t.Errorf("%s status failed. Got %d, expected %d.", url, w.Code, http.StatusBadGateway)
+		}
+		<-slowDone
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("%s timeout failed. Got %s.", url, d)
+		}
+	}
+	if w := doRequest(rt, "GET", "/g/sub/nap"); w.Code != http.StatusOK || w.Body.String() != "nap" {
+		t.Errorf("/g/sub/nap failed. Got %d %s, expected 200 nap.", w.Code, w.Body.String())
+	}
+
+	timeouts := map[string]time.Duration{
+		"/fast":       100 * time.Millisecond,
+		"/g/sub/slow": 100 * time.Millisecond,
+		"/g/sub/nap":  NoTimeout,
+		"/stream/nap": 5 * time.Second,
+	}
+	for _, r := range rt.Routes() {
+		if r.Timeout != timeouts[r.Pattern] {
+			t.Errorf("%s Routes timeout failed. Got %s, expected %s.", r.Pattern, r.Timeout, timeouts[r.Pattern])
+		}
+	}
+}
@@ -0,0 +1,889 @@
+// 链路追踪
+// 每个请求一个服务端span，控制器的Prepare/Filter/Action/Finish/Show、Model的数据库和缓存操作、模板渲染、调用其它服务都记录子span
+// Model是业务代码直接创建的，需要用Controller.InitModel或Model.SetContext设置请求的context后才记录span
+// 使用W3C traceparent传递链路，span批量导出到OTLP/HTTP(JSON编码)或本地JSON文件，配置取配置文件的[trace]段
+//   变更历史
+//     2026-10-18  lixiaoya  新建
+package bingo
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	mrand "math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lixy529/gotools/cache"
+)
+
+// span的类型
+const (
+	SpanInternal = "internal" // 框架内部的处理，如：Prepare、模板渲染
+	SpanServer   = "server"   // 处理请求
+	SpanClient   = "client"   // 调用数据库、缓存和其它服务
+)
+
+// span的状态
+const (
+	SpanUnset = ""
+	SpanOk    = "ok"
+	SpanError = "error"
+)
+
+// traceParentHeader W3C链路的Header名称
+const traceParentHeader = "traceparent"
+
+// 导出span的批量大小、队列长度和间隔
+const (
+	traceBatchSize = 512
+	traceQueueSize = 4096
+	traceInterval  = time.Second
+)
+
+// Span 一次操作的耗时和属性，没有开启追踪时为nil，所有方法都可以用nil调用
+type Span struct {
+	TraceId  string                 `json:"trace_id"`
+	SpanId   string                 `json:"span_id"`
+	ParentId string                 `json:"parent_id,omitempty"`
+	Name     string                 `json:"name"`
+	Kind     string                 `json:"kind"`
+	Start    time.Time              `json:"start"`
+	End      time.Time              `json:"end"`
+	Attrs    map[string]interface{} `json:"attrs,omitempty"`
+	Status   string                 `json:"status,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+
+	sampled bool
+	tracer  *Tracer
+	mu      sync.Mutex
+	ended   bool
+}
+
+// SpanExporter 导出结束的span，如：写文件、发送到OTLP collector
+type SpanExporter interface {
+	Export(spans []*Span) error
+}
+
+// Tracer 按采样率创建span，结束的span放到队列里由单独的协程批量导出
+type Tracer struct {
+	sample    float64
+	exporters []SpanExporter
+	queue     chan *Span
+	flush     chan chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	dropped   int64 // 队列满时丢弃的span数
+}
+
+// traceHolder 当前的Tracer，atomic.Value不能存nil
+type traceHolder struct {
+	t *Tracer
+}
+
+var curTracer atomic.Value
+
+// spanKey span在context里的key
+type spanKey struct{}
+
+// NewTracer 实例化Tracer，并启动导出协程
+//   参数
+//     sample:    没有上游链路时的采样率，0~1，上游链路带了采样标记时按上游的
+//     exporters: 导出span的对象
+//   返回
+//     Tracer对象
+func NewTracer(sample float64, exporters ...SpanExporter) *Tracer {
+	if sample < 0 {
+		sample = 0
+	} else if sample > 1 {
+		sample = 1
+	}
+	t := &Tracer{
+		sample:    sample,
+		exporters: exporters,
+		queue:     make(chan *Span, traceQueueSize),
+		flush:     make(chan chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go t.loop()
+	return t
+}
+
+// SetTracer 设置全局的Tracer，为nil时关闭追踪
+//   参数
+//     t: Tracer对象
+//   返回
+//     void
+func SetTracer(t *Tracer) {
+	curTracer.Store(traceHolder{t})
+}
+
+// GetTracer 返回全局的Tracer
+//   参数
+//     void
+//   返回
+//     Tracer对象，没有开启追踪时为nil
+func GetTracer() *Tracer {
+	h, _ := curTracer.Load().(traceHolder)
+	return h.t
+}
+
+// loop 批量导出span，达到批量大小或间隔时间到时导出
+//   参数
+//     void
+//   返回
+//     void
+func (t *Tracer) loop() {
+	ticker := time.NewTicker(traceInterval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, traceBatchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		for _, e := range t.exporters {
+			if err := e.Export(batch); err != nil && Flogger != nil {
+				Flogger.Errorf("trace: export err [%s]", err.Error())
+			}
+		}
+		batch = make([]*Span, 0, traceBatchSize)
+	}
+
+	for {
+		select {
+		case s := <-t.queue:
+			batch = append(batch, s)
+			if len(batch) >= traceBatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ch := <-t.flush:
+			for n := len(t.queue); n > 0; n-- {
+				batch = append(batch, <-t.queue)
+			}
+			export()
+			close(ch)
+		case <-t.done:
+			for n := len(t.queue); n > 0; n-- {
+				batch = append(batch, <-t.queue)
+			}
+			export()
+			return
+		}
+	}
+}
+
+// Flush 导出队列里所有的span
+//   参数
+//     void
+//   返回
+//     void
+func (t *Tracer) Flush() {
+	ch := make(chan struct{})
+	select {
+	case t.flush <- ch:
+		<-ch
+	case <-t.done:
+	}
+}
+
+// Close 导出队列里所有的span并停止导出协程
+//   参数
+//     void
+//   返回
+//     void
+func (t *Tracer) Close() {
+	t.closeOnce.Do(func() {
+		t.Flush()
+		close(t.done)
+	})
+}
+
+// Dropped 返回队列满时丢弃的span数
+//   参数
+//     void
+//   返回
+//     丢弃的span数
+func (t *Tracer) Dropped() int64 {
+	return atomic.LoadInt64(&t.dropped)
+}
+
+// newSpan 创建span，traceId为空时新建链路
+//   参数
+//     name:     span名称
+//     kind:     span类型
+//     traceId:  所属链路的trace id，新建链路时为空
+//     parentId: 上级span的span id
+//     sampled:  是否采样
+//   返回
+//     Span对象
+func (t *Tracer) newSpan(name, kind, traceId, parentId string, sampled bool) *Span {
+	if traceId == "" {
+		traceId = randHex(16)
+	}
+	return &Span{
+		TraceId:  traceId,
+		SpanId:   randHex(8),
+		ParentId: parentId,
+		Name:     name,
+		Kind:     kind,
+		Start:    time.Now(),
+		sampled:  sampled,
+		tracer:   t,
+	}
+}
+
+// StartSpan 在ctx里的span下创建子span，ctx里没有span时不记录
+// 如：ctx, span := bingo.StartSpan(c.Context(), "rpc.GetUser")
+//     defer span.Finish()
+//   参数
+//     ctx:  context，如：Controller.Context()
+//     name: span名称
+//   返回
+//     带子span的context、子span，不记录时返回原ctx和nil
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return startSpan(ctx, name, SpanInternal)
+}
+
+// startSpan 在ctx里的span下创建指定类型的子span
+//   参数
+//     ctx:  context
+//     name: span名称
+//     kind: span类型
+//   返回
+//     带子span的context、子span，不记录时返回原ctx和nil
+func startSpan(ctx context.Context, name, kind string) (context.Context, *Span) {
+	if ctx == nil {
+		return ctx, nil
+	}
+	parent := SpanFromContext(ctx)
+	if parent == nil || parent.tracer == nil {
+		return ctx, nil
+	}
+	s := parent.tracer.newSpan(name, kind, parent.TraceId, parent.SpanId, parent.sampled)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// SpanFromContext 返回ctx里的span
+//   参数
+//     ctx: context
+//   返回
+//     Span对象，没有时返回nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SetAttr 设置属性，值为string、bool、整数或浮点数
+//   参数
+//     key: 属性名，如：db.statement
+//     val: 属性值
+//   返回
+//     void
+func (s *Span) SetAttr(key string, val interface{}) {
+	if s == nil || !s.sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Attrs == nil {
+		s.Attrs = make(map[string]interface{})
+	}
+	s.Attrs[key] = val
+}
+
+// SetName 修改span名称，如：匹配到路由后使用路由请求路径
+//   参数
+//     name: span名称
+//   返回
+//     void
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.Name = name
+	s.mu.Unlock()
+}
+
+// SetError 设置错误，err为nil时设为成功
+//   参数
+//     err: 错误信息
+//   返回
+//     void
+func (s *Span) SetError(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.Status = SpanOk
+		return
+	}
+	s.Status, s.Error = SpanError, err.Error()
+}
+
+// Finish 结束span，多次调用只记录第一次，采样的span放到导出队列，队列满时丢弃
+//   参数
+//     void
+//   返回
+//     void
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.mu.Unlock()
+
+	if !s.sampled || s.tracer == nil {
+		return
+	}
+	select {
+	case s.tracer.queue <- s:
+	default:
+		atomic.AddInt64(&s.tracer.dropped, 1)
+	}
+}
+
+// end 按操作结果设置状态后结束span
+//   参数
+//     err: 操作的错误信息
+//   返回
+//     void
+func (s *Span) end(err error) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		s.SetError(err)
+	}
+	s.Finish()
+}
+
+// TraceParent 返回W3C traceparent，用于调用其它服务时传递链路
+//   参数
+//     void
+//   返回
+//     如：00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01，span为nil时返回空
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return "00-" + s.TraceId + "-" + s.SpanId + "-" + flags
+}
+
+// parseTraceParent 解析W3C traceparent
+//   参数
+//     v: Header值
+//   返回
+//     trace id、span id、是否采样、是否合法
+func parseTraceParent(v string) (string, string, bool, bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return "", "", false, false
+	}
+	traceId, spanId, flags := parts[1], parts[2], parts[3]
+	if len(traceId) != 32 || len(spanId) != 16 || len(flags) != 2 ||
+		!isLowerHex(parts[0]) || !isLowerHex(traceId) || !isLowerHex(spanId) || !isLowerHex(flags) ||
+		traceId == strings.Repeat("0", 32) || spanId == strings.Repeat("0", 16) {
+		return "", "", false, false
+	}
+	f, _ := strconv.ParseUint(flags, 16, 8)
+	return traceId, spanId, f&1 == 1, true
+}
+
+// isLowerHex 判断是否为小写的十六进制字符串
+//   参数
+//     s: 字符串
+//   返回
+//     是返回true，否则返回false
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// randHex 生成n个字节的随机十六进制字符串
+//   参数
+//     n: 字节数
+//   返回
+//     十六进制字符串
+func randHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		for i := 0; i < n; i += 8 {
+			var u [8]byte
+			binary.BigEndian.PutUint64(u[:], mrand.Uint64())
+			copy(b[i:], u[:])
+		}
+	}
+	return hex.EncodeToString(b)
+}
+
+// startServerSpan 请求开始时创建服务端span，请求带了traceparent时沿用上游链路
+//   参数
+//     r: Request对象
+//   返回
+//     带span的Request对象、span，没有开启追踪时为nil
+func startServerSpan(r *http.Request) (*http.Request, *Span) {
+	t := GetTracer()
+	if t == nil {
+		return r, nil
+	}
+
+	traceId, parentId, sampled, ok := parseTraceParent(r.Header.Get(traceParentHeader))
+	if !ok {
+		traceId, parentId = "", ""
+		sampled = t.sample >= 1 || (t.sample > 0 && mrand.Float64() < t.sample)
+	}
+	s := t.newSpan("HTTP "+r.Method, SpanServer, traceId, parentId, sampled)
+	s.SetAttr("http.method", r.Method)
+	s.SetAttr("http.target", r.URL.RequestURI())
+	s.SetAttr("http.host", r.Host)
+	s.SetAttr("http.user_agent", r.UserAgent())
+	s.SetAttr("request_id", RequestId(r))
+	return r.WithContext(context.WithValue(r.Context(), spanKey{}, s)), s
+}
+
+// finishServerSpan 请求结束时记录状态码和路由，结束服务端span
+//   参数
+//     aw: accessWriter对象
+//     r:  Request对象
+//     s:  服务端span
+//   返回
+//     void
+func finishServerSpan(aw *accessWriter, r *http.Request, s *Span) {
+	if s == nil {
+		return
+	}
+	status := aw.finalStatus(r)
+	if ri := aw.route; ri != nil {
+		s.SetName("HTTP " + r.Method + " " + ri.pattern)
+		s.SetAttr("http.route", ri.pattern)
+		s.SetAttr("controller", ri.desc())
+	}
+	s.SetAttr("http.status_code", status)
+	if status >= 500 {
+		s.SetError(fmt.Errorf("status %d", status))
+	}
+	s.Finish()
+}
+
+// traceStep 在span里执行一步处理，发生panic时记录错误后继续panic
+//   参数
+//     ctx:  context
+//     name: span名称
+//     f:    处理函数
+//   返回
+//     void
+func traceStep(ctx context.Context, name string, f func()) {
+	_, s := startSpan(ctx, name, SpanInternal)
+	if s == nil {
+		f()
+		return
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			s.SetError(fmt.Errorf("panic: %v", err))
+			s.Finish()
+			panic(err)
+		}
+		s.Finish()
+	}()
+	f()
+}
+
+// traceDb 创建数据库操作的span
+//   参数
+//     ctx:    context，为nil或没有span时不记录
+//     op:     操作名称，如：FetchAll
+//     sqlStr: Sql串
+//   返回
+//     Span对象，不记录时为nil
+func traceDb(ctx context.Context, op, sqlStr string) *Span {
+	_, s := startSpan(ctx, "db."+op, SpanClient)
+	s.SetAttr("db.operation", op)
+	s.SetAttr("db.statement", sqlStr)
+	return s
+}
+
+// traceCache 每次缓存操作记录span，见Model.Cache
+type traceCache struct {
+	cache.Cache
+	name string
+	ctx  context.Context
+}
+
+// newTraceCache 包装缓存适配器，常用的读写操作记录span
+//   参数
+//     c:    缓存适配器
+//     name: 缓存适配器名称
+//     ctx:  请求的context
+//   返回
+//     缓存适配器
+func newTraceCache(c cache.Cache, name string, ctx context.Context) cache.Cache {
+	return &traceCache{Cache: c, name: name, ctx: ctx}
+}
+
+// span 创建缓存操作的span
+//   参数
+//     op:  操作名称，如：Get
+//     key: 缓存key
+//   返回
+//     Span对象
+func (c *traceCache) span(op, key string) *Span {
+	_, s := startSpan(c.ctx, "cache."+op, SpanClient)
+	s.SetAttr("cache.name", c.name)
+	s.SetAttr("cache.operation", op)
+	if key != "" {
+		s.SetAttr("cache.key", key)
+	}
+	return s
+}
+
+func (c *traceCache) Get(key string, val interface{}) (error, bool) {
+	s := c.span("Get", key)
+	err, ok := c.Cache.Get(key, val)
+	s.SetAttr("cache.hit", ok)
+	s.end(err)
+	return err, ok
+}
+
+func (c *traceCache) Set(key string, val interface{}, expire int32, encode ...bool) error {
+	s := c.span("Set", key)
+	err := c.Cache.Set(key, val, expire, encode...)
+	s.end(err)
+	return err
+}
+
+func (c *traceCache) Del(key string) error {
+	s := c.span("Del", key)
+	err := c.Cache.Del(key)
+	s.end(err)
+	return err
+}
+
+func (c *traceCache) MGet(keys ...string) (map[string]interface{}, error) {
+	s := c.span("MGet", "")
+	s.SetAttr("cache.keys", len(keys))
+	res, err := c.Cache.MGet(keys...)
+	s.end(err)
+	return res, err
+}
+
+func (c *traceCache) MSet(mList map[string]interface{}, expire int32, encode ...bool) error {
+	s := c.span("MSet", "")
+	s.SetAttr("cache.keys", len(mList))
+	err := c.Cache.MSet(mList, expire, encode...)
+	s.end(err)
+	return err
+}
+
+func (c *traceCache) MDel(keys ...string) error {
+	s := c.span("MDel", "")
+	s.SetAttr("cache.keys", len(keys))
+	err := c.Cache.MDel(keys...)
+	s.end(err)
+	return err
+}
+
+func (c *traceCache) Incr(key string, delta ...uint64) (int64, error) {
+	s := c.span("Incr", key)
+	n, err := c.Cache.Incr(key, delta...)
+	s.end(err)
+	return n, err
+}
+
+func (c *traceCache) Decr(key string, delta ...uint64) (int64, error) {
+	s := c.span("Decr", key)
+	n, err := c.Cache.Decr(key, delta...)
+	s.end(err)
+	return n, err
+}
+
+func (c *traceCache) IsExist(key string) (bool, error) {
+	s := c.span("IsExist", key)
+	ok, err := c.Cache.IsExist(key)
+	s.end(err)
+	return ok, err
+}
+
+func (c *traceCache) HGet(key string, field string, val interface{}) (error, bool) {
+	s := c.span("HGet", key)
+	err, ok := c.Cache.HGet(key, field, val)
+	s.SetAttr("cache.hit", ok)
+	s.end(err)
+	return err, ok
+}
+
+func (c *traceCache) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
+	s := c.span("HSet", key)
+	n, err := c.Cache.HSet(key, field, val, expire)
+	s.end(err)
+	return n, err
+}
+
+func (c *traceCache) HDel(key string, fields ...string) error {
+	s := c.span("HDel", key)
+	err := c.Cache.HDel(key, fields...)
+	s.end(err)
+	return err
+}
+
+func (c *traceCache) HGetAll(key string) (map[string]interface{}, error) {
+	s := c.span("HGetAll", key)
+	res, err := c.Cache.HGetAll(key)
+	s.end(err)
+	return res, err
+}
+
+func (c *traceCache) HMGet(key string, fields ...string) (map[string]interface{}, error) {
+	s := c.span("HMGet", key)
+	res, err := c.Cache.HMGet(key, fields...)
+	s.end(err)
+	return res, err
+}
+
+func (c *traceCache) HMSet(key string, fields map[string]interface{}, expire int32) error {
+	s := c.span("HMSet", key)
+	err := c.Cache.HMSet(key, fields, expire)
+	s.end(err)
+	return err
+}
+
+// fileExporter span以JSON格式按行写到本地文件，用于测试和排查问题
+type fileExporter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileExporter 实例化本地文件的span导出，文件不存在时创建
+//   参数
+//     file: 文件路径，如：/data/log/trace.json
+//   返回
+//     span导出、错误信息
+func NewFileExporter(file string) (SpanExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0660)
+	if err != nil {
+		return nil, err
+	}
+	return &fileExporter{f: f}, nil
+}
+
+func (fe *fileExporter) Export(spans []*Span) error {
+	buf := &bytes.Buffer{}
+	for _, s := range spans {
+		s.mu.Lock()
+		b, err := json.Marshal(s)
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	_, err := fe.f.Write(buf.Bytes())
+	return err
+}
+
+// otlpExporter span按OTLP/HTTP的JSON编码POST到collector
+type otlpExporter struct {
+	url     string
+	service string
+	client  *http.Client
+}
+
+// NewOtlpExporter 实例化OTLP/HTTP的span导出
+//   参数
+//     url:     collector地址，如：http://127.0.0.1:4318/v1/traces
+//     service: 服务名称，为空时使用应用名称
+//     timeout: 请求超时时间，<=0 时使用3秒
+//   返回
+//     span导出
+func NewOtlpExporter(url, service string, timeout time.Duration) SpanExporter {
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	if service == "" {
+		service = AppCfg.AppName
+	}
+	return &otlpExporter{url: url, service: service, client: &http.Client{Timeout: timeout}}
+}
+
+func (oe *otlpExporter) Export(spans []*Span) error {
+	b, err := json.Marshal(otlpRequest(oe.service, spans))
+	if err != nil {
+		return err
+	}
+	rsp, err := oe.client.Post(oe.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return fmt.Errorf("otlp [%s] status [%d]", oe.url, rsp.StatusCode)
+	}
+	return nil
+}
+
+// otlpKinds span类型对应的OTLP SpanKind
+var otlpKinds = map[string]int{SpanInternal: 1, SpanServer: 2, SpanClient: 3}
+
+// otlpRequest 生成OTLP ExportTraceServiceRequest的JSON结构
+//   参数
+//     service: 服务名称
+//     spans:   span列表
+//   返回
+//     JSON结构
+func otlpRequest(service string, spans []*Span) map[string]interface{} {
+	list := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		item := map[string]interface{}{
+			"traceId":           s.TraceId,
+			"spanId":            s.SpanId,
+			"name":              s.Name,
+			"kind":              otlpKinds[s.Kind],
+			"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.End.UnixNano(), 10),
+			"attributes":        otlpAttrs(s.Attrs),
+		}
+		if s.ParentId != "" {
+			item["parentSpanId"] = s.ParentId
+		}
+		switch s.Status {
+		case SpanOk:
+			item["status"] = map[string]interface{}{"code": 1}
+		case SpanError:
+			item["status"] = map[string]interface{}{"code": 2, "message": s.Error}
+		}
+		s.mu.Unlock()
+		list = append(list, item)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttrs(map[string]interface{}{"service.name": service}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "bingo", "version": VERSION},
+				"spans": list,
+			}},
+		}},
+	}
+}
+
+// otlpAttrs 转换成OTLP的属性列表
+//   参数
+//     attrs: 属性
+//   返回
+//     OTLP属性列表
+func otlpAttrs(attrs map[string]interface{}) []interface{} {
+	list := make([]interface{}, 0, len(attrs))
+	for k, v := range attrs {
+		var val map[string]interface{}
+		switch x := v.(type) {
+		case string:
+			val = map[string]interface{}{"stringValue": x}
+		case bool:
+			val = map[string]interface{}{"boolValue": x}
+		case int:
+			val = map[string]interface{}{"intValue": strconv.Itoa(x)}
+		case int64:
+			val = map[string]interface{}{"intValue": strconv.FormatInt(x, 10)}
+		case float64:
+			if math.IsNaN(x) || math.IsInf(x, 0) {
+				val = map[string]interface{}{"stringValue": formatFloat(x)}
+			} else {
+				val = map[string]interface{}{"doubleValue": x}
+			}
+		default:
+			val = map[string]interface{}{"stringValue": fmt.Sprint(x)}
+		}
+		list = append(list, map[string]interface{}{"key": k, "value": val})
+	}
+	return list
+}
+
+// TraceConfig 链路追踪配置
+type TraceConfig struct {
+	On          bool          // 是否开启
+	Sample      float64       // 没有上游链路时的采样率，0~1，默认1
+	File        string        // 本地JSON文件，如：/data/log/trace.json，为空时不写文件
+	Otlp        string        // OTLP/HTTP collector地址，如：http://127.0.0.1:4318/v1/traces，为空时不发送
+	OtlpTimeout time.Duration // 发送超时时间，单位毫秒，默认3000
+	Service     string        // 服务名称，默认使用应用名称
+}
+
+// initTrace 按配置开启链路追踪，代码里已经用SetTracer设置时不覆盖
+//   参数
+//     void
+//   返回
+//     成功返回nil，失败返回错误信息
+func initTrace() error {
+	cfg := AppCfg.TraceCfg
+	if !cfg.On || GetTracer() != nil {
+		return nil
+	}
+
+	var exporters []SpanExporter
+	if cfg.File != "" {
+		fe, err := NewFileExporter(cfg.File)
+		if err != nil {
+			return fmt.Errorf("trace: open file [%s] err [%s]", cfg.File, err.Error())
+		}
+		exporters = append(exporters, fe)
+	}
+	if cfg.Otlp != "" {
+		exporters = append(exporters, NewOtlpExporter(cfg.Otlp, cfg.Service, cfg.OtlpTimeout*time.Millisecond))
+	}
+	if len(exporters) == 0 {
+		return fmt.Errorf("trace: file and otlp are both empty")
+	}
+	SetTracer(NewTracer(cfg.Sample, exporters...))
+	return nil
+}
+
+// unInitTrace 导出剩余的span，关闭链路追踪
+//   参数
+//     void
+//   返回
+//     void
+func unInitTrace() {
+	if t := GetTracer(); t != nil {
+		t.Close()
+	}
+}
@@ -0,0 +1,285 @@
+package bingo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/lixy529/gotools/cache"
+)
+
+// memExporter 测试用，记录导出的span
+type memExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (e *memExporter) Export(spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memExporter) take() map[string]*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	m := make(map[string]*Span, len(e.spans))
+	for _, s := range e.spans {
+		m[s.Name] = s
+	}
+	e.spans = nil
+	return m
+}
+
+// TestParseTraceParent 测试解析W3C traceparent
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		v       string
+		sampled bool
+		ok      bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6-00f067aa0ba902b7-01", false, false},
+		{"", false, false},
+	}
+	for _, test := range tests {
+		_, _, sampled, ok := parseTraceParent(test.v)
+		if ok != test.ok || sampled != test.sampled {
+			t.Errorf("parseTraceParent [%s] failed. Got %v %v, expected %v %v.", test.v, sampled, ok, test.sampled, test.ok)
+		}
+	}
+}
+
+// TestTrace 测试请求的服务端span、控制器每一步的子span和traceparent传递
+func TestTrace(t *testing.T) {
+	exp := &memExporter{}
+	tracer := NewTracer(1, exp)
+	SetTracer(tracer)
+	defer func() {
+		SetTracer(nil)
+		tracer.Close()
+	}()
+
+	got := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get(traceParentHeader)
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: &RequestIdTransport{}}
+
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.Get("/trace/:id", &testController{}, "IndexAction")
+	rt.HandleFunc("/call", func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		rsp, err := client.Do(req.WithContext(r.Context()))
+		if err == nil {
+			rsp.Body.Close()
+		}
+	})
+
+	// 沿用上游链路
+	r := httptest.NewRequest("GET", "/trace/1", nil)
+	r.Header.Set(traceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rt.ServeHTTP(httptest.NewRecorder(), r)
+	tracer.Flush()
+	spans := exp.take()
+	server := spans["HTTP GET /trace/:id"]
+	if server == nil || server.TraceId != "4bf92f3577b34da6a3ce929d0e0e4736" || server.ParentId != "00f067aa0ba902b7" || server.Kind != SpanServer {
+		t.Fatalf("server span failed. Got %+v.", spans)
+	}
+	if server.Attrs["http.route"] != "/trace/:id" || server.Attrs["http.status_code"] != http.StatusOK {
+		t.Errorf("server span attrs failed. Got %v.", server.Attrs)
+	}
+	for _, name := range []string{"Prepare", "Filter", "IndexAction", "Finish", "Show"} {
+		s := spans[name]
+		if s == nil || s.TraceId != server.TraceId || s.ParentId != server.SpanId || s.End.Before(s.Start) {
+			t.Errorf("span [%s] failed. Got %+v.", name, s)
+		}
+	}
+
+	// 调用其它服务时传递链路
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/call", nil))
+	tp := <-got
+	tracer.Flush()
+	spans = exp.take()
+	server, call := spans["HTTP GET /call"], spans["HTTP GET"]
+	if server == nil || call == nil || call.ParentId != server.SpanId || call.Kind != SpanClient {
+		t.Fatalf("client span failed. Got %+v.", spans)
+	}
+	if tp != call.TraceParent() {
+		t.Errorf("traceparent failed. Got %s, expected %s.", tp, call.TraceParent())
+	}
+
+	// 上游没有采样时不导出
+	r = httptest.NewRequest("GET", "/trace/1", nil)
+	r.Header.Set(traceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	rt.ServeHTTP(httptest.NewRecorder(), r)
+	tracer.Flush()
+	if spans = exp.take(); len(spans) != 0 {
+		t.Errorf("not sampled failed. Got %+v.", spans)
+	}
+}
+
+// TestTraceCache 测试缓存和数据库操作的span
+func TestTraceCache(t *testing.T) {
+	exp := &memExporter{}
+	tracer := NewTracer(1, exp)
+	defer tracer.Close()
+
+	parent := tracer.newSpan("parent", SpanServer, "", "", true)
+	ctx := context.WithValue(context.Background(), spanKey{}, parent)
+	c := newTraceCache(&testCache{data: map[string]string{"a": "1"}}, "redis", ctx)
+	var v string
+	c.Get("a", &v)
+	c.Get("err", &v)
+	traceDb(ctx, "FetchAll", "select 1").end(nil)
+	traceDb(nil, "FetchAll", "select 1").end(nil)
+	tracer.Flush()
+
+	exp.mu.Lock()
+	spans := exp.spans
+	exp.mu.Unlock()
+	if len(spans) != 3 {
+		t.Fatalf("trace cache failed. Got %d spans.", len(spans))
+	}
+	if s := spans[0]; s.Name != "cache.Get" || s.Attrs["cache.hit"] != true || s.Attrs["cache.key"] != "a" || s.ParentId != parent.SpanId {
+		t.Errorf("cache span failed. Got %+v.", s)
+	}
+	if s := spans[1]; s.Status != SpanError || s.Error != "conn refused" {
+		t.Errorf("cache error span failed. Got %+v.", s)
+	}
+	if s := spans[2]; s.Name != "db.FetchAll" || s.Attrs["db.statement"] != "select 1" || s.Kind != SpanClient {
+		t.Errorf("db span failed. Got %+v.", s)
+	}
+}
+
+// modelController 测试用控制器，在Action里使用模型
+type modelController struct {
+	Controller
+}
+
+func (c *modelController) ModelAction() {
+	m := &Model{}
+	c.InitModel(m)
+	if cc, err := m.Cache("trace_test"); err == nil {
+		var v string
+		cc.Get("a", &v)
+	}
+
+	// 没有设置context的模型不记录span
+	if cc, err := (&Model{}).Cache("trace_test"); err == nil {
+		var v string
+		cc.Get("b", &v)
+	}
+}
+
+// TestTraceModel 测试控制器里用InitModel设置context后记录模型的span
+func TestTraceModel(t *testing.T) {
+	exp := &memExporter{}
+	tracer := NewTracer(1, exp)
+	SetTracer(tracer)
+	defer func() {
+		SetTracer(nil)
+		tracer.Close()
+	}()
+	cache.Adapters["trace_test"] = &testCache{data: map[string]string{"a": "1"}}
+	defer delete(cache.Adapters, "trace_test")
+
+	rt := NewRouterTab()
+	rt.SetReqTimeout(5)
+	rt.Get("/model", &modelController{}, "ModelAction")
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/model", nil))
+	tracer.Flush()
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+	var server, get *Span
+	n := 0
+	for _, s := range exp.spans {
+		switch s.Name {
+		case "HTTP GET /model":
+			server = s
+		case "cache.Get":
+			get = s
+			n++
+		}
+	}
+	if server == nil || get == nil || n != 1 || get.TraceId != server.TraceId || get.ParentId != server.SpanId || get.Attrs["cache.key"] != "a" {
+		t.Errorf("model span failed. Got %d cache spans, %+v.", n, get)
+	}
+}
+
+// TestTraceExporter 测试本地文件和OTLP的导出格式
+func TestTraceExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bingo_trace")
+	if err != nil {
+		t.Fatalf("TempDir failed. err: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	tracer := NewTracer(1)
+	s := tracer.newSpan("op", SpanInternal, "", "", true)
+	s.SetAttr("n", 1)
+	s.SetError(errors.New("failed"))
+	s.Finish()
+
+	file := filepath.Join(dir, "trace.json")
+	fe, err := NewFileExporter(file)
+	if err != nil {
+		t.Fatalf("NewFileExporter failed. err: %s", err.Error())
+	}
+	if err := fe.Export([]*Span{s}); err != nil {
+		t.Fatalf("Export failed. err: %s", err.Error())
+	}
+	f, _ := os.Open(file)
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	var got Span
+	if !sc.Scan() || json.Unmarshal(sc.Bytes(), &got) != nil || got.SpanId != s.SpanId || got.Status != SpanError {
+		t.Errorf("file exporter failed. Got %+v.", &got)
+	}
+
+	b, _ := json.Marshal(otlpRequest("svc", []*Span{s}))
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceId    string `json:"traceId"`
+					Kind       int    `json:"kind"`
+					Attributes []struct {
+						Key   string            `json:"key"`
+						Value map[string]string `json:"value"`
+					} `json:"attributes"`
+					Status struct {
+						Code    int    `json:"code"`
+						Message string `json:"message"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(b, &req); err != nil {
+		t.Fatalf("Unmarshal failed. err: %s", err.Error())
+	}
+	span := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if span.TraceId != s.TraceId || span.Kind != 1 || span.Status.Code != 2 || span.Status.Message != "failed" ||
+		len(span.Attributes) != 1 || span.Attributes[0].Value["intValue"] != "1" {
+		t.Errorf("otlp failed. Got %s.", b)
+	}
+}